@@ -0,0 +1,393 @@
+// Package actions resolves groups of duplicates: it keeps one media of each group and moves, links, trashes or deletes
+// the others. Every action can be previewed with a dry run, and is recorded in a journal that can be undone.
+package actions
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/vegidio/mediasim"
+)
+
+// Action is what is done with the duplicates of a group, the media that aren't kept.
+type Action string
+
+const (
+	// ActionQuarantine moves the duplicates to the quarantine directory, keeping their paths inside it.
+	ActionQuarantine Action = "quarantine"
+	// ActionHardlink replaces the duplicates with hard links to the kept media.
+	ActionHardlink Action = "hardlink"
+	// ActionSymlink replaces the duplicates with symbolic links to the kept media.
+	ActionSymlink Action = "symlink"
+	// ActionReflink replaces the duplicates with copy-on-write clones of the kept media; only on file systems that
+	// support them, like Btrfs, XFS and APFS.
+	ActionReflink Action = "reflink"
+	// ActionTrash moves the duplicates to the trash of the user, as specified by freedesktop.org.
+	ActionTrash Action = "trash"
+	// ActionDelete deletes the duplicates permanently.
+	ActionDelete Action = "delete"
+)
+
+// Actions are the valid actions of Apply.
+var Actions = []Action{ActionQuarantine, ActionHardlink, ActionSymlink, ActionReflink, ActionTrash, ActionDelete}
+
+// Keep is how the media kept in each group is chosen.
+type Keep string
+
+const (
+	// KeepBest keeps the media with the best quality: the longest, then the one with the most pixels, then the largest.
+	KeepBest Keep = "best"
+	// KeepOldest keeps the media modified the longest ago.
+	KeepOldest Keep = "oldest"
+	// KeepNewest keeps the media modified the most recently.
+	KeepNewest Keep = "newest"
+	// KeepPath keeps the media whose path comes first alphabetically.
+	KeepPath Keep = "path"
+)
+
+// Keeps are the valid ways of choosing the kept media.
+var Keeps = []Keep{KeepBest, KeepOldest, KeepNewest, KeepPath}
+
+// Options represents the configuration options for resolving the groups of duplicates.
+//
+// # Fields:
+//   - Action: What is done with the duplicates.
+//   - Keep: How the media kept in each group is chosen. Empty means KeepBest.
+//   - Quarantine: The directory where the duplicates are moved by ActionQuarantine. With the link actions, the
+//     duplicates are also moved there before being replaced, so they can be restored by Undo; when it's empty, they are
+//     deleted and the links can't be undone.
+//   - TrashDir: The trash directory used by ActionTrash. Empty means the trash of the user: $XDG_DATA_HOME/Trash or
+//     ~/.local/share/Trash.
+//   - DryRun: If true, the operations are planned and returned, but no file is changed.
+//   - Journal: The path of the file where the journal is saved. Each operation is written to it as soon as it's done,
+//     so the run can be undone even when it's interrupted. Empty means the journal is only returned; nothing is
+//     written with DryRun or when no operation is done.
+type Options struct {
+	Action     Action
+	Keep       Keep
+	Quarantine string
+	TrashDir   string
+	DryRun     bool
+	Journal    string
+}
+
+// SetDefaults sets the default values of the options that weren't set.
+func (o *Options) SetDefaults() {
+	if o.Keep == "" {
+		o.Keep = KeepBest
+	}
+
+	if o.TrashDir == "" {
+		o.TrashDir = defaultTrashDir()
+	}
+}
+
+// Apply keeps one media of each group and applies the action to the others.
+//
+// Media inside archives are ignored, as they can't be changed; groups left with less than two files are skipped. A
+// symbolic link is never kept, and the duplicates that are the kept file itself, like the links to it, are left
+// untouched, so the only copy of a media is never removed. When the kept media doesn't exist anymore, as in a stale
+// delete list, its group is skipped and the error is returned after the other groups. The other errors stop the
+// operations, and the journal has the operations done until then, so they can be undone.
+//
+// # Parameters:
+//   - groups: The groups of similar media, as returned by the grouping functions.
+//   - options: The action and how the kept media is chosen.
+//
+// # Returns:
+//   - The journal of the operations; with DryRun, the operations that would be done.
+//   - An error if the options are invalid, a kept media doesn't exist, or any of the operations failed.
+func Apply(groups [][]mediasim.Media, options Options) (Journal, error) {
+	options.SetDefaults()
+	journal := newJournal(options.DryRun)
+
+	if !slices.Contains(Actions, options.Action) {
+		return journal, fmt.Errorf("invalid action %q", options.Action)
+	}
+	if !slices.Contains(Keeps, options.Keep) {
+		return journal, fmt.Errorf("invalid keep %q", options.Keep)
+	}
+	if options.Action == ActionQuarantine && options.Quarantine == "" {
+		return journal, fmt.Errorf("the quarantine directory is required to quarantine files")
+	}
+
+	file := newJournalFile(options.Journal, journal)
+	err := applyGroups(groups, options, &journal, file)
+
+	return journal, errors.Join(err, file.close(journal))
+}
+
+// region - Private functions
+
+// applyGroups applies the action to the groups, adding the operations done to the journal and to its file.
+func applyGroups(groups [][]mediasim.Media, options Options, journal *Journal, file *journalFile) error {
+	skipped := make([]error, 0)
+
+	for _, group := range groups {
+		files := make([]mediasim.Media, 0, len(group))
+		for _, m := range group {
+			if _, _, inArchive := mediasim.SplitArchiveName(m.Name); !inArchive {
+				files = append(files, m)
+			}
+		}
+
+		if len(files) < 2 {
+			continue
+		}
+
+		keep, err := chooseKeep(files, options.Keep)
+		if err != nil {
+			return err
+		}
+
+		// A group with only links has no file that can be kept
+		if keep == "" {
+			continue
+		}
+
+		keepInfo, err := os.Stat(keep)
+		if err != nil {
+			err = fmt.Errorf("the kept media %s can't be found, so its group was skipped: %w", keep, err)
+			skipped = append(skipped, err)
+			continue
+		}
+
+		for _, m := range files {
+			if m.Name == keep || isSameFile(m.Name, keepInfo) {
+				continue
+			}
+
+			operation, opErr := plan(m.Name, keep, options)
+			if opErr == nil && !options.DryRun {
+				opErr = execute(&operation, options)
+			}
+			if opErr != nil {
+				return fmt.Errorf("failed to %s %s: %w", options.Action, m.Name, opErr)
+			}
+
+			journal.Operations = append(journal.Operations, operation)
+			if err = file.append(operation); err != nil {
+				return err
+			}
+		}
+	}
+
+	return errors.Join(skipped...)
+}
+
+// isSameFile reports whether the path is the kept file itself, like a symbolic or a hard link to it.
+func isSameFile(path string, keep os.FileInfo) bool {
+	info, err := os.Stat(path)
+	return err == nil && os.SameFile(info, keep)
+}
+
+// isSymlink reports whether the path is a symbolic link.
+func isSymlink(path string) bool {
+	info, err := os.Lstat(path)
+	return err == nil && info.Mode()&os.ModeSymlink != 0
+}
+
+// chooseKeep returns the name of the media kept in the group; symbolic links are never kept, so it's empty when every
+// media of the group is a link.
+func chooseKeep(group []mediasim.Media, keep Keep) (string, error) {
+	group = slices.DeleteFunc(slices.Clone(group), func(m mediasim.Media) bool { return isSymlink(m.Name) })
+	if len(group) == 0 {
+		return "", nil
+	}
+
+	switch keep {
+	case KeepPath:
+		return slices.MinFunc(group, func(a, b mediasim.Media) int { return cmp.Compare(a.Name, b.Name) }).Name, nil
+	case KeepOldest, KeepNewest:
+		modTimes := make(map[string]time.Time, len(group))
+		for _, m := range group {
+			info, err := os.Stat(m.Name)
+			if err != nil {
+				return "", err
+			}
+
+			modTimes[m.Name] = info.ModTime()
+		}
+
+		// Ties are broken by the quality order of the group, as the first of the tied media is chosen
+		oldest := slices.MinFunc(group, func(a, b mediasim.Media) int {
+			return modTimes[a.Name].Compare(modTimes[b.Name])
+		})
+		newest := slices.MaxFunc(group, func(a, b mediasim.Media) int {
+			return modTimes[a.Name].Compare(modTimes[b.Name])
+		})
+
+		if keep == KeepOldest {
+			return oldest.Name, nil
+		}
+
+		return newest.Name, nil
+	default:
+		// The groups are sorted by quality, with the best media first
+		return group[0].Name, nil
+	}
+}
+
+// plan creates the operation that applies the action to the file, with the paths it will use.
+func plan(path, keep string, options Options) (Operation, error) {
+	// The paths are absolute, so the journal can be undone from any directory
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return Operation{}, err
+	}
+
+	keep, err = filepath.Abs(keep)
+	if err != nil {
+		return Operation{}, err
+	}
+
+	operation := Operation{Action: options.Action, Path: path, Keep: keep}
+
+	switch options.Action {
+	case ActionQuarantine, ActionHardlink, ActionSymlink, ActionReflink:
+		if options.Quarantine != "" {
+			backup, err := quarantinePath(options.Quarantine, path)
+			if err != nil {
+				return operation, err
+			}
+
+			operation.Backup = backup
+		}
+	case ActionTrash:
+		operation.Backup = filepath.Join(options.TrashDir, "files", filepath.Base(path))
+	}
+
+	return operation, nil
+}
+
+// execute changes the files of the operation. The paths of the operation are updated when they are taken by other
+// files.
+func execute(operation *Operation, options Options) error {
+	switch operation.Action {
+	case ActionQuarantine:
+		return moveFile(operation.Path, operation.Backup)
+	case ActionTrash:
+		return trash(operation, options.TrashDir)
+	case ActionDelete:
+		return os.Remove(operation.Path)
+	}
+
+	// The link is created next to the duplicate and then moved over it, so the duplicate is never lost when the link
+	// can't be created
+	temp := operation.Path + ".mediasim-link"
+	if err := link(operation.Action, operation.Keep, temp); err != nil {
+		return err
+	}
+
+	var err error
+	if operation.Backup != "" {
+		err = moveFile(operation.Path, operation.Backup)
+	} else {
+		err = os.Remove(operation.Path)
+	}
+
+	if err == nil {
+		err = os.Rename(temp, operation.Path)
+	}
+
+	if err != nil {
+		_ = os.Remove(temp)
+	}
+
+	return err
+}
+
+// link creates a link of the kind of the action, at the path, to the kept file.
+func link(action Action, keep, path string) error {
+	switch action {
+	case ActionHardlink:
+		return os.Link(keep, path)
+	case ActionSymlink:
+		target, err := filepath.Abs(keep)
+		if err != nil {
+			return err
+		}
+
+		return os.Symlink(target, path)
+	default:
+		return reflink(keep, path)
+	}
+}
+
+// quarantinePath returns the path of the file inside the quarantine directory, which mirrors its absolute path, so
+// files with the same name in different directories don't collide.
+func quarantinePath(quarantine, path string) (string, error) {
+	quarantine, err := filepath.Abs(quarantine)
+	if err != nil {
+		return "", err
+	}
+
+	volume := filepath.VolumeName(path)
+	relative := strings.TrimPrefix(path, volume)
+
+	return filepath.Join(quarantine, strings.TrimSuffix(volume, ":"), relative), nil
+}
+
+// moveFile moves the file to the destination, creating its directory. The destination must not exist. When they are
+// in different file systems, the file is copied and then removed.
+func moveFile(source, destination string) error {
+	if _, err := os.Lstat(destination); err == nil {
+		return fmt.Errorf("%s already exists", destination)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(destination), 0o755); err != nil {
+		return err
+	}
+
+	err := os.Rename(source, destination)
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	if err = copyFile(source, destination); err != nil {
+		_ = os.Remove(destination)
+		return err
+	}
+
+	return os.Remove(source)
+}
+
+// copyFile copies the content and the permissions of the file to a new file.
+func copyFile(source, destination string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(destination, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+
+	if err = out.Close(); err != nil {
+		return err
+	}
+
+	return os.Chtimes(destination, info.ModTime(), info.ModTime())
+}
+
+// endregion
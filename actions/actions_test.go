@@ -0,0 +1,295 @@
+package actions
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vegidio/mediasim"
+)
+
+// createGroup creates a file for each name in the directory, with the name as content, and returns a group with their
+// media, in the same order.
+func createGroup(t *testing.T, dir string, names ...string) []mediasim.Media {
+	t.Helper()
+
+	return lo.Map(names, func(name string, _ int) mediasim.Media {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(name), 0o644))
+
+		return mediasim.Media{Name: path, Type: "image"}
+	})
+}
+
+// readFile returns the content of the file, or an empty string when it doesn't exist.
+func readFile(path string) string {
+	content, _ := os.ReadFile(path)
+	return string(content)
+}
+
+func TestApply(t *testing.T) {
+	t.Run("duplicates are moved to the quarantine", func(t *testing.T) {
+		dir := t.TempDir()
+		quarantine := filepath.Join(dir, "quarantine")
+		group := createGroup(t, dir, "best.jpg", "copy.jpg", "sub/copy.jpg")
+
+		journal, err := Apply([][]mediasim.Media{group}, Options{Action: ActionQuarantine, Quarantine: quarantine})
+		require.NoError(t, err)
+
+		require.Len(t, journal.Operations, 2)
+		assert.Equal(t, JournalVersion, journal.Version)
+		assert.False(t, journal.DryRun)
+
+		assert.FileExists(t, group[0].Name)
+		assert.NoFileExists(t, group[1].Name)
+		assert.NoFileExists(t, group[2].Name)
+
+		for _, operation := range journal.Operations {
+			assert.Equal(t, group[0].Name, operation.Keep)
+			assert.True(t, operation.Reversible())
+			assert.Equal(t, filepath.Base(operation.Path), filepath.Base(operation.Backup))
+			assert.FileExists(t, operation.Backup)
+		}
+
+		// The files with the same name don't collide
+		assert.Equal(t, "sub/copy.jpg", readFile(journal.Operations[1].Backup))
+	})
+
+	t.Run("dry runs don't change the files", func(t *testing.T) {
+		dir := t.TempDir()
+		group := createGroup(t, dir, "best.jpg", "copy.jpg")
+
+		journal, err := Apply([][]mediasim.Media{group}, Options{Action: ActionDelete, DryRun: true})
+		require.NoError(t, err)
+
+		assert.True(t, journal.DryRun)
+		assert.Equal(t, []Operation{{Action: ActionDelete, Path: group[1].Name, Keep: group[0].Name}}, journal.Operations)
+		assert.FileExists(t, group[1].Name)
+	})
+
+	t.Run("duplicates are replaced with links", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("links need special permissions on Windows")
+		}
+
+		dir := t.TempDir()
+		group := createGroup(t, dir, "best.jpg", "hard.jpg")
+		_, err := Apply([][]mediasim.Media{group}, Options{Action: ActionHardlink})
+		require.NoError(t, err)
+
+		keep, _ := os.Stat(group[0].Name)
+		hard, _ := os.Stat(group[1].Name)
+		assert.True(t, os.SameFile(keep, hard))
+
+		group = createGroup(t, dir, "best2.jpg", "soft.jpg")
+		_, err = Apply([][]mediasim.Media{group}, Options{Action: ActionSymlink})
+		require.NoError(t, err)
+
+		target, err := os.Readlink(group[1].Name)
+		require.NoError(t, err)
+		assert.Equal(t, group[0].Name, target)
+		assert.NoFileExists(t, group[1].Name+".mediasim-link")
+	})
+
+	t.Run("duplicates are replaced with clones", func(t *testing.T) {
+		dir := t.TempDir()
+		group := createGroup(t, dir, "best.jpg", "clone.jpg")
+
+		if _, err := Apply([][]mediasim.Media{group}, Options{Action: ActionReflink}); err != nil {
+			t.Skipf("the file system doesn't support reflinks: %v", err)
+		}
+
+		assert.Equal(t, "best.jpg", readFile(group[1].Name))
+	})
+
+	t.Run("failed links keep the duplicate", func(t *testing.T) {
+		dir := t.TempDir()
+		group := createGroup(t, dir, "best.jpg", "copy.jpg")
+		require.NoError(t, os.Remove(group[0].Name))
+
+		_, err := Apply([][]mediasim.Media{group}, Options{Action: ActionHardlink})
+		assert.Error(t, err)
+		assert.Equal(t, "copy.jpg", readFile(group[1].Name))
+	})
+
+	t.Run("the kept media can be the oldest, the newest or the first path", func(t *testing.T) {
+		dir := t.TempDir()
+		group := createGroup(t, dir, "b.jpg", "c.jpg", "a.jpg")
+		now := time.Now()
+		require.NoError(t, os.Chtimes(group[0].Name, now, now.Add(-time.Hour)))
+		require.NoError(t, os.Chtimes(group[1].Name, now, now.Add(-2*time.Hour)))
+		require.NoError(t, os.Chtimes(group[2].Name, now, now))
+
+		keeps := map[Keep]string{KeepBest: "b.jpg", KeepOldest: "c.jpg", KeepNewest: "a.jpg", KeepPath: "a.jpg"}
+		for keep, expected := range keeps {
+			journal, err := Apply([][]mediasim.Media{group}, Options{Action: ActionDelete, Keep: keep, DryRun: true})
+			require.NoError(t, err)
+			assert.Equal(t, filepath.Join(dir, expected), journal.Operations[0].Keep, "keep %s", keep)
+		}
+	})
+
+	t.Run("media in archives are ignored", func(t *testing.T) {
+		dir := t.TempDir()
+		group := createGroup(t, dir, "best.jpg", "copy.jpg")
+		group = append(group, mediasim.Media{Name: filepath.Join(dir, "photos.zip") + mediasim.ArchiveSeparator + "a.jpg"})
+
+		journal, err := Apply([][]mediasim.Media{group, group[1:]}, Options{Action: ActionDelete, DryRun: true})
+		require.NoError(t, err)
+		assert.Len(t, journal.Operations, 1)
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		_, err := Apply(nil, Options{Action: "shred"})
+		assert.EqualError(t, err, `invalid action "shred"`)
+
+		_, err = Apply(nil, Options{Action: ActionDelete, Keep: "largest"})
+		assert.EqualError(t, err, `invalid keep "largest"`)
+
+		_, err = Apply(nil, Options{Action: ActionQuarantine})
+		assert.ErrorContains(t, err, "quarantine directory is required")
+	})
+}
+
+func TestApply_Links(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("links need special permissions on Windows")
+	}
+
+	// linkGroup creates the files and a symbolic link, named first, to the file b.jpg
+	linkGroup := func(t *testing.T, dir string, names ...string) []mediasim.Media {
+		group := createGroup(t, dir, names...)
+		link := filepath.Join(dir, "a.jpg")
+		require.NoError(t, os.Symlink(filepath.Join(dir, "b.jpg"), link))
+
+		return append([]mediasim.Media{{Name: link, Type: "image"}}, group...)
+	}
+
+	t.Run("links are never kept, and links to the kept file are untouched", func(t *testing.T) {
+		for _, keep := range []Keep{KeepPath, KeepBest} {
+			dir := t.TempDir()
+			group := linkGroup(t, dir, "b.jpg")
+
+			journal, err := Apply([][]mediasim.Media{group}, Options{Action: ActionDelete, Keep: keep})
+			require.NoError(t, err)
+
+			assert.Empty(t, journal.Operations, "keep %s", keep)
+			assert.Equal(t, "b.jpg", readFile(group[0].Name), "keep %s", keep)
+			assert.Equal(t, "b.jpg", readFile(group[1].Name), "keep %s", keep)
+		}
+	})
+
+	t.Run("other duplicates are linked to the file, not to the link", func(t *testing.T) {
+		dir := t.TempDir()
+		group := linkGroup(t, dir, "b.jpg", "c.jpg")
+
+		journal, err := Apply([][]mediasim.Media{group}, Options{Action: ActionSymlink, Keep: KeepPath})
+		require.NoError(t, err)
+
+		require.Len(t, journal.Operations, 1)
+		assert.Equal(t, group[2].Name, journal.Operations[0].Path)
+
+		target, err := os.Readlink(group[2].Name)
+		require.NoError(t, err)
+		assert.Equal(t, group[1].Name, target)
+		assert.Equal(t, "b.jpg", readFile(group[0].Name))
+		assert.Equal(t, "b.jpg", readFile(group[2].Name))
+	})
+
+	t.Run("hard links to the kept file are untouched", func(t *testing.T) {
+		dir := t.TempDir()
+		group := createGroup(t, dir, "best.jpg")
+		hard := filepath.Join(dir, "hard.jpg")
+		require.NoError(t, os.Link(group[0].Name, hard))
+		group = append(group, mediasim.Media{Name: hard, Type: "image"})
+
+		journal, err := Apply([][]mediasim.Media{group}, Options{Action: ActionDelete})
+		require.NoError(t, err)
+		assert.Empty(t, journal.Operations)
+		assert.FileExists(t, hard)
+	})
+
+	t.Run("groups with only links are skipped", func(t *testing.T) {
+		dir := t.TempDir()
+		group := linkGroup(t, dir, "b.jpg")
+		other := filepath.Join(dir, "other.jpg")
+		require.NoError(t, os.Symlink(group[1].Name, other))
+
+		journal, err := Apply([][]mediasim.Media{{group[0], {Name: other}}}, Options{Action: ActionDelete})
+		require.NoError(t, err)
+		assert.Empty(t, journal.Operations)
+		assert.FileExists(t, other)
+	})
+
+	t.Run("groups whose kept media doesn't exist are skipped", func(t *testing.T) {
+		dir := t.TempDir()
+		stale := createGroup(t, dir, "gone.jpg", "copy.jpg")
+		require.NoError(t, os.Remove(stale[0].Name))
+		group := createGroup(t, dir, "best.jpg", "other.jpg")
+
+		journal, err := Apply([][]mediasim.Media{stale, group}, Options{Action: ActionDelete})
+		assert.ErrorContains(t, err, "the kept media "+stale[0].Name+" can't be found")
+
+		assert.Equal(t, "copy.jpg", readFile(stale[1].Name))
+		assert.Equal(t, []Operation{{Action: ActionDelete, Path: group[1].Name, Keep: group[0].Name}}, journal.Operations)
+	})
+}
+
+func TestTrash(t *testing.T) {
+	dir := t.TempDir()
+	trashDir := filepath.Join(dir, "Trash")
+	first := createGroup(t, dir, "best.jpg", "my photo.jpg")
+	second := createGroup(t, filepath.Join(dir, "other"), "best.jpg", "my photo.jpg")
+
+	journal, err := Apply([][]mediasim.Media{first, second}, Options{Action: ActionTrash, TrashDir: trashDir})
+	require.NoError(t, err)
+	require.Len(t, journal.Operations, 2)
+
+	// The second file with the same name gets a number
+	assert.Equal(t, filepath.Join(trashDir, "files", "my photo.jpg"), journal.Operations[0].Backup)
+	assert.Equal(t, filepath.Join(trashDir, "files", "my photo.2.jpg"), journal.Operations[1].Backup)
+	assert.Equal(t, "my photo.jpg", readFile(journal.Operations[1].Backup))
+
+	info := readFile(journal.Operations[0].TrashInfo)
+	assert.Contains(t, info, "[Trash Info]\nPath="+filepath.ToSlash(dir)+"/my%20photo.jpg\nDeletionDate=")
+}
+
+func TestDeleteList(t *testing.T) {
+	dir := t.TempDir()
+	group := createGroup(t, dir, "a.jpg", "b.jpg", "c.jpg")
+	path := filepath.Join(dir, "delete-list.json")
+
+	write := func(content string) {
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+
+	write(`{"version": 1, "groups": [{"keep": "` + group[1].Name + `", "delete": ["` + group[0].Name + `"]}]}`)
+	list, err := ReadDeleteList(path)
+	require.NoError(t, err)
+
+	// The media kept is the one in the list, and the media not in the list are untouched
+	journal, err := Apply(list.MediaGroups(), Options{Action: ActionDelete})
+	require.NoError(t, err)
+	assert.Equal(t, []Operation{{Action: ActionDelete, Path: group[0].Name, Keep: group[1].Name}}, journal.Operations)
+	assert.NoFileExists(t, group[0].Name)
+	assert.FileExists(t, group[2].Name)
+
+	t.Run("invalid lists", func(t *testing.T) {
+		write(`{"version": 2, "groups": []}`)
+		_, err = ReadDeleteList(path)
+		assert.ErrorContains(t, err, "unsupported version 2")
+
+		write(`{"version": 1, "groups": [{"delete": ["/a.jpg"]}]}`)
+		_, err = ReadDeleteList(path)
+		assert.ErrorContains(t, err, "has no kept media")
+
+		write(`{"version": 1, "groups": [{"keep": "/a.jpg", "delete": ["/a.jpg"]}]}`)
+		_, err = ReadDeleteList(path)
+		assert.ErrorContains(t, err, "both kept and deleted")
+	})
+}
@@ -0,0 +1,84 @@
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/vegidio/mediasim"
+)
+
+// DeleteListVersion is the version of the format of the delete lists.
+const DeleteListVersion = 1
+
+// DeleteGroup is a group of similar media where a person chose which media are removed.
+//
+// # Fields:
+//   - Keep: The path of the media kept; the links created by Apply point to it.
+//   - Delete: The paths of the media removed.
+type DeleteGroup struct {
+	Keep   string   `json:"keep"`
+	Delete []string `json:"delete"`
+}
+
+// DeleteList is a list of the media chosen to be removed from each group, like the one exported by the HTML report of
+// the command line, so they can be removed by Apply.
+type DeleteList struct {
+	Version int           `json:"version"`
+	Groups  []DeleteGroup `json:"groups"`
+}
+
+// ReadDeleteList reads a delete list written as JSON.
+//
+// # Parameters:
+//   - path: The path of the delete list.
+//
+// # Returns:
+//   - The delete list.
+//   - An error if the file couldn't be read, isn't a delete list of a supported version, or has groups without a kept
+//     media.
+func ReadDeleteList(path string) (DeleteList, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return DeleteList{}, fmt.Errorf("failed to read the delete list: %w", err)
+	}
+
+	var list DeleteList
+	if err = json.Unmarshal(content, &list); err != nil {
+		return DeleteList{}, fmt.Errorf("invalid delete list %s: %w", path, err)
+	}
+
+	if list.Version != DeleteListVersion {
+		return DeleteList{}, fmt.Errorf("unsupported version %d of the delete list %s", list.Version, path)
+	}
+
+	for i, group := range list.Groups {
+		if group.Keep == "" {
+			return DeleteList{}, fmt.Errorf("the group %d of the delete list %s has no kept media", i+1, path)
+		}
+
+		if slices.Contains(group.Delete, group.Keep) {
+			return DeleteList{}, fmt.Errorf("the media %s is both kept and deleted in the delete list %s", group.Keep, path)
+		}
+	}
+
+	return list, nil
+}
+
+// MediaGroups returns the groups of the list with the kept media first, so Apply with KeepBest keeps it and applies the
+// action to the others.
+func (l DeleteList) MediaGroups() [][]mediasim.Media {
+	groups := make([][]mediasim.Media, 0, len(l.Groups))
+
+	for _, group := range l.Groups {
+		media := []mediasim.Media{{Name: group.Keep}}
+		for _, name := range group.Delete {
+			media = append(media, mediasim.Media{Name: name})
+		}
+
+		groups = append(groups, media)
+	}
+
+	return groups
+}
@@ -0,0 +1,341 @@
+package actions
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// JournalVersion is the version of the format of the journal.
+const JournalVersion = 1
+
+// Operation is an action applied to a file.
+//
+// # Fields:
+//   - Action: The action applied to the file.
+//   - Path: The absolute path of the file.
+//   - Keep: The absolute path of the media kept in the group of the file; the links point to it.
+//   - Backup: Where the file was moved to: the quarantine or the trash. It's empty when the file was deleted, in which
+//     case the operation can't be undone.
+//   - TrashInfo: The path of the .trashinfo file created for files moved to the trash.
+//   - Target: The path created by a rename: the new name of the file or, in a review tree, the link to it.
+type Operation struct {
+	Action    Action `json:"action"`
+	Path      string `json:"path"`
+	Keep      string `json:"keep,omitempty"`
+	Backup    string `json:"backup,omitempty"`
+	TrashInfo string `json:"trashInfo,omitempty"`
+	Target    string `json:"target,omitempty"`
+}
+
+// Reversible reports whether the operation can be undone, which is when the original file still exists somewhere.
+func (o Operation) Reversible() bool {
+	return o.Backup != "" || o.Target != ""
+}
+
+// Journal is the record of the operations done on the files, in the order they were done, so they can be undone.
+type Journal struct {
+	Version    int         `json:"version"`
+	Created    time.Time   `json:"created"`
+	DryRun     bool        `json:"dryRun"`
+	Operations []Operation `json:"operations"`
+}
+
+// UndoResult is the result of undoing a journal.
+//
+// # Fields:
+//   - Restored: The operations undone, in the order they were undone.
+//   - Irreversible: The operations that can't be undone, because their files were deleted.
+type UndoResult struct {
+	Restored     []Operation `json:"restored"`
+	Irreversible []Operation `json:"irreversible"`
+}
+
+// Save writes the journal to the file, as JSON. The file is written next to the path and then moved over it, so an
+// existing journal is never left half written.
+//
+// # Parameters:
+//   - path: The path of the file; it's replaced if it exists.
+//
+// # Returns:
+//   - An error if the file couldn't be written.
+func (j Journal) Save(path string) error {
+	content, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal the journal: %w", err)
+	}
+
+	temp := path + ".tmp"
+	if err = writeSynced(temp, content); err == nil {
+		err = os.Rename(temp, path)
+	}
+
+	if err != nil {
+		_ = os.Remove(temp)
+		return fmt.Errorf("failed to write the journal: %w", err)
+	}
+
+	return nil
+}
+
+// ReadJournal reads a journal written by Journal.Save or, when the run was interrupted, the journal that Apply and
+// Rename write as the operations are done: the header of the journal followed by one line for each operation. A last
+// operation cut in half by the interruption is ignored, as it was written after its files were changed.
+//
+// # Parameters:
+//   - path: The path of the journal.
+//
+// # Returns:
+//   - The journal.
+//   - An error if the file couldn't be read or isn't a journal of a supported version.
+func ReadJournal(path string) (Journal, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Journal{}, fmt.Errorf("failed to read the journal: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(content))
+
+	var journal Journal
+	if err = decoder.Decode(&journal); err != nil {
+		return Journal{}, fmt.Errorf("invalid journal %s: %w", path, err)
+	}
+
+	if journal.Version != JournalVersion {
+		return Journal{}, fmt.Errorf("unsupported version %d of the journal %s", journal.Version, path)
+	}
+
+	for decoder.More() {
+		var operation Operation
+		if err = decoder.Decode(&operation); errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		} else if err != nil {
+			return Journal{}, fmt.Errorf("invalid journal %s: %w", path, err)
+		}
+
+		journal.Operations = append(journal.Operations, operation)
+	}
+
+	return journal, nil
+}
+
+// Undo reverts the operations of the journal, from the last to the first: the files are moved back from the quarantine
+// or the trash, the links are replaced by the original files, the renamed files get their names back and the links of
+// the review trees are removed, with the folders left empty. Deleted files can't be restored, so their operations
+// are skipped and returned as irreversible.
+//
+// The operations stop at the first error, and the result has the operations undone until then.
+//
+// # Parameters:
+//   - journal: The journal of the operations.
+//   - dryRun: If true, the operations that would be undone are returned, but no file is changed.
+//
+// # Returns:
+//   - The operations undone and the ones that can't be undone.
+//   - An error if the journal is of a dry run or any of the operations failed.
+func Undo(journal Journal, dryRun bool) (UndoResult, error) {
+	result := UndoResult{Restored: make([]Operation, 0), Irreversible: make([]Operation, 0)}
+	if journal.DryRun {
+		return result, fmt.Errorf("the journal is of a dry run, so there is nothing to undo")
+	}
+
+	for _, operation := range slices.Backward(journal.Operations) {
+		if !operation.Reversible() {
+			result.Irreversible = append(result.Irreversible, operation)
+			continue
+		}
+
+		if !dryRun {
+			if err := revert(operation); err != nil {
+				return result, fmt.Errorf("failed to undo the %s of %s: %w", operation.Action, operation.Path, err)
+			}
+		}
+
+		result.Restored = append(result.Restored, operation)
+	}
+
+	return result, nil
+}
+
+// region - Private functions
+
+// newJournal creates an empty journal.
+func newJournal(dryRun bool) Journal {
+	return Journal{
+		Version:    JournalVersion,
+		Created:    time.Now().UTC().Truncate(time.Second),
+		DryRun:     dryRun,
+		Operations: make([]Operation, 0),
+	}
+}
+
+// journalFile writes the operations to the journal file as soon as they are done, so the operations of a run that is
+// interrupted can still be undone. The file is created with the first operation, as the header of the journal followed
+// by one line for each operation, each synced to the disk before the next file is changed; close saves the complete
+// journal over it.
+type journalFile struct {
+	path   string
+	header Journal
+	file   *os.File
+}
+
+// newJournalFile creates the journal file of the run; it's nil when the path is empty or the run is a dry run, and then
+// nothing is written.
+func newJournalFile(path string, header Journal) *journalFile {
+	if path == "" || header.DryRun {
+		return nil
+	}
+
+	return &journalFile{path: path, header: header}
+}
+
+// append writes the operation to the end of the journal file.
+func (f *journalFile) append(operation Operation) error {
+	if f == nil {
+		return nil
+	}
+
+	if f.file == nil {
+		file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+		if err != nil {
+			return fmt.Errorf("failed to write the journal: %w", err)
+		}
+
+		f.file = file
+		if err = f.writeLine(f.header); err != nil {
+			return err
+		}
+	}
+
+	return f.writeLine(operation)
+}
+
+// close saves the journal over the file written by append, unless no operation was done.
+func (f *journalFile) close(journal Journal) error {
+	if f == nil || f.file == nil {
+		return nil
+	}
+
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("failed to write the journal: %w", err)
+	}
+
+	return journal.Save(f.path)
+}
+
+func (f *journalFile) writeLine(value any) error {
+	line, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the journal: %w", err)
+	}
+
+	if _, err = f.file.Write(append(line, '\n')); err == nil {
+		err = f.file.Sync()
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to write the journal: %w", err)
+	}
+
+	return nil
+}
+
+// writeSynced writes the content to a new file, and syncs it to the disk.
+func writeSynced(path string, content []byte) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if _, err = file.Write(content); err == nil {
+		err = file.Sync()
+	}
+
+	return errors.Join(err, file.Close())
+}
+
+// revert moves the file of the operation back to its path. The links created by the operation are removed first; any
+// other file in the path is kept, and the operation fails.
+func revert(operation Operation) error {
+	switch operation.Action {
+	case ActionRename:
+		if err := moveFile(operation.Target, operation.Path); err != nil {
+			return err
+		}
+
+		removeEmptyDir(filepath.Dir(operation.Target))
+		return nil
+	case ActionReview:
+		if target, err := os.Readlink(operation.Target); err == nil && target != operation.Path {
+			return fmt.Errorf("%s was changed after the %s", operation.Target, operation.Action)
+		}
+
+		if err := os.Remove(operation.Target); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		removeEmptyDir(filepath.Dir(operation.Target))
+		return nil
+	}
+
+	if operation.Action == ActionHardlink || operation.Action == ActionSymlink || operation.Action == ActionReflink {
+		if err := removeLink(operation); err != nil {
+			return err
+		}
+	}
+
+	if err := moveFile(operation.Backup, operation.Path); err != nil {
+		return err
+	}
+
+	if operation.TrashInfo != "" {
+		_ = os.Remove(operation.TrashInfo)
+	}
+
+	return nil
+}
+
+// removeLink removes the link that replaced the file of the operation, after checking it's still the link; clones
+// can't be told apart from other files, so they are removed while they have the size of the kept file.
+func removeLink(operation Operation) error {
+	info, err := os.Lstat(operation.Path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	isLink := false
+	switch operation.Action {
+	case ActionSymlink:
+		target, linkErr := os.Readlink(operation.Path)
+		isLink = linkErr == nil && target == operation.Keep
+	case ActionHardlink:
+		keep, statErr := os.Stat(operation.Keep)
+		isLink = statErr == nil && os.SameFile(info, keep)
+	case ActionReflink:
+		keep, statErr := os.Stat(operation.Keep)
+		isLink = statErr == nil && info.Mode().IsRegular() && info.Size() == keep.Size()
+	}
+
+	if !isLink {
+		return fmt.Errorf("%s was changed after the %s", operation.Path, operation.Action)
+	}
+
+	return os.Remove(operation.Path)
+}
+
+// removeEmptyDir removes the directory when it's empty, like the folders of the groups after their files are moved back.
+func removeEmptyDir(dir string) {
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) == 0 {
+		_ = os.Remove(dir)
+	}
+}
+
+// endregion
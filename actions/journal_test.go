@@ -0,0 +1,186 @@
+package actions
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vegidio/mediasim"
+)
+
+func TestJournal_SaveAndRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.json")
+	journal := newJournal(false)
+	journal.Operations = append(journal.Operations, Operation{Action: ActionTrash, Path: "/a.jpg", Keep: "/b.jpg"})
+
+	require.NoError(t, journal.Save(path))
+
+	read, err := ReadJournal(path)
+	require.NoError(t, err)
+	assert.Equal(t, journal, read)
+
+	t.Run("journals of interrupted runs are read", func(t *testing.T) {
+		file := newJournalFile(path, newJournal(false))
+		require.NoError(t, file.append(Operation{Action: ActionTrash, Path: "/a.jpg", Keep: "/b.jpg"}))
+		require.NoError(t, file.append(Operation{Action: ActionTrash, Path: "/c.jpg", Keep: "/b.jpg"}))
+
+		// The last line was cut in half by the interruption
+		_, err := file.file.WriteString(`{"action":"trash","pa`)
+		require.NoError(t, err)
+
+		read, err := ReadJournal(path)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"/a.jpg", "/c.jpg"}, lo.Map(read.Operations, func(o Operation, _ int) string {
+			return o.Path
+		}))
+
+		require.NoError(t, file.file.Close())
+	})
+
+	t.Run("other versions are rejected", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path, []byte(`{"version": 99}`), 0o644))
+
+		_, err = ReadJournal(path)
+		assert.ErrorContains(t, err, "unsupported version 99")
+	})
+}
+
+func TestApply_Journal(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "journal.json")
+	quarantine := filepath.Join(dir, "quarantine")
+	group := createGroup(t, dir, "best.jpg", "copy1.jpg", "copy2.jpg")
+
+	// The quarantine of the second copy fails, and the first one is already in the journal file
+	blocked, err := quarantinePath(quarantine, group[2].Name)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Dir(blocked), 0o755))
+	require.NoError(t, os.WriteFile(blocked, nil, 0o644))
+
+	journal, err := Apply([][]mediasim.Media{group}, Options{Action: ActionQuarantine, Quarantine: quarantine,
+		Journal: path})
+	assert.ErrorContains(t, err, "already exists")
+
+	read, err := ReadJournal(path)
+	require.NoError(t, err)
+	assert.Equal(t, journal, read)
+	require.Len(t, read.Operations, 1)
+
+	_, err = Undo(read, false)
+	require.NoError(t, err)
+	assert.Equal(t, "copy1.jpg", readFile(group[1].Name))
+
+	t.Run("no file is written without operations or in dry runs", func(t *testing.T) {
+		empty := filepath.Join(dir, "empty.json")
+		_, err = Apply(nil, Options{Action: ActionDelete, Journal: empty})
+		require.NoError(t, err)
+		assert.NoFileExists(t, empty)
+
+		_, err = Apply([][]mediasim.Media{group}, Options{Action: ActionDelete, DryRun: true, Journal: empty})
+		require.NoError(t, err)
+		assert.NoFileExists(t, empty)
+	})
+}
+
+func TestUndo(t *testing.T) {
+	tests := []struct {
+		name    string
+		options func(dir string) Options
+	}{
+		{"quarantine", func(dir string) Options {
+			return Options{Action: ActionQuarantine, Quarantine: filepath.Join(dir, "quarantine")}
+		}},
+		{"trash", func(dir string) Options {
+			return Options{Action: ActionTrash, TrashDir: filepath.Join(dir, "Trash")}
+		}},
+		{"hardlink", func(dir string) Options {
+			return Options{Action: ActionHardlink, Quarantine: filepath.Join(dir, "quarantine")}
+		}},
+		{"symlink", func(dir string) Options {
+			return Options{Action: ActionSymlink, Quarantine: filepath.Join(dir, "quarantine")}
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name+" is undone", func(t *testing.T) {
+			if runtime.GOOS == "windows" && test.name == "symlink" {
+				t.Skip("links need special permissions on Windows")
+			}
+
+			dir := t.TempDir()
+			group := createGroup(t, dir, "best.jpg", "copy1.jpg", "copy2.jpg")
+
+			journal, err := Apply([][]mediasim.Media{group}, test.options(dir))
+			require.NoError(t, err)
+
+			result, err := Undo(journal, false)
+			require.NoError(t, err)
+
+			assert.Len(t, result.Restored, 2)
+			assert.Empty(t, result.Irreversible)
+			assert.Equal(t, group[2].Name, result.Restored[0].Path, "the last operation is undone first")
+
+			for _, m := range group {
+				info, statErr := os.Lstat(m.Name)
+				require.NoError(t, statErr)
+				assert.True(t, info.Mode().IsRegular())
+				assert.Equal(t, filepath.Base(m.Name), readFile(m.Name))
+			}
+
+			for _, operation := range journal.Operations {
+				assert.NoFileExists(t, operation.Backup)
+				if operation.TrashInfo != "" {
+					assert.NoFileExists(t, operation.TrashInfo)
+				}
+			}
+		})
+	}
+
+	t.Run("deleted files can't be restored", func(t *testing.T) {
+		dir := t.TempDir()
+		group := createGroup(t, dir, "best.jpg", "copy.jpg")
+
+		journal, err := Apply([][]mediasim.Media{group}, Options{Action: ActionDelete})
+		require.NoError(t, err)
+
+		result, err := Undo(journal, false)
+		require.NoError(t, err)
+		assert.Empty(t, result.Restored)
+		assert.Equal(t, journal.Operations, result.Irreversible)
+	})
+
+	t.Run("dry runs don't change the files", func(t *testing.T) {
+		dir := t.TempDir()
+		group := createGroup(t, dir, "best.jpg", "copy.jpg")
+
+		journal, err := Apply([][]mediasim.Media{group}, Options{Action: ActionQuarantine, Quarantine: filepath.Join(dir, "q")})
+		require.NoError(t, err)
+
+		result, err := Undo(journal, true)
+		require.NoError(t, err)
+		assert.Len(t, result.Restored, 1)
+		assert.NoFileExists(t, group[1].Name)
+	})
+
+	t.Run("files created after the action aren't replaced", func(t *testing.T) {
+		dir := t.TempDir()
+		group := createGroup(t, dir, "best.jpg", "copy.jpg")
+
+		journal, err := Apply([][]mediasim.Media{group}, Options{Action: ActionQuarantine, Quarantine: filepath.Join(dir, "q")})
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(group[1].Name, []byte("new"), 0o644))
+
+		_, err = Undo(journal, false)
+		assert.ErrorContains(t, err, "already exists")
+		assert.Equal(t, "new", readFile(group[1].Name))
+	})
+
+	t.Run("journals of dry runs can't be undone", func(t *testing.T) {
+		_, err := Undo(newJournal(true), false)
+		assert.ErrorContains(t, err, "dry run")
+	})
+}
@@ -0,0 +1,22 @@
+//go:build darwin
+
+package actions
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// region - Private functions
+
+// reflink creates the file at the path as a copy-on-write clone of the source, with clonefile.
+func reflink(source, path string) error {
+	if err := unix.Clonefile(source, path, unix.CLONE_NOFOLLOW); err != nil {
+		return &os.LinkError{Op: "reflink", Old: source, New: path, Err: err}
+	}
+
+	return nil
+}
+
+// endregion
@@ -0,0 +1,44 @@
+//go:build linux
+
+package actions
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// region - Private functions
+
+// reflink creates the file at the path as a copy-on-write clone of the source, with the FICLONE ioctl.
+func reflink(source, path string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	err = unix.IoctlFileClone(int(out.Fd()), int(in.Fd()))
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		_ = os.Remove(path)
+		return &os.LinkError{Op: "reflink", Old: source, New: path, Err: err}
+	}
+
+	return nil
+}
+
+// endregion
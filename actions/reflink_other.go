@@ -0,0 +1,17 @@
+//go:build !linux && !darwin
+
+package actions
+
+import (
+	"errors"
+	"os"
+)
+
+// region - Private functions
+
+// reflink isn't supported in this operating system.
+func reflink(source, path string) error {
+	return &os.LinkError{Op: "reflink", Old: source, New: path, Err: errors.ErrUnsupported}
+}
+
+// endregion
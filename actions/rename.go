@@ -0,0 +1,318 @@
+package actions
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/vegidio/mediasim"
+)
+
+const (
+	// ActionRename renames the media of the groups, optionally moving them to a folder of each group.
+	ActionRename Action = "rename"
+	// ActionReview creates a symbolic link to each media of the groups in a review tree, without changing the media.
+	ActionReview Action = "review"
+)
+
+// Layout is where the renamed media of each group are placed.
+type Layout string
+
+const (
+	// LayoutInPlace renames the media in their directories.
+	LayoutInPlace Layout = "in-place"
+	// LayoutFolders moves the media of each group to a folder of the group, inside RenameOptions.Directory.
+	LayoutFolders Layout = "folders"
+	// LayoutReview leaves the media untouched, and creates symbolic links to them in a folder of each group, inside
+	// RenameOptions.Directory.
+	LayoutReview Layout = "review"
+)
+
+// Layouts are the valid layouts.
+var Layouts = []Layout{LayoutInPlace, LayoutFolders, LayoutReview}
+
+// Collision is what happens when the new name of a media is taken.
+type Collision string
+
+const (
+	// CollisionSuffix adds a number to the new name, like "group1_photo_2.jpg".
+	CollisionSuffix Collision = "suffix"
+	// CollisionSkip leaves the media with its current name.
+	CollisionSkip Collision = "skip"
+	// CollisionFail stops before any media is renamed.
+	CollisionFail Collision = "fail"
+)
+
+// Collisions are the valid ways of handling collisions.
+var Collisions = []Collision{CollisionSuffix, CollisionSkip, CollisionFail}
+
+// DefaultTemplate is the template of the new names when RenameOptions.Template is empty; it prefixes the names with the
+// number of their groups.
+const DefaultTemplate = "group{group}_{name}{ext}"
+
+// DefaultFolderTemplate is the template of the folders of the groups when RenameOptions.FolderTemplate is empty.
+const DefaultFolderTemplate = "group{group}"
+
+// RenameOptions represents the configuration options for renaming the media of the groups.
+//
+// The templates can have these placeholders:
+//   - {group}: The number of the group, starting at 1, padded with zeros to the width of the number of groups.
+//   - {id}: The ID of the group, as returned by mediasim.GroupID.
+//   - {rank}: The position of the media in its group, where 1 is the best media.
+//   - {score}: The similarity between the media and the best media of its group, with two decimals.
+//   - {name}: The name of the file of the media, without the extension.
+//   - {ext}: The extension of the file of the media, with the dot.
+//
+// # Fields:
+//   - Template: The template of the new names of the media. Empty means DefaultTemplate.
+//   - FolderTemplate: The template of the names of the folders of the groups, with the LayoutFolders and LayoutReview
+//     layouts; only {group} and {id} can be used. Empty means DefaultFolderTemplate.
+//   - Layout: Where the renamed media are placed. Empty means LayoutInPlace.
+//   - Directory: The directory of the folders of the groups, with the LayoutFolders and LayoutReview layouts.
+//   - Collision: What happens when a new name is taken. Empty means CollisionSuffix.
+//   - DryRun: If true, the operations are planned and returned, but no file is changed.
+//   - Journal: The path of the file where the journal is saved, as in Options.Journal.
+type RenameOptions struct {
+	Template       string
+	FolderTemplate string
+	Layout         Layout
+	Directory      string
+	Collision      Collision
+	DryRun         bool
+	Journal        string
+}
+
+// SetDefaults sets the default values of the options that weren't set.
+func (o *RenameOptions) SetDefaults() {
+	if o.Template == "" {
+		o.Template = DefaultTemplate
+	}
+
+	if o.FolderTemplate == "" {
+		o.FolderTemplate = DefaultFolderTemplate
+	}
+
+	if o.Layout == "" {
+		o.Layout = LayoutInPlace
+	}
+
+	if o.Collision == "" {
+		o.Collision = CollisionSuffix
+	}
+}
+
+// Rename gives the media of the groups new names built from the template, in place, in folders of the groups, or as
+// symbolic links in a review tree.
+//
+// All the new names are planned, and the collisions are handled, before any media is renamed; with CollisionFail, no
+// media is renamed when any name is taken. Media inside archives are ignored, as they can't be renamed. The operations
+// stop at the first error, and the journal has the operations done until then, so they can be undone.
+//
+// # Parameters:
+//   - groups: The groups of similar media, as returned by the grouping functions.
+//   - options: The templates, the layout and how collisions are handled.
+//
+// # Returns:
+//   - The journal of the operations; with DryRun, the operations that would be done.
+//   - An error if the options are invalid, a name is taken with CollisionFail, or any of the operations failed.
+func Rename(groups [][]mediasim.Media, options RenameOptions) (Journal, error) {
+	options.SetDefaults()
+	journal := newJournal(options.DryRun)
+
+	if err := options.validate(); err != nil {
+		return journal, err
+	}
+
+	operations, err := planRenames(groups, options)
+	if err != nil {
+		return journal, err
+	}
+
+	if options.DryRun {
+		journal.Operations = operations
+		return journal, nil
+	}
+
+	file := newJournalFile(options.Journal, journal)
+	err = renameFiles(operations, &journal, file)
+
+	return journal, errors.Join(err, file.close(journal))
+}
+
+// region - Private functions
+
+// renameFiles does the planned renames, adding the operations done to the journal and to its file.
+func renameFiles(operations []Operation, journal *Journal, file *journalFile) error {
+	var err error
+
+	for _, operation := range operations {
+		if err = os.MkdirAll(filepath.Dir(operation.Target), 0o755); err == nil {
+			if operation.Action == ActionReview {
+				err = os.Symlink(operation.Path, operation.Target)
+			} else {
+				err = moveFile(operation.Path, operation.Target)
+			}
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to %s %s: %w", operation.Action, operation.Path, err)
+		}
+
+		journal.Operations = append(journal.Operations, operation)
+		if err = file.append(operation); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// placeholderRegex matches the placeholders of the templates.
+var placeholderRegex = regexp.MustCompile(`\{[^{}]*}`)
+
+// validate checks the options, including the placeholders of the templates.
+func (o RenameOptions) validate() error {
+	if !slices.Contains(Layouts, o.Layout) {
+		return fmt.Errorf("invalid layout %q", o.Layout)
+	}
+	if !slices.Contains(Collisions, o.Collision) {
+		return fmt.Errorf("invalid collision handling %q", o.Collision)
+	}
+	if o.Layout != LayoutInPlace && o.Directory == "" {
+		return fmt.Errorf("the directory of the groups is required with the %s layout", o.Layout)
+	}
+
+	templates := []struct {
+		template     string
+		placeholders []string
+	}{
+		{o.Template, []string{"{group}", "{id}", "{rank}", "{score}", "{name}", "{ext}"}},
+		{o.FolderTemplate, []string{"{group}", "{id}"}},
+	}
+
+	for _, t := range templates {
+		for _, placeholder := range placeholderRegex.FindAllString(t.template, -1) {
+			if !slices.Contains(t.placeholders, placeholder) {
+				return fmt.Errorf("invalid placeholder %s in the template %q", placeholder, t.template)
+			}
+		}
+	}
+
+	return nil
+}
+
+// planRenames creates the operations of the media of the groups, handling the collisions.
+func planRenames(groups [][]mediasim.Media, options RenameOptions) ([]Operation, error) {
+	width := len(strconv.Itoa(len(groups)))
+	operations := make([]Operation, 0)
+	taken := make(map[string]bool)
+
+	for i, group := range groups {
+		id := mediasim.GroupID(group)
+		groupValues := map[string]string{"{group}": fmt.Sprintf("%0*d", width, i+1), "{id}": id}
+
+		for rank, m := range group {
+			if _, _, inArchive := mediasim.SplitArchiveName(m.Name); inArchive {
+				continue
+			}
+
+			path, err := filepath.Abs(m.Name)
+			if err != nil {
+				return nil, err
+			}
+
+			base := filepath.Base(path)
+			ext := filepath.Ext(base)
+			values := map[string]string{
+				"{rank}":  strconv.Itoa(rank + 1),
+				"{score}": fmt.Sprintf("%.2f", mediasim.CalculateSimilarity(group[0], m)),
+				"{name}":  strings.TrimSuffix(base, ext),
+				"{ext}":   ext,
+			}
+			for key, value := range groupValues {
+				values[key] = value
+			}
+
+			name := expandTemplate(options.Template, values)
+			if name == "" || strings.ContainsAny(name, `/\`) {
+				return nil, fmt.Errorf("the template %q creates the invalid name %q for %s", options.Template, name, path)
+			}
+
+			operation := Operation{Action: ActionRename, Path: path}
+			switch options.Layout {
+			case LayoutInPlace:
+				operation.Target = filepath.Join(filepath.Dir(path), name)
+			default:
+				folder := expandTemplate(options.FolderTemplate, groupValues)
+				directory, absErr := filepath.Abs(filepath.Join(options.Directory, folder))
+				if absErr != nil {
+					return nil, absErr
+				}
+
+				operation.Target = filepath.Join(directory, name)
+				if options.Layout == LayoutReview {
+					operation.Action = ActionReview
+				}
+			}
+
+			if operation.Target == operation.Path {
+				continue
+			}
+
+			target, ok, err := resolveCollision(operation.Target, taken, options.Collision)
+			if err != nil {
+				return nil, fmt.Errorf("failed to rename %s: %w", path, err)
+			}
+
+			if ok {
+				operation.Target = target
+				taken[target] = true
+				operations = append(operations, operation)
+			}
+		}
+	}
+
+	return operations, nil
+}
+
+// expandTemplate replaces the placeholders of the template with their values.
+func expandTemplate(template string, values map[string]string) string {
+	return placeholderRegex.ReplaceAllStringFunc(template, func(placeholder string) string {
+		return values[placeholder]
+	})
+}
+
+// resolveCollision returns the path where the media can be placed, when the target is already a file or the target of
+// another media. The last value is false when the media must be skipped.
+func resolveCollision(target string, taken map[string]bool, collision Collision) (string, bool, error) {
+	isFree := func(path string) bool {
+		_, err := os.Lstat(path)
+		return !taken[path] && errors.Is(err, os.ErrNotExist)
+	}
+
+	if isFree(target) {
+		return target, true, nil
+	}
+
+	switch collision {
+	case CollisionSkip:
+		return "", false, nil
+	case CollisionFail:
+		return "", false, fmt.Errorf("%s already exists", target)
+	}
+
+	ext := filepath.Ext(target)
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s_%d%s", strings.TrimSuffix(target, ext), i, ext)
+		if isFree(candidate) {
+			return candidate, true, nil
+		}
+	}
+}
+
+// endregion
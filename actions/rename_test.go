@@ -0,0 +1,193 @@
+package actions
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vegidio/mediasim"
+)
+
+// targets returns the base names of the targets of the operations.
+func targets(journal Journal) []string {
+	return lo.Map(journal.Operations, func(op Operation, _ int) string { return filepath.Base(op.Target) })
+}
+
+func TestRename(t *testing.T) {
+	t.Run("the default template prefixes the number of the group", func(t *testing.T) {
+		dir := t.TempDir()
+		groups := [][]mediasim.Media{
+			createGroup(t, dir, "a.jpg", "b.jpg"),
+			createGroup(t, dir, "c.jpg", "sub/d.jpg"),
+		}
+
+		journal, err := Rename(groups, RenameOptions{})
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"group1_a.jpg", "group1_b.jpg", "group2_c.jpg", "group2_d.jpg"}, targets(journal))
+		assert.Equal(t, "sub/d.jpg", readFile(filepath.Join(dir, "sub", "group2_d.jpg")))
+		assert.NoFileExists(t, groups[0][0].Name)
+	})
+
+	t.Run("templates can have the ID, rank and score", func(t *testing.T) {
+		dir := t.TempDir()
+		group := createGroup(t, dir, "best.jpg", "copy.jpg")
+
+		journal, err := Rename([][]mediasim.Media{group}, RenameOptions{Template: "{id}-{rank}-{score}-{name}{ext}", DryRun: true})
+		require.NoError(t, err)
+
+		// Media without frames have a score of 0
+		id := mediasim.GroupID(group)
+		assert.Equal(t, []string{id + "-1-0.00-best.jpg", id + "-2-0.00-copy.jpg"}, targets(journal))
+		assert.FileExists(t, group[0].Name, "dry runs don't change the files")
+	})
+
+	t.Run("groups can be moved to folders", func(t *testing.T) {
+		dir := t.TempDir()
+		out := filepath.Join(dir, "groups")
+		group := createGroup(t, dir, "x/a.jpg", "y/b.jpg")
+
+		journal, err := Rename([][]mediasim.Media{group}, RenameOptions{
+			Template:  "{rank}{ext}",
+			Layout:    LayoutFolders,
+			Directory: out,
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, "x/a.jpg", readFile(filepath.Join(out, "group1", "1.jpg")))
+		assert.Equal(t, "y/b.jpg", readFile(filepath.Join(out, "group1", "2.jpg")))
+		assert.Len(t, journal.Operations, 2)
+
+		_, err = Undo(journal, false)
+		require.NoError(t, err)
+
+		assert.FileExists(t, group[0].Name)
+		assert.FileExists(t, group[1].Name)
+		assert.NoDirExists(t, filepath.Join(out, "group1"), "the empty folders are removed")
+	})
+
+	t.Run("review trees link to the media without changing them", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("links need special permissions on Windows")
+		}
+
+		dir := t.TempDir()
+		review := filepath.Join(dir, "review")
+		group := createGroup(t, dir, "a.jpg", "b.jpg")
+
+		journal, err := Rename([][]mediasim.Media{group}, RenameOptions{
+			Layout:         LayoutReview,
+			Directory:      review,
+			FolderTemplate: "{id}",
+		})
+		require.NoError(t, err)
+
+		link := filepath.Join(review, mediasim.GroupID(group), "group1_a.jpg")
+		target, err := os.Readlink(link)
+		require.NoError(t, err)
+		assert.Equal(t, group[0].Name, target)
+		assert.FileExists(t, group[0].Name)
+		assert.Equal(t, ActionReview, journal.Operations[0].Action)
+
+		_, err = Undo(journal, false)
+		require.NoError(t, err)
+		assert.NoFileExists(t, link)
+		assert.FileExists(t, group[0].Name)
+	})
+
+	t.Run("renames are undone", func(t *testing.T) {
+		dir := t.TempDir()
+		group := createGroup(t, dir, "a.jpg", "b.jpg")
+
+		journal, err := Rename([][]mediasim.Media{group}, RenameOptions{})
+		require.NoError(t, err)
+
+		path := filepath.Join(t.TempDir(), "journal.json")
+		require.NoError(t, journal.Save(path))
+		journal, err = ReadJournal(path)
+		require.NoError(t, err)
+
+		result, err := Undo(journal, false)
+		require.NoError(t, err)
+		assert.Len(t, result.Restored, 2)
+		assert.Equal(t, "a.jpg", readFile(group[0].Name))
+		assert.Equal(t, "b.jpg", readFile(group[1].Name))
+		assert.DirExists(t, dir)
+	})
+}
+
+func TestRename_Collisions(t *testing.T) {
+	setup := func(t *testing.T) (string, [][]mediasim.Media) {
+		dir := t.TempDir()
+		group := createGroup(t, dir, "a.jpg", "sub/a.jpg", "group1_b.jpg")
+		group = append(group, createGroup(t, dir, "b.jpg")...)
+
+		return dir, [][]mediasim.Media{group}
+	}
+
+	t.Run("a number is added to the names that are taken", func(t *testing.T) {
+		dir, groups := setup(t)
+
+		journal, err := Rename(groups, RenameOptions{Template: "{name}{ext}", Layout: LayoutFolders, Directory: dir})
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"a.jpg", "a_2.jpg", "group1_b.jpg", "b.jpg"}, targets(journal))
+	})
+
+	t.Run("the existing files aren't replaced", func(t *testing.T) {
+		_, groups := setup(t)
+
+		journal, err := Rename(groups, RenameOptions{})
+		require.NoError(t, err)
+
+		// group1_b.jpg is renamed to group1_group1_b.jpg after b.jpg found its name taken
+		assert.Equal(t, []string{"group1_a.jpg", "group1_a.jpg", "group1_group1_b.jpg", "group1_b_2.jpg"},
+			targets(journal))
+		assert.Equal(t, "group1_b.jpg", readFile(journal.Operations[2].Target))
+		assert.Equal(t, "b.jpg", readFile(journal.Operations[3].Target))
+	})
+
+	t.Run("media whose names are taken can be skipped", func(t *testing.T) {
+		_, groups := setup(t)
+
+		journal, err := Rename(groups, RenameOptions{Collision: CollisionSkip})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"group1_a.jpg", "group1_a.jpg", "group1_group1_b.jpg"}, targets(journal))
+	})
+
+	t.Run("no media is renamed when a name is taken", func(t *testing.T) {
+		_, groups := setup(t)
+
+		_, err := Rename(groups, RenameOptions{Collision: CollisionFail})
+		assert.ErrorContains(t, err, "group1_b.jpg already exists")
+
+		for _, m := range groups[0] {
+			assert.FileExists(t, m.Name)
+		}
+	})
+}
+
+func TestRenameOptions_Validate(t *testing.T) {
+	tests := []struct {
+		options RenameOptions
+		err     string
+	}{
+		{RenameOptions{Template: "{name}-{date}{ext}"}, "invalid placeholder {date}"},
+		{RenameOptions{FolderTemplate: "{rank}", Layout: LayoutFolders, Directory: "out"}, "invalid placeholder {rank}"},
+		{RenameOptions{Layout: LayoutReview}, "directory of the groups is required"},
+		{RenameOptions{Layout: "flat"}, `invalid layout "flat"`},
+		{RenameOptions{Collision: "replace"}, `invalid collision handling "replace"`},
+	}
+
+	for _, test := range tests {
+		_, err := Rename(nil, test.options)
+		assert.ErrorContains(t, err, test.err)
+	}
+
+	_, err := Rename([][]mediasim.Media{createGroup(t, t.TempDir(), "a.jpg", "b.jpg")}, RenameOptions{Template: "{name}/{ext}"})
+	assert.ErrorContains(t, err, "invalid name")
+}
@@ -0,0 +1,93 @@
+package actions
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// region - Private functions
+
+// defaultTrashDir returns the trash directory of the user, as specified by freedesktop.org: $XDG_DATA_HOME/Trash, or
+// ~/.local/share/Trash when the variable isn't set.
+func defaultTrashDir() string {
+	if dataHome := os.Getenv("XDG_DATA_HOME"); dataHome != "" {
+		return filepath.Join(dataHome, "Trash")
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, ".local", "share", "Trash")
+}
+
+// trash moves the file of the operation to the trash, with the .trashinfo file that file managers use to restore it.
+// When the name is taken in the trash, a number is added to it, and the operation is updated with the paths used.
+func trash(operation *Operation, trashDir string) error {
+	if trashDir == "" {
+		return fmt.Errorf("the trash directory wasn't found")
+	}
+
+	filesDir := filepath.Join(trashDir, "files")
+	infoDir := filepath.Join(trashDir, "info")
+	for _, dir := range []string{filesDir, infoDir} {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return err
+		}
+	}
+
+	info := fmt.Sprintf("[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+		(&url.URL{Path: operation.Path}).EscapedPath(), time.Now().Format("2006-01-02T15:04:05"))
+
+	base := filepath.Base(operation.Path)
+	ext := filepath.Ext(base)
+
+	for i := 1; ; i++ {
+		name := base
+		if i > 1 {
+			name = fmt.Sprintf("%s.%d%s", strings.TrimSuffix(base, ext), i, ext)
+		}
+
+		// The .trashinfo file is created first, to reserve the name
+		infoPath := filepath.Join(infoDir, name+".trashinfo")
+		file, err := os.OpenFile(infoPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		_, err = file.WriteString(info)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+
+		// A file without its .trashinfo also takes the name
+		destination := filepath.Join(filesDir, name)
+		if _, statErr := os.Lstat(destination); err == nil && statErr == nil {
+			_ = os.Remove(infoPath)
+			continue
+		}
+
+		if err == nil {
+			err = moveFile(operation.Path, destination)
+		}
+
+		if err != nil {
+			_ = os.Remove(infoPath)
+			return err
+		}
+
+		operation.Backup = destination
+		operation.TrashInfo = infoPath
+		return nil
+	}
+}
+
+// endregion
@@ -0,0 +1,267 @@
+package mediasim
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/vegidio/go-sak/async"
+	. "github.com/vegidio/go-sak/types"
+)
+
+// ArchiveSeparator separates the path of an archive from the path of an entry inside it, in the name of media loaded
+// from archives; e.g. "backup.zip!/photos/beach.jpg".
+const ArchiveSeparator = "!/"
+
+// archiveTypes are the file extensions (as returned by filepath.Ext) of the supported archives.
+var archiveTypes = []string{".zip", ".tar", ".tgz", ".gz"}
+
+// SplitArchiveName splits the name of a media loaded from an archive into the path of the archive and the path of the
+// entry inside it. The last value is false if the name doesn't refer to an archive entry.
+func SplitArchiveName(name string) (string, string, bool) {
+	archive, entry, found := strings.Cut(name, ArchiveSeparator)
+	if !found || !isArchive(archive) {
+		return name, "", false
+	}
+
+	return archive, entry, true
+}
+
+// region - Private functions
+
+// mediaSource loads a media, either from a regular file or from an archive entry.
+type mediaSource func() (*Media, error)
+
+// entryFilter reports whether the entry of an archive is loaded, from its path in the archive, its metadata and its
+// content, read with open.
+type entryFilter func(name string, info iofs.FileInfo, open func() (io.ReadCloser, error)) bool
+
+// openArchive lists the entries of an archive allowed by the filter, returning them as sources, and a function that
+// releases the resources of the archive once all sources have been loaded.
+func openArchive(archivePath string, allow entryFilter, options LoadOptions) ([]mediaSource, func(), error) {
+	lower := strings.ToLower(archivePath)
+
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return openZip(archivePath, allow, options)
+	case strings.HasSuffix(lower, ".tar"):
+		return openTar(archivePath, allow, options)
+	case strings.HasSuffix(lower, ".tgz"), strings.HasSuffix(lower, ".tar.gz"):
+		return openTarGz(archivePath, allow, options)
+	}
+
+	return nil, nil, newMediaError(archivePath, ErrUnsupportedFormat, nil)
+}
+
+func openZip(archivePath string, allow entryFilter, options LoadOptions) ([]mediaSource, func(), error) {
+	reader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, nil, newMediaError(archivePath, ErrUnreadable, err)
+	}
+
+	sources := make([]mediaSource, 0)
+
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() || !allow(file.Name, file.FileInfo(), file.Open) {
+			continue
+		}
+
+		name := archivePath + ArchiveSeparator + file.Name
+		sources = append(sources, func() (*Media, error) {
+			rc, openErr := file.Open()
+			if openErr != nil {
+				return nil, newMediaError(name, ErrUnreadable, openErr)
+			}
+
+			defer rc.Close()
+			return LoadMediaFromReaderWithOptions(name, rc, options)
+		})
+	}
+
+	return sources, func() { reader.Close() }, nil
+}
+
+func openTar(archivePath string, allow entryFilter, options LoadOptions) ([]mediaSource, func(), error) {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return nil, nil, newMediaError(archivePath, ErrUnreadable, err)
+	}
+
+	sources, err := readTar(file, archivePath, allow, options)
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+
+	return sources, func() { file.Close() }, nil
+}
+
+func openTarGz(archivePath string, allow entryFilter, options LoadOptions) ([]mediaSource, func(), error) {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return nil, nil, newMediaError(archivePath, ErrUnreadable, err)
+	}
+
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, nil, newMediaError(archivePath, ErrUnreadable, err)
+	}
+
+	defer gz.Close()
+
+	// Compressed archives can't be read at random positions, so they are decompressed once to a temp file, which is
+	// then handled as an uncompressed tar.
+	tempDir, err := os.MkdirTemp("", "mediasim-*")
+	if err != nil {
+		return nil, nil, fmt.Errorf("error creating temp directory: %w", err)
+	}
+
+	tarFile, err := os.Create(filepath.Join(tempDir, "archive.tar"))
+	if err != nil {
+		os.RemoveAll(tempDir)
+		return nil, nil, fmt.Errorf("error creating temp file: %w", err)
+	}
+
+	release := func() {
+		tarFile.Close()
+		os.RemoveAll(tempDir)
+	}
+
+	if _, err = io.Copy(tarFile, gz); err != nil {
+		release()
+		return nil, nil, newMediaError(archivePath, ErrUnreadable, err)
+	}
+
+	if _, err = tarFile.Seek(0, io.SeekStart); err != nil {
+		release()
+		return nil, nil, newMediaError(archivePath, ErrUnreadable, err)
+	}
+
+	sources, err := readTar(tarFile, archivePath, allow, options)
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+
+	return sources, release, nil
+}
+
+// readTar lists the entries of an uncompressed tar file allowed by the filter. The sources read the content of the
+// entries directly from the file, so it must be kept open until they are loaded.
+func readTar(file *os.File, archivePath string, allow entryFilter, options LoadOptions) ([]mediaSource, error) {
+	// The tar reader consumes exactly the header blocks before returning an entry, so counting the bytes read tells
+	// where the content of each entry starts. The entries can then be read concurrently with section readers.
+	counter := &countingReader{reader: file}
+	reader := tar.NewReader(counter)
+	sources := make([]mediaSource, 0)
+
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, newMediaError(archivePath, ErrUnreadable, err)
+		}
+
+		offset, size := counter.count, header.Size
+		open := func() (io.ReadCloser, error) { return io.NopCloser(io.NewSectionReader(file, offset, size)), nil }
+
+		if header.Typeflag != tar.TypeReg || !allow(header.Name, header.FileInfo(), open) {
+			continue
+		}
+
+		name := archivePath + ArchiveSeparator + strings.TrimPrefix(header.Name, "./")
+
+		sources = append(sources, func() (*Media, error) {
+			return LoadMediaFromReaderWithOptions(name, io.NewSectionReader(file, offset, size), options)
+		})
+	}
+
+	return sources, nil
+}
+
+// loadMediaFromSources loads the files and the media inside the archives of the directory, releasing the archives once
+// every source has been loaded. The entries of the archives pass the same filters as the files.
+func loadMediaFromSources(
+	directory string,
+	filePaths []string,
+	archivePaths []string,
+	options DirectoryOptions,
+) (<-chan Result[Media], int) {
+	sources := make([]mediaSource, 0, len(filePaths))
+	closers := make([]func(), 0, len(archivePaths))
+	filter := fileFilter{options: options, extensions: options.mediaTypes()}
+	loadOptions := options.loadOptions()
+
+	for _, filePath := range filePaths {
+		sources = append(sources, func() (*Media, error) {
+			return LoadMediaFromFileWithOptions(filePath, loadOptions)
+		})
+	}
+
+	for _, archivePath := range archivePaths {
+		relPath, _ := filepath.Rel(directory, archivePath)
+		allow := func(name string, info iofs.FileInfo, open func() (io.ReadCloser, error)) bool {
+			return filter.allowEntry(filepath.ToSlash(relPath), name, info, open)
+		}
+
+		archiveSources, closeArchive, err := openArchive(archivePath, allow, loadOptions)
+		if err != nil {
+			// A broken archive is reported as a single failed item, so the other sources can still be loaded
+			sources = append(sources, func() (*Media, error) { return nil, err })
+
+			continue
+		}
+
+		sources = append(sources, archiveSources...)
+		closers = append(closers, closeArchive)
+	}
+
+	results := async.SliceToChannel(sources, options.Parallel, func(source mediaSource) Result[Media] {
+		media, err := source()
+		if err != nil {
+			return Result[Media]{Err: err}
+		}
+
+		return Result[Media]{Data: *media}
+	})
+
+	out := make(chan Result[Media])
+
+	go func() {
+		defer close(out)
+
+		for r := range results {
+			out <- r
+		}
+
+		for _, closeArchive := range closers {
+			closeArchive()
+		}
+	}()
+
+	return out, len(sources)
+}
+
+// isArchive checks if the path has the extension of a supported archive.
+func isArchive(filePath string) bool {
+	lower := strings.ToLower(filePath)
+	if strings.HasSuffix(lower, ".gz") {
+		return strings.HasSuffix(lower, ".tar.gz")
+	}
+
+	return slices.Contains(archiveTypes, filepath.Ext(lower))
+}
+
+// endregion
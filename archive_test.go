@@ -0,0 +1,231 @@
+package mediasim
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	. "github.com/vegidio/go-sak/types"
+)
+
+// writeZip creates a ZIP archive with the given entries for testing.
+func writeZip(t *testing.T, path string, entries map[string][]byte) {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range entries {
+		f, err := w.Create(name)
+		assert.NoError(t, err)
+		_, err = f.Write(content)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, w.Close())
+	assert.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+}
+
+// tarBytes creates the content of a TAR archive with the given entries for testing.
+func tarBytes(t *testing.T, entries map[string][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := tar.NewWriter(&buf)
+	for name, content := range entries {
+		assert.NoError(t, w.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content))}))
+		_, err := w.Write(content)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+// gzipBytes compresses the content with gzip for testing.
+func gzipBytes(t *testing.T, content []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write(content)
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+// collectNames drains a channel of loaded media, returning the names of the media and the number of errors.
+func collectNames(ch <-chan Result[Media]) ([]string, int) {
+	names := make([]string, 0)
+	errors := 0
+	for r := range ch {
+		if r.Err != nil {
+			errors++
+			continue
+		}
+		names = append(names, r.Data.Name)
+	}
+	return names, errors
+}
+
+func TestSplitArchiveName(t *testing.T) {
+	t.Run("splits archive entries", func(t *testing.T) {
+		archive, entry, ok := SplitArchiveName("/backup/2019.zip!/photos/beach.jpg")
+		assert.True(t, ok)
+		assert.Equal(t, "/backup/2019.zip", archive)
+		assert.Equal(t, "photos/beach.jpg", entry)
+	})
+
+	t.Run("supports compressed tar archives", func(t *testing.T) {
+		archive, entry, ok := SplitArchiveName("old.tar.gz!/a.png")
+		assert.True(t, ok)
+		assert.Equal(t, "old.tar.gz", archive)
+		assert.Equal(t, "a.png", entry)
+	})
+
+	t.Run("regular files are not archive entries", func(t *testing.T) {
+		name, entry, ok := SplitArchiveName("/photos/wow!/beach.jpg")
+		assert.False(t, ok)
+		assert.Equal(t, "/photos/wow!/beach.jpg", name)
+		assert.Empty(t, entry)
+	})
+}
+
+func TestLoadMediaFromDirectory_Archives(t *testing.T) {
+	img := createGradientImage(64, 48)
+	content := encodePng(t, img)
+	other := encodeJpeg(t, createHalfImage(64, 48))
+
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "loose.png"), content, 0o644))
+	writeZip(t, filepath.Join(dir, "backup.zip"), map[string][]byte{
+		"photos/copy.png": content,
+		"photos/half.jpg": other,
+		"readme.txt":      []byte("not media"),
+	})
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "old.tar"), tarBytes(t, map[string][]byte{
+		"./copy.png": content,
+	}), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "older.tar.gz"), gzipBytes(t, tarBytes(t, map[string][]byte{
+		"deep/copy.png": content,
+	})), 0o644))
+
+	t.Run("archives are ignored by default", func(t *testing.T) {
+		ch, total := LoadMediaFromDirectory(dir, DirectoryOptions{})
+		names, _ := collectNames(ch)
+
+		assert.Equal(t, 1, total)
+		assert.Equal(t, []string{filepath.Join(dir, "loose.png")}, names)
+	})
+
+	t.Run("entries are named after their archives", func(t *testing.T) {
+		ch, total := LoadMediaFromDirectory(dir, DirectoryOptions{IncludeArchives: true})
+		names, errors := collectNames(ch)
+
+		assert.Equal(t, 5, total)
+		assert.Equal(t, 0, errors)
+		assert.ElementsMatch(t, []string{
+			filepath.Join(dir, "loose.png"),
+			filepath.Join(dir, "backup.zip") + "!/photos/copy.png",
+			filepath.Join(dir, "backup.zip") + "!/photos/half.jpg",
+			filepath.Join(dir, "old.tar") + "!/copy.png",
+			filepath.Join(dir, "older.tar.gz") + "!/deep/copy.png",
+		}, names)
+	})
+
+	t.Run("entries have the uncompressed size", func(t *testing.T) {
+		ch, _ := LoadMediaFromDirectory(dir, DirectoryOptions{IncludeArchives: true})
+		for r := range ch {
+			if assert.NoError(t, r.Err) && r.Data.Name != filepath.Join(dir, "backup.zip")+"!/photos/half.jpg" {
+				assert.Equal(t, int64(len(content)), r.Data.Size, r.Data.Name)
+			}
+		}
+	})
+
+	t.Run("loose files and archived copies are grouped", func(t *testing.T) {
+		ch, total := LoadMediaFromDirectory(dir, DirectoryOptions{IncludeArchives: true})
+		groups, err := collectGroups(t, LoadAndGroupMedia(ch, total, 0.9, false))
+
+		assert.NoError(t, err)
+		assert.Len(t, groups, 1)
+		assert.Len(t, groups[0], 4)
+	})
+
+	t.Run("entries pass the same filters as the files", func(t *testing.T) {
+		filterDir := t.TempDir()
+		writeZip(t, filepath.Join(filterDir, "backup.zip"), map[string][]byte{
+			"photos/copy.png":        content,
+			"photos/thumbs/copy.png": content,
+			"photos/.hidden.png":     content,
+			".cache/copy.png":        content,
+			"photos/half.jpg":        other,
+			"photos/no-extension":    content,
+		})
+		assert.NoError(t, os.WriteFile(filepath.Join(filterDir, "old.tar"), tarBytes(t, map[string][]byte{
+			"./copy.png": content,
+		}), 0o644))
+		archive := filepath.Join(filterDir, "backup.zip") + ArchiveSeparator
+
+		tests := map[string]struct {
+			options  DirectoryOptions
+			expected []string
+		}{
+			"exclude": {
+				options:  DirectoryOptions{Exclude: []string{"**/thumbs/**", "*.jpg", "old.tar"}, ExcludeHidden: true},
+				expected: []string{archive + "photos/copy.png"},
+			},
+			"include": {
+				options:  DirectoryOptions{Include: []string{"backup.zip/photos/*.jpg"}},
+				expected: []string{archive + "photos/half.jpg"},
+			},
+			"size": {
+				// The JPEG is the only media smaller than the PNG
+				options:  DirectoryOptions{MaxSize: int64(len(content)) - 1},
+				expected: []string{archive + "photos/half.jpg"},
+			},
+			"content": {
+				options: DirectoryOptions{
+					Include:     []string{"no-extension"},
+					LoadOptions: LoadOptions{DetectContent: true},
+				},
+				expected: []string{archive + "photos/no-extension"},
+			},
+		}
+
+		for name, test := range tests {
+			test.options.IncludeArchives = true
+			ch, total := LoadMediaFromDirectory(filterDir, test.options)
+			names, errors := collectNames(ch)
+
+			assert.Equal(t, len(test.expected), total, name)
+			assert.Equal(t, 0, errors, name)
+			assert.ElementsMatch(t, test.expected, names, name)
+		}
+	})
+
+	t.Run("compressed files that aren't tar archives are ignored", func(t *testing.T) {
+		gzDir := t.TempDir()
+		assert.NoError(t, os.WriteFile(filepath.Join(gzDir, "loose.png"), content, 0o644))
+		assert.NoError(t, os.WriteFile(filepath.Join(gzDir, "notes.txt.gz"), gzipBytes(t, []byte("notes")), 0o644))
+		assert.NoError(t, os.WriteFile(filepath.Join(gzDir, "photo.png.gz"), gzipBytes(t, content), 0o644))
+
+		ch, total := LoadMediaFromDirectory(gzDir, DirectoryOptions{IncludeArchives: true})
+		names, errors := collectNames(ch)
+
+		assert.Equal(t, 1, total)
+		assert.Equal(t, 0, errors)
+		assert.Equal(t, []string{filepath.Join(gzDir, "loose.png")}, names)
+	})
+
+	t.Run("broken archives are reported as errors", func(t *testing.T) {
+		brokenDir := t.TempDir()
+		assert.NoError(t, os.WriteFile(filepath.Join(brokenDir, "loose.png"), content, 0o644))
+		assert.NoError(t, os.WriteFile(filepath.Join(brokenDir, "broken.zip"), []byte("not a zip"), 0o644))
+
+		ch, total := LoadMediaFromDirectory(brokenDir, DirectoryOptions{IncludeArchives: true})
+		names, errors := collectNames(ch)
+
+		assert.Equal(t, 2, total)
+		assert.Len(t, names, 1)
+		assert.Equal(t, 1, errors)
+	})
+}
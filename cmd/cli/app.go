@@ -1,12 +1,27 @@
 package main
 
 import (
+	"bytes"
 	"cli/internal/charm"
+	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"image/jpeg"
+	"io"
+	"log/slog"
+	"os"
 	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/vegidio/go-sak/types"
 	"github.com/vegidio/mediasim"
+	"github.com/vegidio/mediasim/actions"
+	"github.com/vegidio/mediasim/evaluation"
+	"github.com/vegidio/mediasim/watch"
 )
 
 var numWorkers = runtime.NumCPU()
@@ -18,22 +33,137 @@ func (c *cmdContext) loadFiles(files []string) ([]mediasim.Media, error) {
 
 	mediaCh := mediasim.LoadMediaFromFiles(files, mediasim.FilesOptions{
 		Parallel:     numWorkers,
-		FrameOptions: mediasim.FrameOptions{FrameFlip: c.frameFlip, FrameRotate: c.frameRotate},
+		FrameOptions: c.frameOptions(),
+		LoadOptions:  c.loadOptions(),
 	})
 
 	return c.getMedia(mediaCh, len(files))
 }
 
+// frameOptions builds the frame transformation options from the flags.
+func (c *cmdContext) frameOptions() mediasim.FrameOptions {
+	return mediasim.FrameOptions{FrameFlip: c.frameFlip, FrameRotate: c.frameRotate}
+}
+
+// loadOptions builds the options to load each media from the flags.
+func (c *cmdContext) loadOptions() mediasim.LoadOptions {
+	// The sizes were already validated by the flags
+	maxImageSize, _ := parseSize(c.maxImageSize)
+	memoryBudget, _ := parseSize(c.memoryBudget)
+
+	return mediasim.LoadOptions{
+		Types:             c.types,
+		DetectContent:     c.detectContent,
+		FFmpegFallback:    c.fallback,
+		FFmpegPath:        c.ffmpegPath,
+		FFmpegTimeout:     c.ffmpegTimeout,
+		FFmpegConcurrency: c.ffmpegJobs,
+		FFmpegThreads:     c.ffmpegThreads,
+		MaxPixels:         c.maxPixels,
+		MaxFileSize:       maxImageSize,
+		MemoryBudget:      memoryBudget,
+		FastDecode:        c.fastDecode,
+		Observer:          c.observer(),
+		FrameOptions:      c.frameOptions(),
+	}
+}
+
+// directoryOptions builds the options to load the media of a directory from the flags.
+func (c *cmdContext) directoryOptions() (mediasim.DirectoryOptions, error) {
+	options := mediasim.DirectoryOptions{
+		IncludeImages:  c.mediaType != "video",
+		IncludeVideos:  c.mediaType != "image",
+		IsRecursive:    c.recursive,
+		Include:        c.filter.include,
+		Exclude:        c.filter.exclude,
+		ExcludeHidden:  c.filter.excludeHidden,
+		FollowSymlinks: c.filter.followSymlinks,
+		MaxDepth:       c.filter.maxDepth,
+		Parallel:       numWorkers,
+		FrameOptions:   c.frameOptions(),
+		LoadOptions:    c.loadOptions(),
+	}
+
+	var err error
+
+	if options.MinSize, err = parseSize(c.filter.minSize); err != nil {
+		return options, err
+	}
+
+	if options.MaxSize, err = parseSize(c.filter.maxSize); err != nil {
+		return options, err
+	}
+
+	if options.ModifiedSince, err = parseDate(c.filter.modifiedSince); err != nil {
+		return options, err
+	}
+
+	if options.ModifiedBefore, err = parseDate(c.filter.modifiedBefore); err != nil {
+		return options, err
+	}
+
+	return options, nil
+}
+
+// loadRoots loads the media of several roots, where each root can be a directory or a single file.
+func (c *cmdContext) loadRoots(roots []string) ([]mediasim.Media, error) {
+	options, err := c.directoryOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	options.IncludeArchives = c.archives
+	media := make([]mediasim.Media, 0)
+	files := make([]string, 0)
+
+	for _, root := range roots {
+		info, statErr := os.Stat(root)
+		if statErr != nil {
+			return nil, statErr
+		}
+
+		if !info.IsDir() {
+			files = append(files, root)
+			continue
+		}
+
+		mediaCh, total := mediasim.LoadMediaFromDirectory(root, options)
+		loaded, loadErr := c.getMedia(mediaCh, total)
+		if loadErr != nil {
+			return nil, loadErr
+		}
+
+		media = append(media, loaded...)
+	}
+
+	if len(files) > 0 {
+		mediaCh := mediasim.LoadMediaFromFiles(files, mediasim.FilesOptions{
+			Parallel:     numWorkers,
+			FrameOptions: options.FrameOptions,
+			LoadOptions:  options.LoadOptions,
+		})
+
+		loaded, loadErr := c.getMedia(mediaCh, len(files))
+		if loadErr != nil {
+			return nil, loadErr
+		}
+
+		media = append(media, loaded...)
+	}
+
+	return media, nil
+}
 
 func (c *cmdContext) getMedia(
 	channel <-chan types.Result[mediasim.Media],
 	total int,
 ) ([]mediasim.Media, error) {
 	media := make([]mediasim.Media, 0, total)
+	channel = c.track(channel)
 	var err error
 
 	if c.output == "report" {
-		media, err = charm.StartProgress(channel, total)
+		media, err = charm.StartProgress(channel, total, c.activity)
 		if err != nil {
 			return nil, fmt.Errorf("error loading media: %w", err)
 		}
@@ -54,20 +184,106 @@ func (c *cmdContext) getMedia(
 	return media, nil
 }
 
+// track passes the results through, recording the media that failed to load and the media whose extensions don't
+// match their content, and writing them to the NDJSON outputs.
+func (c *cmdContext) track(
+	channel <-chan types.Result[mediasim.Media],
+) <-chan types.Result[mediasim.Media] {
+	out := make(chan types.Result[mediasim.Media])
+
+	go func() {
+		defer close(out)
+
+		for r := range channel {
+			if r.Err != nil {
+				c.failures = append(c.failures, r.Err)
+			} else if r.Data.HasExtMismatch() {
+				c.mismatches = append(c.mismatches, r.Data)
+			}
+
+			for _, o := range c.outputs {
+				switch {
+				case o.stream == nil:
+				case r.Err != nil:
+					o.stream.Error(r.Err)
+				default:
+					o.stream.Media(r.Data)
+				}
+			}
+
+			out <- r
+		}
+	}()
+
+	return out
+}
+
 func calculateScore(media []mediasim.Media) float64 {
 	return mediasim.CalculateSimilarity(media[0], media[1])
 }
 
+func (c *cmdContext) groupOptions() mediasim.GroupOptions {
+	return mediasim.GroupOptions{
+		Threshold:    c.threshold,
+		IgnoreErrors: c.ignoreErrors,
+		Prefilter:    c.prefilter,
+		Order:        mediasim.GroupOrder(c.order),
+		Observer:     c.observer(),
+	}
+}
+
+// observer returns the observer of the loading and grouping events: the activity shown below the progress bars in the
+// report output and, with --log-events, a logger that writes every event to stderr.
+func (c *cmdContext) observer() mediasim.Observer {
+	observers := make([]mediasim.Observer, 0, 2)
+
+	if c.output == "report" {
+		if c.activity == nil {
+			c.activity = charm.NewActivity()
+		}
+
+		observers = append(observers, c.activity)
+	}
+
+	if c.logEvents {
+		logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		observers = append(observers, mediasim.NewSlogObserver(logger))
+	}
+
+	if len(observers) == 0 {
+		return nil
+	}
+
+	return mediasim.MultiObserver(observers...)
+}
+
 func (c *cmdContext) loadAndGroup(
 	channel <-chan types.Result[mediasim.Media],
 	total int,
 ) ([][]mediasim.Media, error) {
+	for _, o := range c.outputs {
+		if o.stream != nil {
+			o.stream.Start(total)
+		}
+	}
+
+	channel = c.track(channel)
+
 	if c.output == "report" {
-		return charm.StartLoadAndGroup(channel, total, c.threshold, c.ignoreErrors)
+		groups, stats, err := charm.StartLoadAndGroup(channel, total, c.groupOptions(), c.activity)
+		if err != nil {
+			return nil, err
+		}
+
+		c.stats = stats
+		charm.PrintGroupStats(stats)
+		charm.PrintMismatches(c.mismatches)
+		charm.PrintFailures(c.failures)
+		return groups, nil
 	}
 
 	var groups [][]mediasim.Media
-	for update := range mediasim.LoadAndGroupMedia(channel, total, c.threshold, c.ignoreErrors) {
+	for update := range mediasim.LoadAndGroupMediaWithOptions(channel, total, c.groupOptions()) {
 		if update.Err != nil {
 			if update.Done {
 				return nil, fmt.Errorf("error loading media: %w", update.Err)
@@ -77,7 +293,7 @@ func (c *cmdContext) loadAndGroup(
 		}
 
 		if update.Done {
-			groups = update.Groups
+			groups, c.stats = update.Groups, update.Stats
 		}
 	}
 
@@ -95,14 +311,406 @@ func printScore(output string, score float64) {
 	}
 }
 
-func printGroups(output string, groups [][]mediasim.Media) error {
-	switch output {
+func (c *cmdContext) printScoreMatrix(media []mediasim.Media, matrix [][]float64) error {
+	switch c.output {
+	case "report":
+		charm.PrintMismatches(c.mismatches)
+		charm.PrintFailures(c.failures)
+		charm.PrintScoreMatrixReport(media, matrix, c.threshold, c.highlight)
+	case "json":
+		return charm.PrintScoreMatrixJson(media, matrix, c.failures)
+	case "csv":
+		charm.PrintScoreMatrixCsv(media, matrix)
+	}
+
+	return nil
+}
+
+// diff finds the differences between the images and prints them, writing the heatmap to --out when it's set.
+func (c *cmdContext) diff(path1, path2 string) error {
+	if c.output == "report" {
+		charm.PrintCalculateDiff(path1, path2)
+	}
+
+	difference, err := mediasim.DiffFiles(path1, path2, mediasim.DiffOptions{
+		Threshold:   c.diffThreshold,
+		LoadOptions: c.loadOptions(),
+	})
+	if err != nil {
+		return err
+	}
+
+	heatmap := ""
+	if c.diffOut != "" {
+		if heatmap, err = expandPath(c.diffOut); err != nil {
+			return err
+		}
+
+		if err = savePng(heatmap, difference.Heatmap); err != nil {
+			return err
+		}
+	}
+
+	switch c.output {
 	case "report":
+		charm.PrintDiffReport(difference, heatmap)
+	case "json":
+		return charm.PrintDiffJson(difference, heatmap)
+	case "csv":
+		charm.PrintDiffCsv(difference)
+	}
+
+	return nil
+}
+
+// output is where the groups are written, in one of the formats of the flags.
+type output struct {
+	format string
+	writer io.Writer
+	file   *os.File
+	stream *charm.NdjsonStream
+}
+
+// openOutputs opens the outputs of the groups: the standard output, unless the format is the report, and the files of
+// --output-file. The NDJSON outputs have a stream, which writes the media while they are loaded.
+func (c *cmdContext) openOutputs() error {
+	if c.output != "report" {
+		c.outputs = append(c.outputs, newOutput(c.output, os.Stdout, nil))
+	}
+
+	for _, value := range c.outputFiles {
+		// The output files were already validated by the flag
+		format, path, _ := parseOutputFile(value)
+
+		path, err := expandPath(path)
+		if err != nil {
+			return errors.Join(err, c.closeOutputs())
+		}
+
+		file, err := os.Create(path)
+		if err != nil {
+			return errors.Join(fmt.Errorf("failed to create the output file: %w", err), c.closeOutputs())
+		}
+
+		c.outputs = append(c.outputs, newOutput(format, file, file))
+	}
+
+	return nil
+}
+
+func newOutput(format string, writer io.Writer, file *os.File) output {
+	o := output{format: format, writer: writer, file: file}
+	if format == "ndjson" {
+		o.stream = charm.NewNdjsonStream(writer)
+	}
+
+	return o
+}
+
+// closeOutputs closes the output files.
+func (c *cmdContext) closeOutputs() error {
+	var err error
+	for _, o := range c.outputs {
+		if o.file != nil {
+			err = errors.Join(err, o.file.Close())
+		}
+	}
+
+	c.outputs = nil
+	return err
+}
+
+// printGroups writes the groups to every output; the report is only printed to the terminal.
+func (c *cmdContext) printGroups(groups [][]mediasim.Media) error {
+	var previews map[string]string
+	var err error
+	paths := make([]string, 0, len(c.outputs))
+
+	if c.output == "report" {
 		charm.PrintGroupReport(groups)
+	}
+
+	for _, o := range c.outputs {
+		var outputErr error
+
+		switch o.format {
+		case "json":
+			outputErr = charm.PrintGroupJson(o.writer, groups, c.failures)
+		case "ndjson":
+			o.stream.Groups(groups, c.stats)
+			outputErr = o.stream.Err()
+		case "csv":
+			outputErr = charm.PrintGroupCsv(o.writer, groups)
+		case "html":
+			// The previews are loaded once, even when there are several HTML outputs
+			if previews == nil {
+				previews = c.previews(groups)
+			}
+
+			outputErr = charm.PrintGroupHtml(o.writer, groups, previews, c.failures)
+		}
+
+		if o.file != nil {
+			paths = append(paths, o.file.Name())
+		}
+
+		err = errors.Join(err, outputErr)
+	}
+
+	if c.output == "report" {
+		charm.PrintOutputFiles(paths)
+	}
+
+	return err
+}
+
+// previewSize is the maximum width and height of the previews of the HTML output, in pixels.
+const previewSize = 240
+
+// previews loads the previews of the media in the groups, in parallel, as JPEG data URIs. The media inside archives,
+// and the media whose previews fail to load, have no preview.
+func (c *cmdContext) previews(groups [][]mediasim.Media) map[string]string {
+	names := make(chan string)
+	previews := make(map[string]string)
+	options := c.loadOptions()
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+
+	for range numWorkers {
+		wg.Go(func() {
+			for name := range names {
+				img, err := mediasim.LoadPreview(name, previewSize, options)
+				if err != nil {
+					continue
+				}
+
+				var buffer bytes.Buffer
+				if err = jpeg.Encode(&buffer, img, &jpeg.Options{Quality: 80}); err != nil {
+					continue
+				}
+
+				mutex.Lock()
+				previews[name] = "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(buffer.Bytes())
+				mutex.Unlock()
+			}
+		})
+	}
+
+	for _, group := range groups {
+		for _, m := range group {
+			if _, _, inArchive := mediasim.SplitArchiveName(m.Name); !inArchive {
+				names <- m.Name
+			}
+		}
+	}
+
+	close(names)
+	wg.Wait()
+
+	return previews
+}
+
+// checkOutput returns an error when the output format of the flags isn't one of the outputs supported by the command.
+func (c *cmdContext) checkOutput(command string, outputs ...string) error {
+	if slices.Contains(outputs, c.output) {
+		return nil
+	}
+
+	list := strings.Join(outputs[:len(outputs)-1], ", ") + " and " + outputs[len(outputs)-1]
+	return fmt.Errorf("the %s command only supports the %s outputs", command, list)
+}
+
+func (c *cmdContext) printCross(result mediasim.CrossResult) error {
+	switch c.output {
+	case "report":
+		charm.PrintCrossReport(result.Matches)
+	case "json":
+		return charm.PrintCrossJson(result.Matches, c.failures)
+	case "csv":
+		charm.PrintCrossCsv(result.Matches)
+	}
+
+	return nil
+}
+
+// strategies returns the grouping strategies compared by the evaluation: without prefilters and, when any is set, with
+// the prefilters of the flags.
+func (c *cmdContext) strategies() []evaluation.Strategy {
+	strategies := []evaluation.Strategy{{Name: "exhaustive"}}
+	if c.prefilter.IsEnabled() {
+		strategies = append(strategies, evaluation.Strategy{Name: "prefilter", Prefilter: c.prefilter})
+	}
+
+	return strategies
+}
+
+func (c *cmdContext) printTune(advice mediasim.ThresholdAdvice) error {
+	switch c.output {
+	case "report":
+		charm.PrintMismatches(c.mismatches)
+		charm.PrintFailures(c.failures)
+		charm.PrintTuneReport(advice)
+	case "json":
+		return charm.PrintTuneJson(advice, c.failures)
+	case "csv":
+		charm.PrintTuneCsv(advice)
+	}
+
+	return nil
+}
+
+func (c *cmdContext) printEvaluation(report evaluation.Report) error {
+	switch c.output {
+	case "report":
+		charm.PrintMismatches(c.mismatches)
+		charm.PrintFailures(c.failures)
+		charm.PrintEvaluationReport(report)
+	case "json":
+		return charm.PrintEvaluationJson(report, c.failures)
+	case "csv":
+		charm.PrintEvaluationCsv(report)
+	}
+
+	return nil
+}
+
+// dedupe applies the action to the groups. The journal is written as the operations are done, so they can be undone
+// even when the action fails or is interrupted.
+func (c *cmdContext) dedupe(groups [][]mediasim.Media) error {
+	path := c.journalPath()
+	journal, err := actions.Apply(groups, actions.Options{
+		Action:     actions.Action(c.action),
+		Keep:       actions.Keep(c.keep),
+		Quarantine: c.quarantine,
+		DryRun:     c.dryRun,
+		Journal:    path,
+	})
+
+	return c.printJournal(journal, path, err)
+}
+
+// rename renames the media of the groups. The journal is written as the operations are done, so they can be undone even
+// when the renaming fails or is interrupted.
+func (c *cmdContext) rename(groups [][]mediasim.Media, directory string) error {
+	path := c.journalPath()
+	journal, err := actions.Rename(groups, actions.RenameOptions{
+		Template:       c.template,
+		FolderTemplate: c.folderTemplate,
+		Layout:         actions.Layout(c.layout),
+		Directory:      directory,
+		Collision:      actions.Collision(c.collision),
+		DryRun:         c.dryRun,
+		Journal:        path,
+	})
+
+	return c.printJournal(journal, path, err)
+}
+
+// journalPath returns the path of the journal of the operations: the one in the flag, or a new name with the time.
+func (c *cmdContext) journalPath() string {
+	if c.journal != "" {
+		return c.journal
+	}
+
+	return fmt.Sprintf("mediasim-journal-%s.json", time.Now().Format("20060102-150405"))
+}
+
+// printJournal prints the operations and the path of their journal, which is only written when something was changed.
+// The error of the operations is returned with the error of printing.
+func (c *cmdContext) printJournal(journal actions.Journal, path string, err error) error {
+	if c.dryRun || len(journal.Operations) == 0 {
+		path = ""
+	}
+
+	var printErr error
+	switch c.output {
+	case "report":
+		charm.PrintActionsReport(journal, path)
+	case "json":
+		printErr = charm.PrintActionsJson(journal, c.failures)
+	case "csv":
+		charm.PrintActionsCsv(journal)
+	}
+
+	return errors.Join(err, printErr)
+}
+
+// undo reverts the operations recorded in the journal and prints them.
+func (c *cmdContext) undo(path string) error {
+	path, err := expandPath(path)
+	if err != nil {
+		return err
+	}
+
+	journal, err := actions.ReadJournal(path)
+	if err != nil {
+		return err
+	}
+
+	result, err := actions.Undo(journal, c.dryRun)
+	if printErr := c.printUndo(result); printErr != nil {
+		return printErr
+	}
+
+	return err
+}
+
+func (c *cmdContext) printUndo(result actions.UndoResult) error {
+	switch c.output {
+	case "report":
+		charm.PrintUndoReport(result, c.dryRun)
 	case "json":
-		return charm.PrintGroupJson(groups)
+		return charm.PrintUndoJson(result)
 	case "csv":
-		charm.PrintGroupCsv(groups)
+		charm.PrintUndoCsv(result)
+	}
+
+	return nil
+}
+
+// watch reports the events of the directory until the context is cancelled, running the hook of --exec for each media
+// with matches. The hooks run one at a time, in the order of the events.
+func (c *cmdContext) watch(ctx context.Context, directory string) error {
+	options, err := c.directoryOptions()
+	if err != nil {
+		return err
+	}
+
+	events, err := watch.Watch(ctx, directory, watch.Options{
+		Threshold:        c.threshold,
+		Prefilter:        c.prefilter,
+		Index:            c.index,
+		Settle:           c.settle,
+		DirectoryOptions: options,
+	})
+	if err != nil {
+		return err
+	}
+
+	for event := range events {
+		if err = c.printWatch(event); err != nil {
+			return err
+		}
+
+		if c.exec != "" && len(event.Matches) > 0 {
+			if hookErr := runHook(ctx, c.exec, event, c.output == "report"); hookErr != nil {
+				err = c.printWatch(watch.Event{Kind: watch.KindError, Time: time.Now(), Path: event.Path, Err: hookErr})
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *cmdContext) printWatch(event watch.Event) error {
+	switch c.output {
+	case "report":
+		charm.PrintWatchReport(event)
+	case "json", "ndjson":
+		return charm.PrintWatchJson(event)
 	}
 
 	return nil
@@ -3,23 +3,93 @@ package main
 import (
 	"cli/internal/charm"
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"os/signal"
 	"shared"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/urfave/cli/v3"
 	"github.com/vegidio/go-sak/o11y"
 	"github.com/vegidio/mediasim"
+	"github.com/vegidio/mediasim/actions"
+	"github.com/vegidio/mediasim/evaluation"
+	"github.com/vegidio/mediasim/watch"
 )
 
 type cmdContext struct {
-	threshold    float64
-	output       string
-	recursive    bool
-	frameFlip    bool
-	frameRotate  bool
-	mediaType    string
-	ignoreErrors bool
-	otel         *o11y.Telemetry
+	threshold      float64
+	output         string
+	recursive      bool
+	archives       bool
+	candidates     bool
+	frameFlip      bool
+	frameRotate    bool
+	mediaType      string
+	ignoreErrors   bool
+	prefilter      mediasim.PrefilterOptions
+	filter         filterFlags
+	types          *mediasim.TypeRegistry
+	detectContent  bool
+	fallback       []string
+	ffmpegPath     string
+	ffmpegTimeout  time.Duration
+	ffmpegJobs     int
+	ffmpegThreads  int
+	maxPixels      int64
+	maxImageSize   string
+	memoryBudget   string
+	fastDecode     bool
+	order          string
+	labels         string
+	samples        int
+	bins           int
+	truth          string
+	thresholds     []float64
+	action         string
+	keep           string
+	quarantine     string
+	journal        string
+	dryRun         bool
+	template       string
+	folderTemplate string
+	deleteList     string
+	outputFiles    []string
+	highlight      bool
+	diffOut        string
+	diffThreshold  float64
+	outputs        []output
+	stats          mediasim.GroupStats
+	layout         string
+	groupsDir      string
+	collision      string
+	undoJournal    string
+	index          string
+	settle         time.Duration
+	exec           string
+	mismatches     []mediasim.Media
+	failures       []error
+	logEvents      bool
+	activity       *charm.Activity
+	otel           *o11y.Telemetry
+}
+
+// filterFlags holds the values of the flags that filter the files of a directory.
+type filterFlags struct {
+	include        []string
+	exclude        []string
+	minSize        string
+	maxSize        string
+	modifiedSince  string
+	modifiedBefore string
+	excludeHidden  bool
+	followSymlinks bool
+	maxDepth       int
 }
 
 func validateMediaType(s string) error {
@@ -30,8 +100,123 @@ func validateMediaType(s string) error {
 	return nil
 }
 
-func buildCliCommands(otel *o11y.Telemetry) *cli.Command {
-	c := &cmdContext{otel: otel}
+func validateRatio(f float64) error {
+	if f != 0 && f < 1 {
+		return fmt.Errorf("ratio must be 0 (disabled) or at least 1")
+	}
+
+	return nil
+}
+
+func validatePositive(n int) error {
+	if n < 1 {
+		return fmt.Errorf("value must be at least 1")
+	}
+
+	return nil
+}
+
+func validateSize(s string) error {
+	_, err := parseSize(s)
+	return err
+}
+
+func validateDate(s string) error {
+	_, err := parseDate(s)
+	return err
+}
+
+// outputFileFlag returns the flag of the files where the groups are also written, in other formats than the output.
+func (c *cmdContext) outputFileFlag() cli.Flag {
+	return &cli.StringSliceFlag{
+		Name:        "output-file",
+		Aliases:     []string{"of"},
+		Usage:       "also write the groups to this file; json | ndjson | csv | html, from the extension or as <format>=<path>; can be repeated",
+		Destination: &c.outputFiles,
+		Validator: func(values []string) error {
+			for _, value := range values {
+				if _, _, err := parseOutputFile(value); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	}
+}
+
+// filterFlags returns the flags that filter the files of a directory, shared by the commands that search directories.
+func (c *cmdContext) filterFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:        "include",
+			Aliases:     []string{"in"},
+			Usage:       "only compare files matching this glob pattern, e.g. '*.jpg' or 'photos/**/*.png'; can be repeated",
+			Destination: &c.filter.include,
+		},
+		&cli.StringSliceFlag{
+			Name:        "exclude",
+			Aliases:     []string{"ex"},
+			Usage:       "skip files and directories matching this glob pattern, e.g. 'cache'; can be repeated",
+			Destination: &c.filter.exclude,
+		},
+		&cli.StringFlag{
+			Name:        "min-size",
+			Usage:       "skip files smaller than this size, e.g. 10KB",
+			Destination: &c.filter.minSize,
+			Validator:   validateSize,
+		},
+		&cli.StringFlag{
+			Name:        "max-size",
+			Usage:       "skip files larger than this size, e.g. 2GB",
+			Destination: &c.filter.maxSize,
+			Validator:   validateSize,
+		},
+		&cli.StringFlag{
+			Name:        "modified-since",
+			Usage:       "skip files modified before this date; YYYY-MM-DD or RFC 3339",
+			Destination: &c.filter.modifiedSince,
+			Validator:   validateDate,
+		},
+		&cli.StringFlag{
+			Name:        "modified-before",
+			Usage:       "skip files modified at or after this date; YYYY-MM-DD or RFC 3339",
+			Destination: &c.filter.modifiedBefore,
+			Validator:   validateDate,
+		},
+		&cli.BoolFlag{
+			Name:        "exclude-hidden",
+			Usage:       "skip hidden files and hidden directories",
+			Value:       false,
+			DefaultText: "false",
+			Destination: &c.filter.excludeHidden,
+		},
+		&cli.BoolFlag{
+			Name:        "follow-symlinks",
+			Usage:       "search symlinked directories; loops are skipped",
+			Value:       false,
+			DefaultText: "false",
+			Destination: &c.filter.followSymlinks,
+		},
+		&cli.IntFlag{
+			Name:        "max-depth",
+			Usage:       "maximum number of subdirectory levels to search with -r; 0 means no limit",
+			Value:       0,
+			DefaultText: "0",
+			Destination: &c.filter.maxDepth,
+			Validator: func(i int) error {
+				if i < 0 {
+					return fmt.Errorf("max depth must not be negative")
+				}
+
+				return nil
+			},
+		},
+	}
+}
+
+func buildCliCommands(otel *o11y.Telemetry, types *mediasim.TypeRegistry) *cli.Command {
+	c := &cmdContext{otel: otel, types: types}
 
 	return &cli.Command{
 		Name:            "mediasim",
@@ -42,19 +227,34 @@ func buildCliCommands(otel *o11y.Telemetry) *cli.Command {
 		Commands: []*cli.Command{
 			{
 				Name:      "score",
-				Usage:     "calculate the similarity score of two media files",
-				UsageText: "mediasim score <file1> <file2>",
+				Usage:     "calculate the similarity score of two media files, or the matrix of scores of more files",
+				UsageText: "mediasim score <file1> <file2> [<file3> ...] [--highlight]",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:        "highlight",
+						Aliases:     []string{"hl"},
+						Usage:       "highlight the scores at or above the threshold in the matrix of scores",
+						Value:       false,
+						DefaultText: "false",
+						Destination: &c.highlight,
+					},
+				},
 				Action: func(ctx context.Context, command *cli.Command) error {
 					c.otel.LogInfo("Calculate score", map[string]any{
 						"frame.flip":   c.frameFlip,
 						"frame.rotate": c.frameRotate,
 						"output.type":  c.output,
+						"files":        command.NArg(),
 					})
 
+					if err := c.checkOutput("score", "report", "json", "csv"); err != nil {
+						return err
+					}
+
 					files := command.Args().Slice()
 
-					if len(files) != 2 {
-						return fmt.Errorf("you must specify exactly two files")
+					if len(files) < 2 {
+						return fmt.Errorf("at least two files must be specified")
 					}
 
 					files, err := expandPaths(files)
@@ -67,15 +267,82 @@ func buildCliCommands(otel *o11y.Telemetry) *cli.Command {
 						return err
 					}
 
-					score := calculateScore(media)
-					printScore(c.output, score)
-					return nil
+					if len(media) < 2 {
+						return fmt.Errorf("at least two media must be loaded to calculate their similarity")
+					}
+
+					if len(files) == 2 {
+						score := calculateScore(media)
+						printScore(c.output, score)
+						return nil
+					}
+
+					// The media are loaded in parallel, so they are sorted back to the order of the files
+					slices.SortFunc(media, func(a, b mediasim.Media) int {
+						return slices.Index(files, a.Name) - slices.Index(files, b.Name)
+					})
+
+					matrix := mediasim.CalculateSimilarityMatrix(media, mediasim.MatrixOptions{Parallel: numWorkers})
+					return c.printScoreMatrix(media, matrix)
+				},
+			},
+			{
+				Name:      "diff",
+				Usage:     "show what is different between two similar images, in a heatmap and a list of the changed regions",
+				UsageText: "mediasim diff <image1> <image2> [--out <file>] [--change-threshold <threshold>]",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:        "out",
+						Usage:       "PNG file where the heatmap of the differences is written",
+						Destination: &c.diffOut,
+					},
+					&cli.FloatFlag{
+						Name:        "change-threshold",
+						Aliases:     []string{"ct"},
+						Usage:       "difference above which an area of the images is changed; between 0-1",
+						Value:       mediasim.DefaultDiffThreshold,
+						DefaultText: strconv.FormatFloat(mediasim.DefaultDiffThreshold, 'g', -1, 64),
+						Destination: &c.diffThreshold,
+						Validator: func(f float64) error {
+							if f <= 0 || f > 1 {
+								return fmt.Errorf("change threshold must be greater than 0 and at most 1")
+							}
+
+							return nil
+						},
+					},
+				},
+				Action: func(ctx context.Context, command *cli.Command) error {
+					c.otel.LogInfo("Diff images", map[string]any{
+						"frame.flip":   c.frameFlip,
+						"frame.rotate": c.frameRotate,
+						"output.type":  c.output,
+						"heatmap":      c.diffOut != "",
+					})
+
+					if err := c.checkOutput("diff", "report", "json", "csv"); err != nil {
+						return err
+					}
+
+					files := command.Args().Slice()
+
+					if len(files) != 2 {
+						return fmt.Errorf("you must specify exactly two images")
+					}
+
+					files, err := expandPaths(files)
+					if err != nil {
+						return err
+					}
+
+					return c.diff(files[0], files[1])
 				},
 			},
 			{
 				Name:      "files",
 				Usage:     "group two or more media files based on similarity",
-				UsageText: "mediasim files <file1> <file2> [<file3> ...] ",
+				UsageText: "mediasim files <file1> <file2> [<file3> ...] [--output-file <file>]",
+				Flags:     []cli.Flag{c.outputFileFlag()},
 				Action: func(ctx context.Context, command *cli.Command) error {
 					c.otel.LogInfo("Compare files", map[string]any{
 						"frame.flip":   c.frameFlip,
@@ -94,6 +361,10 @@ func buildCliCommands(otel *o11y.Telemetry) *cli.Command {
 						return err
 					}
 
+					if err = c.openOutputs(); err != nil {
+						return err
+					}
+
 					if c.output == "report" {
 						charm.PrintCalculateFiles(len(files))
 						charm.PrintGroupingThreshold(c.threshold)
@@ -101,22 +372,24 @@ func buildCliCommands(otel *o11y.Telemetry) *cli.Command {
 
 					mediaCh := mediasim.LoadMediaFromFiles(files, mediasim.FilesOptions{
 						Parallel:     numWorkers,
-						FrameOptions: mediasim.FrameOptions{FrameFlip: c.frameFlip, FrameRotate: c.frameRotate},
+						FrameOptions: c.frameOptions(),
+						LoadOptions:  c.loadOptions(),
 					})
 
 					groups, err := c.loadAndGroup(mediaCh, len(files))
 					if err != nil {
-						return err
+						return errors.Join(err, c.closeOutputs())
 					}
 
-					return printGroups(c.output, groups)
+					return errors.Join(c.printGroups(groups), c.closeOutputs())
 				},
 			},
 			{
 				Name:      "dir",
 				Usage:     "group media files in a directory based on similarity",
-				UsageText: "mediasim dir <directory> [-r] [--mt <media-type>]",
-				Flags: []cli.Flag{
+				UsageText: "mediasim dir <directory> [-r] [--ar] [--mt <media-type>] [--output-file <file>] [filters]",
+				Flags: append([]cli.Flag{
+					c.outputFileFlag(),
 					&cli.BoolFlag{
 						Name:        "recursive",
 						Aliases:     []string{"r"},
@@ -125,6 +398,14 @@ func buildCliCommands(otel *o11y.Telemetry) *cli.Command {
 						DefaultText: "false",
 						Destination: &c.recursive,
 					},
+					&cli.BoolFlag{
+						Name:        "archives",
+						Aliases:     []string{"ar"},
+						Usage:       "also compare the media inside zip and tar (.tar, .tar.gz, .tgz) archives",
+						Value:       false,
+						DefaultText: "false",
+						Destination: &c.archives,
+					},
 					&cli.StringFlag{
 						Name:        "media-type",
 						Aliases:     []string{"mt"},
@@ -134,13 +415,14 @@ func buildCliCommands(otel *o11y.Telemetry) *cli.Command {
 						Destination: &c.mediaType,
 						Validator:   validateMediaType,
 					},
-				},
+				}, c.filterFlags()...),
 				Action: func(ctx context.Context, command *cli.Command) error {
 					c.otel.LogInfo("Compare directory", map[string]any{
 						"frame.flip":   c.frameFlip,
 						"frame.rotate": c.frameRotate,
 						"output.type":  c.output,
 						"media.type":   c.mediaType,
+						"archives":     c.archives,
 					})
 
 					directory, err := expandPath(command.Args().First())
@@ -148,35 +430,45 @@ func buildCliCommands(otel *o11y.Telemetry) *cli.Command {
 						return err
 					}
 
-					includeImages := c.mediaType != "video"
-					includeVideos := c.mediaType != "image"
+					options, err := c.directoryOptions()
+					if err != nil {
+						return err
+					}
+
+					options.IncludeArchives = c.archives
+
+					if err = c.openOutputs(); err != nil {
+						return err
+					}
 
 					if c.output == "report" {
 						charm.PrintCalculateDirectory(directory)
 						charm.PrintGroupingThreshold(c.threshold)
 					}
 
-					mediaCh, total := mediasim.LoadMediaFromDirectory(directory, mediasim.DirectoryOptions{
-						IncludeImages: includeImages,
-						IncludeVideos: includeVideos,
-						IsRecursive:   c.recursive,
-						Parallel:      numWorkers,
-						FrameOptions:  mediasim.FrameOptions{FrameFlip: c.frameFlip, FrameRotate: c.frameRotate},
-					})
+					mediaCh, total := mediasim.LoadMediaFromDirectory(directory, options)
 
 					groups, err := c.loadAndGroup(mediaCh, total)
 					if err != nil {
-						return err
+						return errors.Join(err, c.closeOutputs())
 					}
 
-					return printGroups(c.output, groups)
+					return errors.Join(c.printGroups(groups), c.closeOutputs())
 				},
 			},
 			{
 				Name:      "rename",
 				Usage:     "rename files to group them based on similarity",
-				UsageText: "mediasim group <directory> [--mt <media-type>]",
-				Flags: []cli.Flag{
+				UsageText: "mediasim rename <directory> [--template <template>] [--layout <layout>] [--dir <dir>] [--collision <collision>] [--dry-run] [--journal <file>] [-r] [--mt <media-type>] [filters]\n   mediasim rename --undo <journal> [--dry-run]",
+				Flags: append([]cli.Flag{
+					&cli.BoolFlag{
+						Name:        "recursive",
+						Aliases:     []string{"r"},
+						Usage:       "recursively search for files in the directory",
+						Value:       false,
+						DefaultText: "false",
+						Destination: &c.recursive,
+					},
 					&cli.StringFlag{
 						Name:        "media-type",
 						Aliases:     []string{"mt"},
@@ -186,36 +478,653 @@ func buildCliCommands(otel *o11y.Telemetry) *cli.Command {
 						Destination: &c.mediaType,
 						Validator:   validateMediaType,
 					},
-				},
+					&cli.StringFlag{
+						Name:        "template",
+						Usage:       "template of the new names; {group} | {id} | {rank} | {score} | {name} | {ext}",
+						Value:       actions.DefaultTemplate,
+						DefaultText: actions.DefaultTemplate,
+						Destination: &c.template,
+					},
+					&cli.StringFlag{
+						Name:        "folder-template",
+						Usage:       "template of the folders of the groups, with the folders and review layouts; {group} | {id}",
+						Value:       actions.DefaultFolderTemplate,
+						DefaultText: actions.DefaultFolderTemplate,
+						Destination: &c.folderTemplate,
+					},
+					&cli.StringFlag{
+						Name:        "layout",
+						Usage:       "where the renamed files are placed; in-place | folders (moved to a folder of each group) | review (links to the files, which aren't changed)",
+						Value:       string(actions.LayoutInPlace),
+						DefaultText: string(actions.LayoutInPlace),
+						Destination: &c.layout,
+						Validator: func(s string) error {
+							if !slices.Contains(actions.Layouts, actions.Layout(s)) {
+								return fmt.Errorf("invalid layout %q", s)
+							}
+
+							return nil
+						},
+					},
+					&cli.StringFlag{
+						Name:        "dir",
+						Usage:       "directory of the folders of the groups, with the folders and review layouts",
+						DefaultText: "<directory> for folders, <directory>-mediasim-review, next to it, for review",
+						Destination: &c.groupsDir,
+					},
+					&cli.StringFlag{
+						Name:        "collision",
+						Usage:       "what happens when a new name is taken; suffix (adds a number) | skip | fail (before any file is renamed)",
+						Value:       string(actions.CollisionSuffix),
+						DefaultText: string(actions.CollisionSuffix),
+						Destination: &c.collision,
+						Validator: func(s string) error {
+							if !slices.Contains(actions.Collisions, actions.Collision(s)) {
+								return fmt.Errorf("invalid collision %q", s)
+							}
+
+							return nil
+						},
+					},
+					&cli.BoolFlag{
+						Name:        "dry-run",
+						Aliases:     []string{"n"},
+						Usage:       "show the new names, without changing any file",
+						Value:       false,
+						DefaultText: "false",
+						Destination: &c.dryRun,
+					},
+					&cli.StringFlag{
+						Name:        "journal",
+						Usage:       "file where the operations are recorded, to be undone with --undo",
+						DefaultText: "mediasim-journal-<date>.json",
+						Destination: &c.journal,
+					},
+					&cli.StringFlag{
+						Name:        "undo",
+						Usage:       "undo the renaming recorded in the journal, instead of renaming the files",
+						Destination: &c.undoJournal,
+					},
+				}, c.filterFlags()...),
 				Action: func(ctx context.Context, command *cli.Command) error {
 					c.otel.LogInfo("Rename files", map[string]any{
 						"frame.flip":   c.frameFlip,
 						"frame.rotate": c.frameRotate,
+						"output.type":  c.output,
+						"media.type":   c.mediaType,
+						"layout":       c.layout,
+						"collision":    c.collision,
+						"dry.run":      c.dryRun,
+						"undo":         c.undoJournal != "",
+					})
+
+					if err := c.checkOutput("rename", "report", "json", "csv"); err != nil {
+						return err
+					}
+
+					if c.undoJournal != "" {
+						return c.undo(c.undoJournal)
+					}
+
+					directory, err := expandPath(command.Args().First())
+					if err != nil {
+						return err
+					}
+
+					groupsDir := c.groupsDir
+					if groupsDir != "" {
+						if groupsDir, err = expandPath(groupsDir); err != nil {
+							return err
+						}
+					}
+
+					switch {
+					case c.layout == string(actions.LayoutReview):
+						if groupsDir, err = reviewDir(directory, groupsDir); err != nil {
+							return err
+						}
+					case groupsDir == "" && c.layout == string(actions.LayoutFolders):
+						groupsDir = directory
+					}
+
+					options, err := c.directoryOptions()
+					if err != nil {
+						return err
+					}
+
+					if c.output == "report" {
+						charm.PrintCalculateDirectory(directory)
+						charm.PrintGroupingThreshold(c.threshold)
+					}
+
+					mediaCh, total := mediasim.LoadMediaFromDirectory(directory, options)
+
+					groups, err := c.loadAndGroup(mediaCh, total)
+					if err != nil {
+						return err
+					}
+
+					return c.rename(groups, groupsDir)
+				},
+			},
+			{
+				Name:      "cross",
+				Usage:     "find which candidate media already exist among the reference media",
+				UsageText: "mediasim cross <reference> <candidate1> [<candidate2> ...] [-r] [--ar] [--cc] [--mt <media-type>] [filters]",
+				Flags: append([]cli.Flag{
+					&cli.BoolFlag{
+						Name:        "recursive",
+						Aliases:     []string{"r"},
+						Usage:       "recursively search for files in the directories",
+						Value:       false,
+						DefaultText: "false",
+						Destination: &c.recursive,
+					},
+					&cli.BoolFlag{
+						Name:        "archives",
+						Aliases:     []string{"ar"},
+						Usage:       "also compare the media inside zip and tar (.tar, .tar.gz, .tgz) archives",
+						Value:       false,
+						DefaultText: "false",
+						Destination: &c.archives,
+					},
+					&cli.BoolFlag{
+						Name:        "compare-candidates",
+						Aliases:     []string{"cc"},
+						Usage:       "also compare the candidates with each other",
+						Value:       false,
+						DefaultText: "false",
+						Destination: &c.candidates,
+					},
+					&cli.StringFlag{
+						Name:        "media-type",
+						Aliases:     []string{"mt"},
+						Usage:       "type of media to compare; image | video | all",
+						Value:       "all",
+						DefaultText: "all",
+						Destination: &c.mediaType,
+						Validator:   validateMediaType,
+					},
+				}, c.filterFlags()...),
+				Action: func(ctx context.Context, command *cli.Command) error {
+					c.otel.LogInfo("Cross compare", map[string]any{
+						"frame.flip":         c.frameFlip,
+						"frame.rotate":       c.frameRotate,
+						"output.type":        c.output,
+						"media.type":         c.mediaType,
+						"archives":           c.archives,
+						"compare.candidates": c.candidates,
+					})
+
+					if err := c.checkOutput("cross", "report", "json", "csv"); err != nil {
+						return err
+					}
+
+					paths := command.Args().Slice()
+
+					if len(paths) < 2 {
+						return fmt.Errorf("you must specify a reference and at least one candidate")
+					}
+
+					paths, err := expandPaths(paths)
+					if err != nil {
+						return err
+					}
+
+					if c.output == "report" {
+						charm.PrintCalculateCross(paths[0], paths[1:])
+						charm.PrintGroupingThreshold(c.threshold)
+					}
+
+					references, err := c.loadRoots(paths[:1])
+					if err != nil {
+						return err
+					}
+
+					candidates, err := c.loadRoots(paths[1:])
+					if err != nil {
+						return err
+					}
+
+					result := mediasim.CrossCompareMedia(references, candidates, mediasim.CrossOptions{
+						GroupOptions:      c.groupOptions(),
+						CompareCandidates: c.candidates,
+					})
+
+					if c.output == "report" {
+						charm.PrintGroupStats(result.Stats)
+						charm.PrintMismatches(c.mismatches)
+						charm.PrintFailures(c.failures)
+					}
+
+					return c.printCross(result)
+				},
+			},
+			{
+				Name:      "tune",
+				Usage:     "suggest a similarity threshold based on the scores of the media in a directory",
+				UsageText: "mediasim tune <directory> [-r] [--ar] [--mt <media-type>] [--labels <file>] [filters]",
+				Flags: append([]cli.Flag{
+					&cli.BoolFlag{
+						Name:        "recursive",
+						Aliases:     []string{"r"},
+						Usage:       "recursively search for files in the directory",
+						Value:       false,
+						DefaultText: "false",
+						Destination: &c.recursive,
+					},
+					&cli.BoolFlag{
+						Name:        "archives",
+						Aliases:     []string{"ar"},
+						Usage:       "also compare the media inside zip and tar (.tar, .tar.gz, .tgz) archives",
+						Value:       false,
+						DefaultText: "false",
+						Destination: &c.archives,
+					},
+					&cli.StringFlag{
+						Name:        "media-type",
+						Aliases:     []string{"mt"},
+						Usage:       "type of media to compare; image | video | all",
+						Value:       "all",
+						DefaultText: "all",
+						Destination: &c.mediaType,
+						Validator:   validateMediaType,
+					},
+					&cli.StringFlag{
+						Name:        "labels",
+						Usage:       "CSV file of pairs labelled as duplicates or not (file1,file2,1|0) to report the precision and recall",
+						Destination: &c.labels,
+					},
+					&cli.IntFlag{
+						Name:        "samples",
+						Usage:       "maximum number of pairs to score; larger directories are sampled",
+						Value:       mediasim.DefaultTuneComparisons,
+						DefaultText: strconv.Itoa(mediasim.DefaultTuneComparisons),
+						Destination: &c.samples,
+						Validator:   validatePositive,
+					},
+					&cli.IntFlag{
+						Name:        "bins",
+						Usage:       "number of bins of the histogram of scores",
+						Value:       50,
+						DefaultText: "50",
+						Destination: &c.bins,
+						Validator:   validatePositive,
+					},
+				}, c.filterFlags()...),
+				Action: func(ctx context.Context, command *cli.Command) error {
+					c.otel.LogInfo("Tune threshold", map[string]any{
+						"frame.flip":   c.frameFlip,
+						"frame.rotate": c.frameRotate,
+						"output.type":  c.output,
+						"media.type":   c.mediaType,
+						"labels":       c.labels != "",
+					})
+
+					if err := c.checkOutput("tune", "report", "json", "csv"); err != nil {
+						return err
+					}
+
+					directory, err := expandPath(command.Args().First())
+					if err != nil {
+						return err
+					}
+
+					var pairs []mediasim.LabeledPair
+					if c.labels != "" {
+						if pairs, err = readLabels(c.labels, directory); err != nil {
+							return err
+						}
+					}
+
+					if c.output == "report" {
+						charm.PrintCalculateDirectory(directory)
+					}
+
+					media, err := c.loadRoots([]string{directory})
+					if err != nil {
+						return err
+					}
+
+					advice := mediasim.AdviseThreshold(media, mediasim.TuneOptions{
+						MaxComparisons: c.samples,
+						Bins:           c.bins,
+						Prefilter:      c.prefilter,
+						Pairs:          pairs,
+					})
+
+					if advice.Pairs == 0 {
+						return fmt.Errorf("there are no pairs of media to score")
+					}
+
+					return c.printTune(advice)
+				},
+			},
+			{
+				Name:      "eval",
+				Usage:     "measure how well the media in a directory are grouped, compared with the known duplicates",
+				UsageText: "mediasim eval <directory> --truth <file> [-r] [--ar] [--mt <media-type>] [--thresholds <t1,t2,...>] [filters]",
+				Flags: append([]cli.Flag{
+					&cli.BoolFlag{
+						Name:        "recursive",
+						Aliases:     []string{"r"},
+						Usage:       "recursively search for files in the directory",
+						Value:       false,
+						DefaultText: "false",
+						Destination: &c.recursive,
+					},
+					&cli.BoolFlag{
+						Name:        "archives",
+						Aliases:     []string{"ar"},
+						Usage:       "also compare the media inside zip and tar (.tar, .tar.gz, .tgz) archives",
+						Value:       false,
+						DefaultText: "false",
+						Destination: &c.archives,
+					},
+					&cli.StringFlag{
+						Name:        "media-type",
+						Aliases:     []string{"mt"},
+						Usage:       "type of media to compare; image | video | all",
+						Value:       "all",
+						DefaultText: "all",
+						Destination: &c.mediaType,
+						Validator:   validateMediaType,
+					},
+					&cli.StringFlag{
+						Name:        "truth",
+						Usage:       "file with the clusters of duplicates, one cluster per line with its files separated by commas",
+						Destination: &c.truth,
+					},
+					&cli.FloatSliceFlag{
+						Name:        "thresholds",
+						Usage:       "thresholds to evaluate, separated by commas",
+						DefaultText: "0.5 to 0.95, in steps of 0.05",
+						Destination: &c.thresholds,
+						Validator: func(thresholds []float64) error {
+							for _, threshold := range thresholds {
+								if threshold < 0 || threshold > 1 {
+									return fmt.Errorf("threshold must be between 0 and 1")
+								}
+							}
+
+							return nil
+						},
+					},
+				}, c.filterFlags()...),
+				Action: func(ctx context.Context, command *cli.Command) error {
+					c.otel.LogInfo("Evaluate grouping", map[string]any{
+						"frame.flip":   c.frameFlip,
+						"frame.rotate": c.frameRotate,
+						"output.type":  c.output,
 						"media.type":   c.mediaType,
 					})
 
+					if err := c.checkOutput("eval", "report", "json", "csv"); err != nil {
+						return err
+					}
+
+					if c.truth == "" {
+						return fmt.Errorf("you must specify the ground truth with --truth")
+					}
+
 					directory, err := expandPath(command.Args().First())
 					if err != nil {
 						return err
 					}
 
-					includeImages := c.mediaType != "video"
-					includeVideos := c.mediaType != "image"
+					clusters, err := readClusters(c.truth, directory)
+					if err != nil {
+						return err
+					}
+
+					if c.output == "report" {
+						charm.PrintCalculateDirectory(directory)
+					}
+
+					media, err := c.loadRoots([]string{directory})
+					if err != nil {
+						return err
+					}
 
-					mediaCh, total := mediasim.LoadMediaFromDirectory(directory, mediasim.DirectoryOptions{
-						IncludeImages: includeImages,
-						IncludeVideos: includeVideos,
-						IsRecursive:   c.recursive,
-						Parallel:      numWorkers,
-						FrameOptions:  mediasim.FrameOptions{FrameFlip: c.frameFlip, FrameRotate: c.frameRotate},
+					report := evaluation.Evaluate(media, clusters, evaluation.Options{
+						Thresholds: c.thresholds,
+						Strategies: c.strategies(),
 					})
 
+					return c.printEvaluation(report)
+				},
+			},
+			{
+				Name:      "dedupe",
+				Usage:     "keep the best media of each group and move, link, trash or delete the others",
+				UsageText: "mediasim dedupe <directory> --action <action> [--keep <keep>] [--quarantine <dir>] [--dry-run] [--journal <file>] [-r] [--mt <media-type>] [filters]\n   mediasim dedupe --delete-list <file> --action <action> [--quarantine <dir>] [--dry-run] [--journal <file>]",
+				Flags: append([]cli.Flag{
+					&cli.BoolFlag{
+						Name:        "recursive",
+						Aliases:     []string{"r"},
+						Usage:       "recursively search for files in the directory",
+						Value:       false,
+						DefaultText: "false",
+						Destination: &c.recursive,
+					},
+					&cli.StringFlag{
+						Name:        "media-type",
+						Aliases:     []string{"mt"},
+						Usage:       "type of media to compare; image | video | all",
+						Value:       "all",
+						DefaultText: "all",
+						Destination: &c.mediaType,
+						Validator:   validateMediaType,
+					},
+					&cli.StringFlag{
+						Name:        "action",
+						Usage:       "what to do with the duplicates; quarantine | hardlink | symlink | reflink | trash | delete",
+						Destination: &c.action,
+						Validator: func(s string) error {
+							if !slices.Contains(actions.Actions, actions.Action(s)) {
+								return fmt.Errorf("invalid action %q", s)
+							}
+
+							return nil
+						},
+					},
+					&cli.StringFlag{
+						Name:        "keep",
+						Usage:       "media kept in each group; best | oldest | newest | path (first alphabetically)",
+						Value:       string(actions.KeepBest),
+						DefaultText: string(actions.KeepBest),
+						Destination: &c.keep,
+						Validator: func(s string) error {
+							if !slices.Contains(actions.Keeps, actions.Keep(s)) {
+								return fmt.Errorf("invalid keep %q", s)
+							}
+
+							return nil
+						},
+					},
+					&cli.StringFlag{
+						Name:        "quarantine",
+						Usage:       "directory where the duplicates are moved; with the link actions, it keeps the originals so they can be undone",
+						Destination: &c.quarantine,
+					},
+					&cli.BoolFlag{
+						Name:        "dry-run",
+						Aliases:     []string{"n"},
+						Usage:       "show what would be done, without changing any file",
+						Value:       false,
+						DefaultText: "false",
+						Destination: &c.dryRun,
+					},
+					&cli.StringFlag{
+						Name:        "journal",
+						Usage:       "file where the operations are recorded, to be undone with 'mediasim undo'",
+						DefaultText: "mediasim-journal-<date>.json",
+						Destination: &c.journal,
+					},
+					&cli.StringFlag{
+						Name:        "delete-list",
+						Usage:       "delete list exported by the HTML output; the action is applied to the media checked in it, instead of the groups of a directory",
+						Destination: &c.deleteList,
+					},
+				}, c.filterFlags()...),
+				Action: func(ctx context.Context, command *cli.Command) error {
+					c.otel.LogInfo("Dedupe files", map[string]any{
+						"frame.flip":   c.frameFlip,
+						"frame.rotate": c.frameRotate,
+						"output.type":  c.output,
+						"media.type":   c.mediaType,
+						"action":       c.action,
+						"keep":         c.keep,
+						"dry.run":      c.dryRun,
+						"delete.list":  c.deleteList != "",
+					})
+
+					if err := c.checkOutput("dedupe", "report", "json", "csv"); err != nil {
+						return err
+					}
+
+					if c.action == "" {
+						return fmt.Errorf("you must specify the action with --action")
+					}
+
+					if c.deleteList != "" {
+						if command.IsSet("keep") {
+							return fmt.Errorf("the media kept are chosen by the delete list; --keep can't be used with it")
+						}
+
+						path, err := expandPath(c.deleteList)
+						if err != nil {
+							return err
+						}
+
+						list, err := actions.ReadDeleteList(path)
+						if err != nil {
+							return err
+						}
+
+						return c.dedupe(list.MediaGroups())
+					}
+
+					directory, err := expandPath(command.Args().First())
+					if err != nil {
+						return err
+					}
+
+					options, err := c.directoryOptions()
+					if err != nil {
+						return err
+					}
+
+					if c.output == "report" {
+						charm.PrintCalculateDirectory(directory)
+						charm.PrintGroupingThreshold(c.threshold)
+					}
+
+					mediaCh, total := mediasim.LoadMediaFromDirectory(directory, options)
+
 					groups, err := c.loadAndGroup(mediaCh, total)
 					if err != nil {
 						return err
 					}
 
-					return renameMedia(groups)
+					return c.dedupe(groups)
+				},
+			},
+			{
+				Name:      "undo",
+				Usage:     "undo the operations recorded in a journal of 'mediasim dedupe' or 'mediasim rename'",
+				UsageText: "mediasim undo <journal> [--dry-run]",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:        "dry-run",
+						Aliases:     []string{"n"},
+						Usage:       "show what would be undone, without changing any file",
+						Value:       false,
+						DefaultText: "false",
+						Destination: &c.dryRun,
+					},
+				},
+				Action: func(ctx context.Context, command *cli.Command) error {
+					c.otel.LogInfo("Undo journal", map[string]any{
+						"output.type": c.output,
+						"dry.run":     c.dryRun,
+					})
+
+					if err := c.checkOutput("undo", "report", "json", "csv"); err != nil {
+						return err
+					}
+
+					return c.undo(command.Args().First())
+				},
+			},
+			{
+				Name:      "watch",
+				Usage:     "watch a directory and report the new files that are similar to the files already in it",
+				UsageText: "mediasim watch <directory> [--index <file>] [--settle <duration>] [--exec <command>] [-r] [--mt <media-type>] [filters]",
+				Flags: append([]cli.Flag{
+					&cli.BoolFlag{
+						Name:        "recursive",
+						Aliases:     []string{"r"},
+						Usage:       "recursively watch the subdirectories",
+						Value:       false,
+						DefaultText: "false",
+						Destination: &c.recursive,
+					},
+					&cli.StringFlag{
+						Name:        "media-type",
+						Aliases:     []string{"mt"},
+						Usage:       "type of media to compare; image | video | all",
+						Value:       "all",
+						DefaultText: "all",
+						Destination: &c.mediaType,
+						Validator:   validateMediaType,
+					},
+					&cli.StringFlag{
+						Name:        "index",
+						Usage:       "file where the fingerprints are kept, so only new and modified files are loaded when the watch restarts",
+						Destination: &c.index,
+					},
+					&cli.DurationFlag{
+						Name:        "settle",
+						Usage:       "how long a file must go without changes before it's compared, so uploads are complete",
+						Value:       watch.DefaultSettle,
+						DefaultText: watch.DefaultSettle.String(),
+						Destination: &c.settle,
+					},
+					&cli.StringFlag{
+						Name:        "exec",
+						Usage:       "command run by the shell for each new file with matches; the event is in its standard input, as in the json output",
+						Destination: &c.exec,
+					},
+				}, c.filterFlags()...),
+				Action: func(ctx context.Context, command *cli.Command) error {
+					c.otel.LogInfo("Watch directory", map[string]any{
+						"frame.flip":   c.frameFlip,
+						"frame.rotate": c.frameRotate,
+						"output.type":  c.output,
+						"media.type":   c.mediaType,
+						"index":        c.index != "",
+						"exec":         c.exec != "",
+					})
+
+					if err := c.checkOutput("watch", "report", "json", "ndjson"); err != nil {
+						return err
+					}
+
+					directory, err := expandPath(command.Args().First())
+					if err != nil {
+						return err
+					}
+
+					if c.output == "report" {
+						charm.PrintWatchDirectory(directory)
+						charm.PrintGroupingThreshold(c.threshold)
+					}
+
+					ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+					defer stop()
+
+					return c.watch(ctx, directory)
 				},
 			},
 		},
@@ -250,21 +1159,165 @@ func buildCliCommands(otel *o11y.Telemetry) *cli.Command {
 				DefaultText: "false",
 				Destination: &c.frameRotate,
 			},
+			&cli.BoolFlag{
+				Name:        "detect-content",
+				Aliases:     []string{"dc"},
+				Usage:       "detect the media type from the file content, so files with wrong or missing extensions are included",
+				Value:       false,
+				DefaultText: "false",
+				Destination: &c.detectContent,
+			},
+			&cli.StringFlag{
+				Name:        "ffmpeg-path",
+				Usage:       "path to the FFmpeg executable; by default, the one in the PATH or installed by mediasim",
+				Sources:     cli.EnvVars(mediasim.FFmpegPathEnv),
+				Destination: &c.ffmpegPath,
+			},
+			&cli.DurationFlag{
+				Name:        "ffmpeg-timeout",
+				Usage:       "kill FFmpeg when it takes longer than this to decode a file (e.g. 30s); 0 for no timeout",
+				Value:       0,
+				DefaultText: "0",
+				Destination: &c.ffmpegTimeout,
+			},
+			&cli.IntFlag{
+				Name:        "ffmpeg-jobs",
+				Usage:       "maximum number of FFmpeg processes running at the same time; 0 for no limit",
+				Value:       0,
+				DefaultText: "0",
+				Destination: &c.ffmpegJobs,
+				Validator: func(value int) error {
+					if value < 0 {
+						return fmt.Errorf("the number of FFmpeg jobs can't be negative")
+					}
+
+					return nil
+				},
+			},
+			&cli.IntFlag{
+				Name:        "ffmpeg-threads",
+				Usage:       "maximum number of threads used by each FFmpeg process; 0 to let FFmpeg decide",
+				Value:       0,
+				DefaultText: "0",
+				Destination: &c.ffmpegThreads,
+				Validator: func(value int) error {
+					if value < 0 {
+						return fmt.Errorf("the number of FFmpeg threads can't be negative")
+					}
+
+					return nil
+				},
+			},
+			&cli.BoolFlag{
+				Name:        "fast-decode",
+				Usage:       "compare JPEGs using their embedded thumbnail, instead of decoding them at full resolution",
+				Value:       false,
+				DefaultText: "false",
+				Destination: &c.fastDecode,
+			},
+			&cli.Int64Flag{
+				Name:        "max-pixels",
+				Usage:       "skip images with more pixels (width × height) than this, without decoding them; -1 for no limit",
+				Value:       mediasim.DefaultMaxPixels,
+				Destination: &c.maxPixels,
+			},
+			&cli.StringFlag{
+				Name:        "max-image-size",
+				Usage:       "fail on image files larger than this size, e.g. 100MB",
+				Destination: &c.maxImageSize,
+				Validator:   validateSize,
+			},
+			&cli.StringFlag{
+				Name:        "memory-budget",
+				Usage:       "maximum memory used by the images being decoded at the same time, e.g. 2GB",
+				Destination: &c.memoryBudget,
+				Validator:   validateSize,
+			},
+			&cli.BoolFlag{
+				Name:        "log-events",
+				Aliases:     []string{"le"},
+				Usage:       "log the progress events, like each file loaded and the pairs compared, to stderr",
+				Value:       false,
+				DefaultText: "false",
+				Destination: &c.logEvents,
+			},
+			&cli.StringSliceFlag{
+				Name:        "ffmpeg-fallback",
+				Aliases:     []string{"fb"},
+				Usage:       "decode images with these extensions (e.g. .jxl) using FFmpeg when Go can't decode them; '*' for all",
+				Destination: &c.fallback,
+				Validator: func(exts []string) error {
+					for _, ext := range exts {
+						if ext != mediasim.FallbackAll && !strings.HasPrefix(ext, ".") {
+							return fmt.Errorf("invalid extension %q; it must start with a dot, like .jxl, or be '*'", ext)
+						}
+					}
+
+					return nil
+				},
+			},
 			&cli.StringFlag{
 				Name:        "output",
 				Aliases:     []string{"o"},
-				Usage:       "format how similarity is reported; report | json | csv",
+				Usage:       "format how similarity is reported; report | json | csv | ndjson, html (only files and dir)",
 				Value:       "report",
 				DefaultText: "report",
 				Destination: &c.output,
 				Validator: func(s string) error {
-					if s != "report" && s != "json" && s != "csv" {
+					if s != "report" && !slices.Contains(fileOutputs, s) {
 						return fmt.Errorf("invalid output format")
 					}
 
 					return nil
 				},
 			},
+			&cli.StringFlag{
+				Name:        "sort",
+				Usage:       "order of the groups; path (of the best file) | size (largest first) | reclaimable (most bytes first)",
+				Value:       string(mediasim.GroupOrderPath),
+				DefaultText: string(mediasim.GroupOrderPath),
+				Destination: &c.order,
+				Validator: func(s string) error {
+					if !slices.Contains(mediasim.GroupOrders, mediasim.GroupOrder(s)) {
+						return fmt.Errorf("invalid group order %q", s)
+					}
+
+					return nil
+				},
+			},
+			&cli.FloatFlag{
+				Name:        "max-duration-ratio",
+				Aliases:     []string{"mdr"},
+				Usage:       "skip videos whose durations differ by more than this ratio; 0 disables it",
+				Value:       0,
+				DefaultText: "0",
+				Destination: &c.prefilter.MaxDurationRatio,
+				Validator:   validateRatio,
+			},
+			&cli.FloatFlag{
+				Name:        "max-aspect-distance",
+				Aliases:     []string{"mad"},
+				Usage:       "skip media whose aspect ratios differ by more than this log distance; 0 disables it",
+				Value:       0,
+				DefaultText: "0",
+				Destination: &c.prefilter.MaxAspectDistance,
+				Validator: func(f float64) error {
+					if f < 0 {
+						return fmt.Errorf("aspect distance must not be negative")
+					}
+
+					return nil
+				},
+			},
+			&cli.FloatFlag{
+				Name:        "max-resolution-ratio",
+				Aliases:     []string{"mrr"},
+				Usage:       "skip media whose resolutions differ by more than this ratio; 0 disables it",
+				Value:       0,
+				DefaultText: "0",
+				Destination: &c.prefilter.MaxResolutionRatio,
+				Validator:   validateRatio,
+			},
 			&cli.BoolFlag{
 				Name:        "ignore-errors",
 				Aliases:     []string{"ie"},
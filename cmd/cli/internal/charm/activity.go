@@ -0,0 +1,74 @@
+package charm
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/vegidio/mediasim"
+)
+
+// Activity is an observer that keeps the running totals of the loading and grouping events, shown below the progress
+// bars.
+type Activity struct {
+	mu        sync.Mutex
+	startTime time.Time
+	decoded   int
+	failed    int
+	bytes     int64
+	compared  int
+	pruned    int
+}
+
+// NewActivity creates an observer that keeps the running totals of the events.
+func NewActivity() *Activity {
+	return &Activity{startTime: time.Now()}
+}
+
+func (a *Activity) OnEvent(event mediasim.Event) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	switch e := event.(type) {
+	case mediasim.FileFinishedEvent:
+		if e.Err != nil {
+			a.failed++
+			return
+		}
+
+		a.decoded++
+		a.bytes += e.Bytes
+	case mediasim.ComparisonEvent:
+		a.compared += e.Compared
+		a.pruned += e.Pruned
+	}
+}
+
+// view renders the totals, like the number of files decoded per second and the number of pairs compared.
+func (a *Activity) view() string {
+	if a == nil {
+		return ""
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	rate := 0.0
+	if elapsed := time.Since(a.startTime).Seconds(); elapsed > 0 {
+		rate = float64(a.decoded) / elapsed
+	}
+
+	detail := fmt.Sprintf("%d decoded (%s, %.1f files/s)", a.decoded, formatBytes(a.bytes), rate)
+	if a.failed > 0 {
+		detail += fmt.Sprintf(", %d failed", a.failed)
+	}
+
+	if a.compared > 0 || a.pruned > 0 {
+		detail += fmt.Sprintf(" · %d pairs compared", a.compared)
+		if a.pruned > 0 {
+			detail += fmt.Sprintf(", %d pruned", a.pruned)
+		}
+	}
+
+	return gray.Render(detail)
+}
@@ -0,0 +1,124 @@
+package charm
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"path/filepath"
+	"time"
+
+	"github.com/vegidio/mediasim"
+	"github.com/vegidio/mediasim/actions"
+)
+
+//go:embed templates/report.html
+var reportHtml string
+
+var reportTemplate = template.Must(template.New("report").Parse(reportHtml))
+
+// htmlReport is the data of the HTML output.
+type htmlReport struct {
+	Version     int
+	Reclaimable string
+	Groups      []htmlGroup
+	Errors      []string
+}
+
+// htmlGroup is a group of similar media, as it's shown in the HTML output.
+type htmlGroup struct {
+	Number      int
+	ID          string
+	Reclaimable string
+	Items       []htmlItem
+}
+
+// htmlItem is a media of a group, as it's shown in the HTML output.
+type htmlItem struct {
+	Path       string
+	Name       string
+	Preview    template.URL
+	Resolution string
+	Size       string
+	Duration   string
+	Score      string
+	Keeper     bool
+	Archived   bool
+}
+
+// PrintGroupHtml prints the groups as a self-contained HTML page, with the previews embedded, where the media to be
+// deleted can be checked and exported to a delete list read by 'mediasim dedupe --delete-list'.
+//
+// # Parameters:
+//   - w: The writer of the HTML page.
+//   - groups: The groups of similar media.
+//   - previews: The previews of the media, as data URIs, by the name of the media; media without one show a placeholder.
+//   - failures: The errors of the media that failed to load.
+func PrintGroupHtml(w io.Writer, groups [][]mediasim.Media, previews map[string]string, failures []error) error {
+	if err := reportTemplate.Execute(w, toHtmlReport(groups, previews, failures)); err != nil {
+		return fmt.Errorf("failed to write the HTML report: %w", err)
+	}
+
+	return nil
+}
+
+// toHtmlReport builds the data of the HTML output; the recommended keeper of each group is its best media outside the
+// archives.
+func toHtmlReport(groups [][]mediasim.Media, previews map[string]string, failures []error) htmlReport {
+	report := htmlReport{Version: actions.DeleteListVersion, Groups: make([]htmlGroup, 0, len(groups))}
+	reclaimable := int64(0)
+
+	for i, media := range groups {
+		group := htmlGroup{
+			Number:      i + 1,
+			ID:          mediasim.GroupID(media),
+			Reclaimable: formatBytes(mediasim.ReclaimableBytes(media)),
+			Items:       make([]htmlItem, 0, len(media)),
+		}
+
+		keeper := false
+		for j, m := range media {
+			_, _, archived := mediasim.SplitArchiveName(m.Name)
+
+			item := htmlItem{
+				Path:       m.Name,
+				Name:       filepath.Base(m.Name),
+				Preview:    template.URL(previews[m.Name]),
+				Resolution: fmt.Sprintf("%d × %d", m.Width, m.Height),
+				Size:       formatBytes(m.Size),
+				Score:      "best",
+				Archived:   archived,
+			}
+
+			// The paths are absolute, so the delete list works from any directory
+			if path, err := filepath.Abs(m.Name); err == nil && !archived {
+				item.Path = path
+			}
+
+			if m.Type == "video" {
+				item.Duration = (time.Duration(m.Length) * time.Second).String()
+			}
+
+			if j > 0 {
+				item.Score = fmt.Sprintf("%.3f", mediasim.CalculateSimilarity(media[0], m))
+			}
+
+			// The media inside archives are never deleted, so the best media outside them is kept
+			if !archived && !keeper {
+				item.Keeper, keeper = true, true
+			}
+
+			group.Items = append(group.Items, item)
+		}
+
+		reclaimable += mediasim.ReclaimableBytes(media)
+		report.Groups = append(report.Groups, group)
+	}
+
+	report.Reclaimable = formatBytes(reclaimable)
+	for _, err := range failures {
+		report.Errors = append(report.Errors, err.Error())
+	}
+
+	return report
+}
@@ -0,0 +1,63 @@
+package charm
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vegidio/mediasim"
+)
+
+func TestToHtmlReport(t *testing.T) {
+	photo, err := filepath.Abs("photo.jpg")
+	require.NoError(t, err)
+
+	groups := [][]mediasim.Media{{
+		{Name: "backup.zip!/photo.jpg", Type: "image", Width: 800, Height: 600, Size: 3000},
+		{Name: "photo.jpg", Type: "image", Width: 400, Height: 300, Size: 2048},
+		{Name: "clip.mp4", Type: "video", Width: 320, Height: 240, Size: 1024, Length: 90},
+	}}
+	previews := map[string]string{"photo.jpg": "data:image/jpeg;base64,AAAA"}
+
+	report := toHtmlReport(groups, previews, []error{errors.New("boom")})
+
+	require.Len(t, report.Groups, 1)
+	assert.Equal(t, "3.0 KiB", report.Reclaimable)
+	assert.Equal(t, []string{"boom"}, report.Errors)
+
+	items := report.Groups[0].Items
+	assert.True(t, items[0].Archived)
+	assert.False(t, items[0].Keeper, "media inside archives are never kept")
+	assert.Equal(t, "best", items[0].Score)
+
+	assert.True(t, items[1].Keeper)
+	assert.Equal(t, photo, items[1].Path)
+	assert.Equal(t, "400 × 300", items[1].Resolution)
+	assert.Equal(t, "2.0 KiB", items[1].Size)
+	assert.EqualValues(t, "data:image/jpeg;base64,AAAA", items[1].Preview)
+	assert.Empty(t, items[1].Duration)
+
+	assert.False(t, items[2].Keeper)
+	assert.Equal(t, "1m30s", items[2].Duration)
+	assert.Empty(t, items[2].Preview)
+}
+
+func TestReportTemplate(t *testing.T) {
+	groups := [][]mediasim.Media{{
+		{Name: "/photos/a.jpg", Type: "image", Width: 800, Height: 600},
+		{Name: "/photos/b.jpg", Type: "image", Width: 400, Height: 300},
+	}}
+	previews := map[string]string{"/photos/a.jpg": "data:image/jpeg;base64,AAAA"}
+
+	var buffer bytes.Buffer
+	require.NoError(t, reportTemplate.Execute(&buffer, toHtmlReport(groups, previews, nil)))
+
+	html := buffer.String()
+	assert.Contains(t, html, `<img src="data:image/jpeg;base64,AAAA"`, "the previews aren't escaped")
+	assert.Contains(t, html, `data-path="/photos/a.jpg" data-keeper>`)
+	assert.Contains(t, html, `data-path="/photos/b.jpg" checked>`)
+	assert.Contains(t, html, "version:  1 ,")
+}
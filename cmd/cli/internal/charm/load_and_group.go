@@ -33,7 +33,9 @@ type loadAndGroupModel struct {
 	total         int
 	loaded        int
 	groups        [][]mediasim.Media
+	stats         mediasim.GroupStats
 	err           error
+	activity      *Activity
 	startTime     time.Time
 	lastEtaUpdate time.Time
 	eta           time.Duration
@@ -75,6 +77,7 @@ func (m *loadAndGroupModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 			m.groups = update.Groups
+			m.stats = update.Stats
 			barCmd := m.progress.SetPercent(1)
 			m.eta = time.Duration(0)
 			return m, barCmd
@@ -138,10 +141,23 @@ func (m *loadAndGroupModel) View() string {
 		m.progress.View(),
 		green.Render(percentStr),
 		magenta.Render(fmt.Sprintf("ETA %v", eta)),
-	)
+	) + m.activityView()
+}
+
+// activityView renders the totals of the activity below the progress bar, if there's one.
+func (m *loadAndGroupModel) activityView() string {
+	if m.activity == nil {
+		return ""
+	}
+
+	return m.activity.view() + "\n"
 }
 
-func initLoadAndGroupModel(updateCh <-chan mediasim.LoadAndGroupResult, total int) *loadAndGroupModel {
+func initLoadAndGroupModel(
+	updateCh <-chan mediasim.LoadAndGroupResult,
+	total int,
+	activity *Activity,
+) *loadAndGroupModel {
 	p := progress.New(
 		progress.WithDefaultGradient(),
 		progress.WithoutPercentage(),
@@ -152,34 +168,36 @@ func initLoadAndGroupModel(updateCh <-chan mediasim.LoadAndGroupResult, total in
 		progress:      p,
 		updateCh:      updateCh,
 		total:         total,
+		activity:      activity,
 		startTime:     time.Now(),
 		lastEtaUpdate: time.Now(),
 		eta:           time.Duration(0),
 	}
 }
 
-// StartLoadAndGroup runs a combined load-and-group operation with a progress bar TUI.
+// StartLoadAndGroup runs a combined load-and-group operation with a progress bar TUI. When activity is not nil, it
+// must also observe the loading and the grouping, and its totals are shown below the progress bar.
 func StartLoadAndGroup(
 	channel <-chan types.Result[mediasim.Media],
 	total int,
-	threshold float64,
-	ignoreErrors bool,
-) ([][]mediasim.Media, error) {
-	updateCh := mediasim.LoadAndGroupMedia(channel, total, threshold, ignoreErrors)
+	options mediasim.GroupOptions,
+	activity *Activity,
+) ([][]mediasim.Media, mediasim.GroupStats, error) {
+	updateCh := mediasim.LoadAndGroupMediaWithOptions(channel, total, options)
 
-	model, err := tea.NewProgram(initLoadAndGroupModel(updateCh, total)).Run()
+	model, err := tea.NewProgram(initLoadAndGroupModel(updateCh, total, activity)).Run()
 	if err != nil {
-		return nil, err
+		return nil, mediasim.GroupStats{}, err
 	}
 
 	m, ok := model.(*loadAndGroupModel)
 	if !ok {
-		return nil, fmt.Errorf("unexpected model type from load-and-group program")
+		return nil, mediasim.GroupStats{}, fmt.Errorf("unexpected model type from load-and-group program")
 	}
 
 	if m.err != nil {
-		return nil, m.err
+		return nil, mediasim.GroupStats{}, m.err
 	}
 
-	return m.groups, nil
+	return m.groups, m.stats, nil
 }
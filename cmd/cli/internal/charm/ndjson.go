@@ -0,0 +1,106 @@
+package charm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/vegidio/mediasim"
+)
+
+// NdjsonStream writes the results of a run as they happen, as one line of JSON for each file loaded or failed, then one
+// line for each group and a last line with the totals. Every line has a "type": start, media, error, group or end.
+type NdjsonStream struct {
+	mutex   sync.Mutex
+	encoder *json.Encoder
+	loaded  int
+	failed  int
+	err     error
+}
+
+// NewNdjsonStream creates a stream that writes the lines to the writer.
+func NewNdjsonStream(w io.Writer) *NdjsonStream {
+	return &NdjsonStream{encoder: json.NewEncoder(w)}
+}
+
+// Start writes the first line, with the version of the schema and the number of files to be loaded.
+func (s *NdjsonStream) Start(total int) {
+	s.write(struct {
+		Type    string `json:"type"`
+		Version int    `json:"version"`
+		Total   int    `json:"total"`
+	}{"start", jsonVersion, total})
+}
+
+// Media writes the line of a media that was loaded.
+func (s *NdjsonStream) Media(media mediasim.Media) {
+	s.mutex.Lock()
+	s.loaded++
+	done := s.loaded + s.failed
+	s.mutex.Unlock()
+
+	s.write(struct {
+		Type  string         `json:"type"`
+		Done  int            `json:"done"`
+		Media mediasim.Media `json:"media"`
+	}{"media", done, media})
+}
+
+// Error writes the line of a media that failed to load.
+func (s *NdjsonStream) Error(err error) {
+	s.mutex.Lock()
+	s.failed++
+	done := s.loaded + s.failed
+	s.mutex.Unlock()
+
+	s.write(struct {
+		Type  string    `json:"type"`
+		Done  int       `json:"done"`
+		Error errorJson `json:"error"`
+	}{"error", done, toErrorJson([]error{err})[0]})
+}
+
+// Groups writes one line for each group, followed by the last line, with the totals of the run.
+func (s *NdjsonStream) Groups(groups [][]mediasim.Media, stats mediasim.GroupStats) {
+	for i, group := range toGroupJson(groups) {
+		s.write(struct {
+			Type   string `json:"type"`
+			Number int    `json:"number"`
+			groupJson
+		}{"group", i + 1, group})
+	}
+
+	s.mutex.Lock()
+	loaded, failed := s.loaded, s.failed
+	s.mutex.Unlock()
+
+	s.write(struct {
+		Type   string `json:"type"`
+		Loaded int    `json:"loaded"`
+		Failed int    `json:"failed"`
+		Groups int    `json:"groups"`
+		mediasim.GroupStats
+	}{"end", loaded, failed, len(groups), stats})
+}
+
+// Err returns the error of the first line that failed to be written, if any.
+func (s *NdjsonStream) Err() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.err
+}
+
+func (s *NdjsonStream) write(line any) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.err != nil {
+		return
+	}
+
+	if err := s.encoder.Encode(line); err != nil {
+		s.err = fmt.Errorf("failed to write the NDJSON output: %w", err)
+	}
+}
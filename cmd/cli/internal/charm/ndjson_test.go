@@ -0,0 +1,61 @@
+package charm
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vegidio/mediasim"
+)
+
+func TestNdjsonStream(t *testing.T) {
+	var buffer bytes.Buffer
+	stream := NewNdjsonStream(&buffer)
+
+	a := mediasim.Media{Name: "a.jpg", Type: "image", Size: 200}
+	b := mediasim.Media{Name: "b.jpg", Type: "image", Size: 100}
+
+	stream.Start(3)
+	stream.Media(a)
+	stream.Error(&mediasim.MediaError{Path: "c.jpg", Kind: mediasim.ErrDecode})
+	stream.Media(b)
+	stream.Groups([][]mediasim.Media{{a, b}}, mediasim.GroupStats{Compared: 1, Unions: 1})
+	require.NoError(t, stream.Err())
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	require.Len(t, lines, 6)
+
+	types := make([]string, 0, len(lines))
+	for _, line := range lines {
+		var typed struct {
+			Type string `json:"type"`
+		}
+
+		require.NoError(t, json.Unmarshal([]byte(line), &typed), line)
+		types = append(types, typed.Type)
+	}
+
+	assert.Equal(t, []string{"start", "media", "error", "media", "group", "end"}, types)
+	assert.JSONEq(t, `{"type":"start","version":1,"total":3}`, lines[0])
+	assert.Contains(t, lines[2], `"done":2,"error":{"path":"c.jpg","category":"decode"`)
+	assert.Contains(t, lines[4], `"number":1,"id":"`+mediasim.GroupID([]mediasim.Media{a, b})+`","reclaimable":100`)
+	assert.JSONEq(t, `{"type":"end","loaded":2,"failed":1,"groups":1,"compared":1,"pruned":0,"unions":1}`, lines[5])
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestNdjsonStream_Err(t *testing.T) {
+	stream := NewNdjsonStream(failingWriter{})
+	stream.Start(1)
+	stream.Media(mediasim.Media{Name: "a.jpg"})
+
+	assert.ErrorContains(t, stream.Err(), "disk full")
+}
@@ -2,12 +2,24 @@ package charm
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/vegidio/mediasim"
+	"github.com/vegidio/mediasim/actions"
+	"github.com/vegidio/mediasim/evaluation"
+	"github.com/vegidio/mediasim/watch"
 )
 
+// jsonVersion is the version of the schema of the JSON outputs; it changes when a field is changed or removed.
+const jsonVersion = 1
+
 func PrintError(message string, a ...interface{}) {
 	format := fmt.Sprintf(message, a...)
 	fmt.Printf("\n🧨 %s\n", red.Render(format))
@@ -19,13 +31,61 @@ func PrintScoreReport(score float64) {
 }
 
 func PrintScoreJson(score float64) {
-	fmt.Printf("{\n  \"score\": %.5f\n}", score)
+	fmt.Printf("{\n  \"version\": %d,\n  \"score\": %.5f\n}", jsonVersion, score)
 }
 
 func PrintScoreCsv(score float64) {
 	fmt.Printf("%.5f", score)
 }
 
+func PrintScoreMatrixReport(media []mediasim.Media, matrix [][]float64, threshold float64, highlight bool) {
+	fmt.Printf("\n🧮 Similarity scores between the %s files", green.Render(strconv.Itoa(len(media))))
+	if highlight {
+		fmt.Printf("; the scores of at least %s are highlighted", yellow.Render(fmt.Sprintf("%.5g", threshold)))
+	}
+
+	fmt.Print("\n\n")
+	for _, line := range matrixLines(media, matrix, threshold, highlight) {
+		fmt.Println(line)
+	}
+}
+
+func PrintScoreMatrixJson(media []mediasim.Media, matrix [][]float64, failures []error) error {
+	output := struct {
+		Version int              `json:"version"`
+		Media   []mediasim.Media `json:"media"`
+		Scores  [][]float64      `json:"scores"`
+		Errors  []errorJson      `json:"errors"`
+	}{jsonVersion, media, matrix, toErrorJson(failures)}
+
+	jsonBytes, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal the scores to JSON: %w", err)
+	}
+
+	fmt.Println(string(jsonBytes))
+	return nil
+}
+
+// PrintScoreMatrixCsv prints the matrix with a header of the names of the media, and the name of the media in the first
+// column of each row.
+func PrintScoreMatrixCsv(media []mediasim.Media, matrix [][]float64) {
+	names := make([]string, 0, len(media))
+	for _, m := range media {
+		names = append(names, m.Name)
+	}
+
+	fmt.Printf(",%s\n", strings.Join(names, ","))
+	for i, row := range matrix {
+		scores := make([]string, 0, len(row))
+		for _, score := range row {
+			scores = append(scores, fmt.Sprintf("%.5f", score))
+		}
+
+		fmt.Printf("%s,%s\n", names[i], strings.Join(scores, ","))
+	}
+}
+
 func PrintCalculateFiles(amount int) {
 	fmt.Printf("\n⏳ Calculating similarity in %s files\n", green.Render(strconv.Itoa(amount)))
 }
@@ -34,14 +94,137 @@ func PrintCalculateDirectory(dir string) {
 	fmt.Printf("\n⏳ Calculating similarity in the directory %s\n", green.Render(dir))
 }
 
+func PrintWatchDirectory(dir string) {
+	fmt.Printf("\n👀 Indexing the media of the directory %s\n", green.Render(dir))
+}
+
+func PrintCalculateDiff(path1, path2 string) {
+	fmt.Printf("\n⏳ Finding the differences between %s and %s\n", green.Render(path1), green.Render(path2))
+}
+
+func PrintCalculateCross(reference string, candidates []string) {
+	fmt.Printf("\n⏳ Comparing %s against the reference %s\n",
+		green.Render(strings.Join(candidates, ", ")), green.Render(reference))
+}
+
 func PrintGroupingThreshold(threshold float64) {
 	temp := fmt.Sprintf("%.5g", threshold)
 	fmt.Printf("🔎 Grouping media with at least %s similarity threshold...\n", yellow.Render(temp))
 }
 
+func PrintGroupStats(stats mediasim.GroupStats) {
+	if stats.Pruned == 0 {
+		fmt.Printf("\n📊 Compared %s pairs\n", green.Render(strconv.Itoa(stats.Compared)))
+		return
+	}
+
+	fmt.Printf("\n📊 Compared %s pairs; %s pairs were pruned by the prefilters\n",
+		green.Render(strconv.Itoa(stats.Compared)), yellow.Render(strconv.Itoa(stats.Pruned)))
+}
+
+func PrintMismatches(media []mediasim.Media) {
+	if len(media) == 0 {
+		return
+	}
+
+	fmt.Printf("\n⚠️  %s files have extensions that don't match their content:\n",
+		yellow.Render(strconv.Itoa(len(media))))
+
+	for _, m := range media {
+		fmt.Printf("  -> %s is %s\n", m.Name, yellow.Render(m.ContentExt))
+	}
+}
+
+// PrintFailures lists the media that failed to load and were skipped, with the number of failures of each category.
+func PrintFailures(failures []error) {
+	if len(failures) == 0 {
+		return
+	}
+
+	counts := make(map[string]int)
+	for _, err := range failures {
+		counts[mediasim.ErrorCategory(err)]++
+	}
+
+	categories := make([]string, 0, len(counts))
+	for category, count := range counts {
+		categories = append(categories, fmt.Sprintf("%d %s", count, category))
+	}
+
+	slices.Sort(categories)
+	fmt.Printf("\n⚠️  %s files failed to load and were skipped (%s):\n",
+		yellow.Render(strconv.Itoa(len(failures))), strings.Join(categories, ", "))
+
+	for _, err := range failures {
+		fmt.Printf("  -> %s\n", err.Error())
+	}
+}
+
+// PrintOutputFiles lists the files where the results were also written.
+func PrintOutputFiles(paths []string) {
+	for _, path := range paths {
+		fmt.Printf("\n💾 The results were also written to %s", green.Render(path))
+	}
+
+	if len(paths) > 0 {
+		fmt.Println()
+	}
+}
+
+func PrintDiffReport(difference mediasim.Difference, heatmap string) {
+	score := magenta.Render(fmt.Sprintf("%.5g", difference.Score))
+	if difference.Transform == mediasim.TransformNone {
+		fmt.Printf("\n🧮 Similarity score between the images is %s\n", score)
+	} else {
+		fmt.Printf("\n🧮 Similarity score between the images is %s, with the second image aligned by %s\n", score,
+			yellow.Render(string(difference.Transform)))
+	}
+
+	if len(difference.Regions) == 0 {
+		fmt.Println("\n✅ No area of the images is different")
+	} else {
+		fmt.Printf("\n🔥 %s regions are different, with %s of the area:\n",
+			magenta.Render(strconv.Itoa(len(difference.Regions))),
+			magenta.Render(fmt.Sprintf("%.1f%%", difference.Changed*100)))
+
+		for _, region := range difference.Regions {
+			fmt.Printf("  -> %d×%d at (%d, %d), difference %s\n", region.Width, region.Height, region.X, region.Y,
+				yellow.Render(fmt.Sprintf("%.3f", region.Difference)))
+		}
+	}
+
+	if heatmap != "" {
+		fmt.Printf("\n💾 The heatmap of the differences was written to %s\n", green.Render(heatmap))
+	} else {
+		fmt.Printf("\n%s\n", gray.Render("Use --out <file.png> to see the differences in a heatmap"))
+	}
+}
+
+func PrintDiffJson(difference mediasim.Difference, heatmap string) error {
+	output := struct {
+		Version int `json:"version"`
+		mediasim.Difference
+		Heatmap string `json:"heatmap,omitempty"`
+	}{jsonVersion, difference, heatmap}
+
+	jsonBytes, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal the differences to JSON: %w", err)
+	}
+
+	fmt.Println(string(jsonBytes))
+	return nil
+}
+
+func PrintDiffCsv(difference mediasim.Difference) {
+	for _, region := range difference.Regions {
+		fmt.Printf("%d,%d,%d,%d,%.5f\n", region.X, region.Y, region.Width, region.Height, region.Difference)
+	}
+}
+
 func PrintGroupReport(groups [][]mediasim.Media) {
 	for i, media := range groups {
-		fmt.Printf("\nGroup %s:\n", magenta.Render(strconv.Itoa(i+1)))
+		fmt.Printf("\nGroup %s %s:\n", magenta.Render(strconv.Itoa(i+1)), gray.Render(mediasim.GroupID(media)))
 
 		// Best media
 		best := media[0]
@@ -53,24 +236,501 @@ func PrintGroupReport(groups [][]mediasim.Media) {
 	}
 }
 
-func PrintGroupJson(groups [][]mediasim.Media) error {
-	jsonBytes, err := json.MarshalIndent(groups, "", "  ")
+// groupJson is a group of similar media, as it's shown in the JSON output.
+type groupJson struct {
+	ID          string           `json:"id"`
+	Reclaimable int64            `json:"reclaimable"`
+	Media       []mediasim.Media `json:"media"`
+}
+
+func toGroupJson(groups [][]mediasim.Media) []groupJson {
+	result := make([]groupJson, 0, len(groups))
+
+	for _, media := range groups {
+		result = append(result, groupJson{
+			ID:          mediasim.GroupID(media),
+			Reclaimable: mediasim.ReclaimableBytes(media),
+			Media:       media,
+		})
+	}
+
+	return result
+}
+
+func PrintGroupJson(w io.Writer, groups [][]mediasim.Media, failures []error) error {
+	output := struct {
+		Version int         `json:"version"`
+		Groups  []groupJson `json:"groups"`
+		Errors  []errorJson `json:"errors"`
+	}{jsonVersion, toGroupJson(groups), toErrorJson(failures)}
+
+	jsonBytes, err := json.MarshalIndent(output, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal groups to JSON: %w", err)
 	}
 
-	fmt.Println(string(jsonBytes))
+	if _, err = fmt.Fprintln(w, string(jsonBytes)); err != nil {
+		return fmt.Errorf("failed to write the JSON output: %w", err)
+	}
+
 	return nil
 }
 
-func PrintGroupCsv(groups [][]mediasim.Media) {
+func PrintGroupCsv(w io.Writer, groups [][]mediasim.Media) error {
 	for i, media := range groups {
+		id := mediasim.GroupID(media)
 		for _, m := range media {
-			fmt.Printf("Group %d,%s\n", i+1, m.Name)
+			if _, err := fmt.Fprintf(w, "Group %d,%s,%s\n", i+1, m.Name, id); err != nil {
+				return fmt.Errorf("failed to write the CSV output: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func PrintCrossReport(matches []mediasim.CrossMatch) {
+	found := 0
+
+	for _, match := range matches {
+		if match.Reference == nil {
+			continue
+		}
+
+		found++
+		score := fmt.Sprintf("%.5g", match.Score)
+		fmt.Printf("\n%s %s\n", bold.Render(match.Candidate.Name), mediaInfo(match.Candidate))
+		fmt.Printf("  -> %s %s, score %s\n", match.Reference.Name, mediaInfo(*match.Reference), magenta.Render(score))
+	}
+
+	fmt.Printf("\n📦 %s of %s candidates already exist in the reference\n",
+		magenta.Render(strconv.Itoa(found)), green.Render(strconv.Itoa(len(matches))))
+}
+
+func PrintCrossJson(matches []mediasim.CrossMatch, failures []error) error {
+	output := struct {
+		Version int                   `json:"version"`
+		Matches []mediasim.CrossMatch `json:"matches"`
+		Errors  []errorJson           `json:"errors"`
+	}{jsonVersion, matches, toErrorJson(failures)}
+
+	jsonBytes, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal matches to JSON: %w", err)
+	}
+
+	fmt.Println(string(jsonBytes))
+	return nil
+}
+
+func PrintCrossCsv(matches []mediasim.CrossMatch) {
+	for _, match := range matches {
+		if match.Reference == nil {
+			fmt.Printf("%s,,\n", match.Candidate.Name)
+			continue
+		}
+
+		fmt.Printf("%s,%s,%.5f\n", match.Candidate.Name, match.Reference.Name, match.Score)
+	}
+}
+
+// histogramWidth is the number of characters of the longest bar of the histogram.
+const histogramWidth = 40
+
+func PrintTuneReport(advice mediasim.ThresholdAdvice) {
+	fmt.Printf("\n📊 Scored %s pairs\n\n", green.Render(strconv.Itoa(advice.Pairs)))
+
+	for _, line := range histogramLines(advice.Histogram) {
+		fmt.Println(line)
+	}
+
+	if advice.Tradeoff != nil {
+		fmt.Printf("\n%-10s %-10s %-10s %s\n", "Threshold", "Precision", "Recall", "F1")
+
+		for _, m := range advice.Tradeoff {
+			// Only every 0.05 from 0.5, and the suggested threshold, to keep the table short
+			step := int(math.Round(m.Threshold * 100))
+			if (step < 50 || step%5 != 0) && m.Threshold != advice.Threshold {
+				continue
+			}
+
+			line := fmt.Sprintf("%-10.2f %-10.3f %-10.3f %.3f", m.Threshold, m.Precision, m.Recall, m.F1)
+			if m.Threshold == advice.Threshold {
+				line = bold.Render(line + " ←")
+			}
+
+			fmt.Println(line)
+		}
+
+		if advice.Missing > 0 {
+			fmt.Printf("\n⚠️  %s labelled pairs were ignored because their files weren't found\n",
+				yellow.Render(strconv.Itoa(advice.Missing)))
+		}
+	} else if advice.Separated {
+		fmt.Printf("\nMost pairs score around %s and the near-duplicates around %s; the gap between them is %s\n",
+			yellow.Render(fmt.Sprintf("%.2f", advice.BackgroundMode)),
+			green.Render(fmt.Sprintf("%.2f", advice.NearDuplicateMode)),
+			magenta.Render(fmt.Sprintf("%.2f–%.2f", advice.GapLow, advice.GapHigh)))
+	} else {
+		fmt.Printf("\n⚠️  The near-duplicates aren't clearly apart from the other pairs; the threshold keeps only the " +
+			"0.1%% most similar pairs\n")
+	}
+
+	fmt.Printf("\n💡 Suggested threshold: %s (use it with -t %.2f)\n",
+		magenta.Render(fmt.Sprintf("%.2f", advice.Threshold)), advice.Threshold)
+}
+
+func PrintTuneJson(advice mediasim.ThresholdAdvice, failures []error) error {
+	output := struct {
+		Version int `json:"version"`
+		mediasim.ThresholdAdvice
+		Errors []errorJson `json:"errors"`
+	}{jsonVersion, advice, toErrorJson(failures)}
+
+	jsonBytes, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal the threshold advice to JSON: %w", err)
+	}
+
+	fmt.Println(string(jsonBytes))
+	return nil
+}
+
+func PrintTuneCsv(advice mediasim.ThresholdAdvice) {
+	if advice.Tradeoff != nil {
+		for _, m := range advice.Tradeoff {
+			fmt.Printf("%.2f,%.5f,%.5f,%.5f\n", m.Threshold, m.Precision, m.Recall, m.F1)
+		}
+
+		return
+	}
+
+	for _, bin := range advice.Histogram {
+		fmt.Printf("%.2f,%.2f,%d\n", bin.Min, bin.Max, bin.Count)
+	}
+}
+
+func PrintEvaluationReport(report evaluation.Report) {
+	fmt.Printf("\n📊 Evaluated %s media in %s clusters of duplicates\n",
+		green.Render(strconv.Itoa(report.Media)), green.Render(strconv.Itoa(report.Clusters)))
+
+	if len(report.Missing) > 0 {
+		fmt.Printf("\n⚠️  %s files of the ground truth weren't found and were ignored:\n",
+			yellow.Render(strconv.Itoa(len(report.Missing))))
+
+		for _, name := range report.Missing {
+			fmt.Printf("  -> %s\n", name)
+		}
+	}
+
+	for _, best := range report.Best {
+		fmt.Printf("\nStrategy %s:\n", magenta.Render(best.Strategy))
+		fmt.Printf("%-10s %-10s %-10s %-10s %-10s %s\n", "Threshold", "Precision", "Recall", "F1", "Purity", "Groups")
+
+		for _, r := range report.Results {
+			if r.Strategy != best.Strategy {
+				continue
+			}
+
+			line := fmt.Sprintf("%-10.2f %-10.3f %-10.3f %-10.3f %-10.3f %d",
+				r.Threshold, r.Precision, r.Recall, r.F1, r.Purity, r.Groups)
+			if r.Threshold == best.Threshold {
+				line = bold.Render(line + " ←")
+			}
+
+			fmt.Println(line)
+		}
+	}
+
+	for _, best := range report.Best {
+		fmt.Printf("\n🏆 Best F1 of %s is %s, with the threshold %s\n", best.Strategy,
+			magenta.Render(fmt.Sprintf("%.3f", best.F1)), magenta.Render(fmt.Sprintf("%.2f", best.Threshold)))
+	}
+}
+
+func PrintEvaluationJson(report evaluation.Report, failures []error) error {
+	output := struct {
+		Version int `json:"version"`
+		evaluation.Report
+		Errors []errorJson `json:"errors"`
+	}{jsonVersion, report, toErrorJson(failures)}
+
+	jsonBytes, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal the evaluation to JSON: %w", err)
+	}
+
+	fmt.Println(string(jsonBytes))
+	return nil
+}
+
+func PrintEvaluationCsv(report evaluation.Report) {
+	for _, r := range report.Results {
+		fmt.Printf("%s,%.2f,%.5f,%.5f,%.5f,%.5f,%d\n",
+			r.Strategy, r.Threshold, r.Precision, r.Recall, r.F1, r.Purity, r.Groups)
+	}
+}
+
+func PrintActionsReport(journal actions.Journal, journalPath string) {
+	if len(journal.Operations) == 0 {
+		fmt.Println("\n✅ There are no duplicates to resolve")
+		return
+	}
+
+	for _, op := range journal.Operations {
+		fmt.Printf("  -> %s %s\n", magenta.Render(string(op.Action)), operationDetails(op))
+	}
+
+	if journal.DryRun {
+		fmt.Printf("\n🔍 Dry run: %s files would be changed; run again without --dry-run to apply it\n",
+			yellow.Render(strconv.Itoa(len(journal.Operations))))
+		return
+	}
+
+	fmt.Printf("\n✅ %s files were changed; the journal %s can undo it with 'mediasim undo'\n",
+		green.Render(strconv.Itoa(len(journal.Operations))), green.Render(journalPath))
+}
+
+func PrintActionsJson(journal actions.Journal, failures []error) error {
+	output := struct {
+		Version int `json:"version"`
+		actions.Journal
+		Errors []errorJson `json:"errors"`
+	}{jsonVersion, journal, toErrorJson(failures)}
+
+	jsonBytes, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal the journal to JSON: %w", err)
+	}
+
+	fmt.Println(string(jsonBytes))
+	return nil
+}
+
+func PrintActionsCsv(journal actions.Journal) {
+	for _, op := range journal.Operations {
+		fmt.Printf("%s,%s,%s,%s,%s\n", op.Action, op.Path, op.Keep, op.Backup, op.Target)
+	}
+}
+
+func PrintUndoReport(result actions.UndoResult, dryRun bool) {
+	fmt.Println()
+	for _, op := range result.Restored {
+		fmt.Printf("  -> %s %s\n", green.Render("restore"), op.Path)
+	}
+
+	for _, op := range result.Irreversible {
+		fmt.Printf("  -> %s %s was deleted by the %s\n", red.Render("lost"), op.Path, op.Action)
+	}
+
+	verb := "were restored"
+	if dryRun {
+		verb = "would be restored"
+	}
+
+	fmt.Printf("\n↩️  %s files %s", green.Render(strconv.Itoa(len(result.Restored))), verb)
+	if len(result.Irreversible) > 0 {
+		fmt.Printf("; %s files can't be restored", red.Render(strconv.Itoa(len(result.Irreversible))))
+	}
+
+	fmt.Println()
+}
+
+func PrintUndoJson(result actions.UndoResult) error {
+	output := struct {
+		Version int `json:"version"`
+		actions.UndoResult
+	}{jsonVersion, result}
+
+	jsonBytes, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal the undo result to JSON: %w", err)
+	}
+
+	fmt.Println(string(jsonBytes))
+	return nil
+}
+
+func PrintUndoCsv(result actions.UndoResult) {
+	for _, op := range result.Restored {
+		fmt.Printf("restored,%s\n", op.Path)
+	}
+
+	for _, op := range result.Irreversible {
+		fmt.Printf("irreversible,%s\n", op.Path)
+	}
+}
+
+func PrintWatchReport(event watch.Event) {
+	clock := gray.Render(event.Time.Local().Format(time.TimeOnly))
+
+	switch event.Kind {
+	case watch.KindReady:
+		fmt.Printf("\n👀 %s media indexed; watching for new files, press Ctrl+C to stop\n",
+			green.Render(strconv.Itoa(event.Indexed)))
+	case watch.KindAdded, watch.KindModified:
+		if len(event.Matches) == 0 {
+			fmt.Printf("%s %s %s %s\n", clock, green.Render(string(event.Kind)), event.Path, mediaInfo(*event.Media))
+			return
+		}
+
+		fmt.Printf("%s %s %s %s is similar to:\n", clock, magenta.Render("match"), bold.Render(event.Path),
+			mediaInfo(*event.Media))
+
+		for _, match := range event.Matches {
+			score := fmt.Sprintf("%.5g", match.Score)
+			fmt.Printf("  -> %s %s, score %s\n", match.Media.Name, mediaInfo(match.Media), magenta.Render(score))
 		}
+	case watch.KindRenamed:
+		fmt.Printf("%s %s %s → %s\n", clock, yellow.Render(string(event.Kind)), event.OldPath, event.Path)
+	case watch.KindRemoved:
+		fmt.Printf("%s %s %s\n", clock, yellow.Render(string(event.Kind)), event.Path)
+	case watch.KindError:
+		fmt.Printf("%s %s %s\n", clock, red.Render(string(event.Kind)), event.Err.Error())
 	}
 }
 
+// PrintWatchJson prints the event as a single line of JSON, so the events can be read as they happen (NDJSON).
+func PrintWatchJson(event watch.Event) error {
+	jsonBytes, err := json.Marshal(ToWatchJson(event))
+	if err != nil {
+		return fmt.Errorf("failed to marshal the event to JSON: %w", err)
+	}
+
+	fmt.Println(string(jsonBytes))
+	return nil
+}
+
+// WatchJson is an event of the watch, as it's shown in the JSON output and given to the commands of --exec.
+type WatchJson struct {
+	Version int `json:"version"`
+	watch.Event
+	Error *errorJson `json:"error,omitempty"`
+}
+
+// ToWatchJson returns the event with the version of the schema and the category of its error, so the JSON output and
+// the commands of --exec get the same events.
+func ToWatchJson(event watch.Event) WatchJson {
+	output := WatchJson{Version: jsonVersion, Event: event}
+	if event.Err != nil {
+		output.Error = &toErrorJson([]error{event.Err})[0]
+	}
+
+	return output
+}
+
+// operationDetails describes what happens to the file of the operation.
+func operationDetails(op actions.Operation) string {
+	switch op.Action {
+	case actions.ActionDelete:
+		return op.Path
+	case actions.ActionQuarantine, actions.ActionTrash:
+		return fmt.Sprintf("%s → %s", op.Path, gray.Render(op.Backup))
+	case actions.ActionRename, actions.ActionReview:
+		return fmt.Sprintf("%s → %s", op.Path, gray.Render(op.Target))
+	default:
+		return fmt.Sprintf("%s → %s", op.Path, gray.Render(op.Keep))
+	}
+}
+
+// matrixLines renders the matrix as an aligned table, where each row starts with the number and the name of its media,
+// and the columns have only the numbers. The diagonal is gray and, with highlight, the other scores at or above the
+// threshold are magenta.
+func matrixLines(media []mediasim.Media, matrix [][]float64, threshold float64, highlight bool) []string {
+	const cellWidth = 7
+
+	labels := make([]string, 0, len(media))
+	labelWidth := 0
+	for i, m := range media {
+		label := fmt.Sprintf("#%d %s", i+1, m.Name)
+		labels = append(labels, label)
+		labelWidth = max(labelWidth, len([]rune(label)))
+	}
+
+	var header strings.Builder
+	header.WriteString(strings.Repeat(" ", labelWidth))
+	for i := range media {
+		header.WriteString(fmt.Sprintf("%*s", cellWidth, fmt.Sprintf("#%d", i+1)))
+	}
+
+	lines := make([]string, 0, len(media)+1)
+	lines = append(lines, header.String())
+
+	for i, row := range matrix {
+		var line strings.Builder
+		line.WriteString(labels[i] + strings.Repeat(" ", labelWidth-len([]rune(labels[i]))))
+
+		for j, score := range row {
+			// The cells are padded before they are styled, so the escape codes don't change the alignment
+			cell := fmt.Sprintf("%*.3f", cellWidth, score)
+			switch {
+			case i == j:
+				cell = gray.Render(cell)
+			case highlight && score >= threshold:
+				cell = magenta.Render(cell)
+			}
+
+			line.WriteString(cell)
+		}
+
+		lines = append(lines, line.String())
+	}
+
+	return lines
+}
+
+// histogramLines renders the bins between the first and the last with scores. The bars are in a logarithmic scale, so
+// the few near-duplicates are still visible next to the many unrelated pairs.
+func histogramLines(histogram []mediasim.HistogramBin) []string {
+	first := slices.IndexFunc(histogram, func(b mediasim.HistogramBin) bool { return b.Count > 0 })
+	if first < 0 {
+		return nil
+	}
+
+	last := len(histogram) - 1
+	for histogram[last].Count == 0 {
+		last--
+	}
+
+	highest := slices.MaxFunc(histogram, func(a, b mediasim.HistogramBin) int { return a.Count - b.Count }).Count
+	lines := make([]string, 0, last-first+1)
+
+	for _, bin := range histogram[first : last+1] {
+		width := 0
+		if bin.Count > 0 {
+			width = max(1, int(math.Round(math.Log1p(float64(bin.Count))/math.Log1p(float64(highest))*histogramWidth)))
+		}
+
+		lines = append(lines, fmt.Sprintf("%.2f–%.2f │%s %d", bin.Min, bin.Max, strings.Repeat("█", width), bin.Count))
+	}
+
+	return lines
+}
+
+// errorJson is a media that failed to load, as it's shown in the JSON output.
+type errorJson struct {
+	Path     string `json:"path"`
+	Category string `json:"category"`
+	Message  string `json:"message"`
+}
+
+func toErrorJson(failures []error) []errorJson {
+	result := make([]errorJson, 0, len(failures))
+
+	for _, err := range failures {
+		e := errorJson{Category: mediasim.ErrorCategory(err), Message: err.Error()}
+
+		var mediaErr *mediasim.MediaError
+		if errors.As(err, &mediaErr) {
+			e.Path = mediaErr.Path
+		}
+
+		result = append(result, e)
+	}
+
+	return result
+}
+
 func mediaInfo(media mediasim.Media) string {
 	const megapixel = 1_000_000
 
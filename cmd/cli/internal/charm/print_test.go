@@ -0,0 +1,103 @@
+package charm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vegidio/mediasim"
+	"github.com/vegidio/mediasim/actions"
+	"github.com/vegidio/mediasim/watch"
+)
+
+func TestToErrorJson(t *testing.T) {
+	mediaErr := &mediasim.MediaError{Path: "a.mp4", Kind: mediasim.ErrNoVideoStream}
+
+	result := toErrorJson([]error{fmt.Errorf("error loading media: %w", mediaErr), errors.New("boom")})
+
+	assert.Equal(t, []errorJson{
+		{Path: "a.mp4", Category: "no_video_stream", Message: "error loading media: no video stream 'a.mp4'"},
+		{Path: "", Category: "other", Message: "boom"},
+	}, result)
+	assert.NotNil(t, toErrorJson(nil), "no errors are an empty list in the JSON output")
+}
+
+func TestToGroupJson(t *testing.T) {
+	groups := [][]mediasim.Media{{{Name: "a.jpg", Size: 300}, {Name: "b.jpg", Size: 200}, {Name: "c.jpg", Size: 100}}}
+
+	result := toGroupJson(groups)
+
+	assert.Len(t, result, 1)
+	assert.Equal(t, mediasim.GroupID(groups[0]), result[0].ID)
+	assert.Equal(t, int64(300), result[0].Reclaimable)
+	assert.Equal(t, groups[0], result[0].Media)
+	assert.NotNil(t, toGroupJson(nil), "no groups are an empty list in the JSON output")
+}
+
+func TestHistogramLines(t *testing.T) {
+	histogram := []mediasim.HistogramBin{
+		{Min: 0, Max: 0.25},
+		{Min: 0.25, Max: 0.5, Count: 999},
+		{Min: 0.5, Max: 0.75},
+		{Min: 0.75, Max: 1, Count: 9},
+	}
+
+	lines := histogramLines(histogram)
+
+	assert.Equal(t, []string{
+		"0.25–0.50 │" + strings.Repeat("█", 40) + " 999",
+		"0.50–0.75 │ 0",
+		"0.75–1.00 │" + strings.Repeat("█", 13) + " 9",
+	}, lines)
+	assert.Nil(t, histogramLines([]mediasim.HistogramBin{{Max: 1}}))
+}
+
+func TestMatrixLines(t *testing.T) {
+	media := []mediasim.Media{{Name: "a.jpg"}, {Name: "photos/b.jpg"}, {Name: "c.jpg"}}
+	matrix := [][]float64{
+		{1, 0.93, 0.1},
+		{0.93, 1, 0.2},
+		{0.1, 0.2, 1},
+	}
+
+	lines := matrixLines(media, matrix, 0.9, true)
+
+	assert.Equal(t, []string{
+		"                    #1     #2     #3",
+		"#1 a.jpg       " + gray.Render("  1.000") + magenta.Render("  0.930") + "  0.100",
+		"#2 photos/b.jpg" + magenta.Render("  0.930") + gray.Render("  1.000") + "  0.200",
+		"#3 c.jpg       " + "  0.100  0.200" + gray.Render("  1.000"),
+	}, lines)
+
+	plain := matrixLines(media, matrix, 0.9, false)
+	assert.Equal(t, "#1 a.jpg       "+gray.Render("  1.000")+"  0.930  0.100", plain[1])
+}
+
+func TestOperationDetails(t *testing.T) {
+	quarantined := actions.Operation{Action: actions.ActionQuarantine, Path: "/a.jpg", Keep: "/b.jpg", Backup: "/q/a.jpg"}
+	linked := actions.Operation{Action: actions.ActionSymlink, Path: "/a.jpg", Keep: "/b.jpg"}
+	deleted := actions.Operation{Action: actions.ActionDelete, Path: "/a.jpg", Keep: "/b.jpg"}
+	renamed := actions.Operation{Action: actions.ActionRename, Path: "/a.jpg", Target: "/group1_a.jpg"}
+
+	assert.Equal(t, "/a.jpg → "+gray.Render("/q/a.jpg"), operationDetails(quarantined))
+	assert.Equal(t, "/a.jpg → "+gray.Render("/b.jpg"), operationDetails(linked))
+	assert.Equal(t, "/a.jpg", operationDetails(deleted))
+	assert.Equal(t, "/a.jpg → "+gray.Render("/group1_a.jpg"), operationDetails(renamed))
+}
+
+func TestToWatchJson(t *testing.T) {
+	media := mediasim.Media{Name: "/photos/b.jpg", Type: "image"}
+	event := watch.Event{Kind: watch.KindAdded, Path: media.Name, Media: &media, Matches: []watch.Match{{Score: 0.95}}}
+
+	jsonBytes, err := json.Marshal(ToWatchJson(event))
+	assert.NoError(t, err)
+	assert.Contains(t, string(jsonBytes), `"kind":"added","time":`)
+	assert.Contains(t, string(jsonBytes), `"score":0.95`)
+	assert.NotContains(t, string(jsonBytes), `"error"`)
+
+	failed := ToWatchJson(watch.Event{Kind: watch.KindError, Path: "/photos/c.jpg", Err: errors.New("boom")})
+	assert.Equal(t, &errorJson{Category: "other", Message: "boom"}, failed.Error)
+}
@@ -41,6 +41,7 @@ type progressModel struct {
 	media         []mediasim.Media
 	total         int
 	completed     int
+	activity      *Activity
 	startTime     time.Time
 	lastEtaUpdate time.Time
 	eta           time.Duration
@@ -131,10 +132,23 @@ func (m *progressModel) View() string {
 		m.progress.View(),
 		green.Render(percentStr),
 		magenta.Render(fmt.Sprintf("ETA %v", eta)),
-	)
+	) + m.activityView()
+}
+
+// activityView renders the totals of the activity below the progress bar, if there's one.
+func (m *progressModel) activityView() string {
+	if m.activity == nil {
+		return ""
+	}
+
+	return m.activity.view() + "\n"
 }
 
-func initProgressModel(result <-chan types.Result[mediasim.Media], total int) *progressModel {
+func initProgressModel(
+	result <-chan types.Result[mediasim.Media],
+	total int,
+	activity *Activity,
+) *progressModel {
 	p := progress.New(
 		progress.WithDefaultGradient(),
 		progress.WithoutPercentage(),
@@ -146,14 +160,21 @@ func initProgressModel(result <-chan types.Result[mediasim.Media], total int) *p
 		result:        result,
 		media:         make([]mediasim.Media, 0),
 		total:         total,
+		activity:      activity,
 		startTime:     time.Now(),
 		lastEtaUpdate: time.Now(),
 		eta:           time.Duration(0),
 	}
 }
 
-func StartProgress(result <-chan types.Result[mediasim.Media], total int) ([]mediasim.Media, error) {
-	model, err := tea.NewProgram(initProgressModel(result, total)).Run()
+// StartProgress shows a progress bar while the media are loaded. When activity is not nil, it must also observe the
+// loading, and its totals are shown below the progress bar.
+func StartProgress(
+	result <-chan types.Result[mediasim.Media],
+	total int,
+	activity *Activity,
+) ([]mediasim.Media, error) {
+	model, err := tea.NewProgram(initProgressModel(result, total, activity)).Run()
 	if err != nil {
 		return nil, err
 	}
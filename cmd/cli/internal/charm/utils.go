@@ -1,6 +1,9 @@
 package charm
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 const etaFallback = 7 * 24 * time.Hour
 
@@ -21,3 +24,19 @@ func calculateETA(total, completed int, elapsed time.Duration) time.Duration {
 
 	return eta
 }
+
+// formatBytes formats the number of bytes with binary units, like "1.5 MiB".
+func formatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
@@ -60,3 +60,11 @@ func TestCalculateETA(t *testing.T) {
 		assert.Equal(t, 15*time.Second, eta)
 	})
 }
+
+func TestFormatBytes(t *testing.T) {
+	assert.Equal(t, "0 B", formatBytes(0))
+	assert.Equal(t, "1023 B", formatBytes(1023))
+	assert.Equal(t, "1.0 KiB", formatBytes(1024))
+	assert.Equal(t, "1.5 MiB", formatBytes(3*512*1024))
+	assert.Equal(t, "2.0 GiB", formatBytes(2<<30))
+}
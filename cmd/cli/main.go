@@ -28,9 +28,10 @@ func main() {
 	shared.CleanupTempDirs()
 
 	// Add support for AVIF and HEIC images
-	mediasim.AddImageType(".avif", ".heic")
+	types := mediasim.NewTypeRegistry()
+	types.AddImageType(".avif", ".heic")
 
-	cmd := buildCliCommands(otel)
+	cmd := buildCliCommands(otel, types)
 
 	if err := cmd.Run(context.Background(), os.Args); err != nil {
 		otel.LogError("Error running app", make(map[string]any), err)
@@ -1,14 +1,26 @@
 package main
 
 import (
+	"bytes"
+	"cli/internal/charm"
+	"context"
+	"encoding/json"
 	"fmt"
+	"image"
+	"image/png"
 	"os"
+	"os/exec"
 	"os/user"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/vegidio/mediasim"
+	"github.com/vegidio/mediasim/evaluation"
+	"github.com/vegidio/mediasim/watch"
 )
 
 func expandPaths(paths []string) ([]string, error) {
@@ -38,20 +50,232 @@ func expandPath(path string) (string, error) {
 	return path, nil
 }
 
-func renameMedia(groups [][]mediasim.Media) error {
-	size := len(groups)
-	width := len(strconv.Itoa(size))
+// reviewDir returns the directory of the review tree of the scanned directory: the one chosen, or a directory named
+// after the scanned one, next to it. The tree can't be inside the scanned directory, as the next recursive scans would
+// find its links as duplicates of the files.
+func reviewDir(directory, chosen string) (string, error) {
+	directory, err := filepath.Abs(directory)
+	if err != nil {
+		return "", err
+	}
+
+	if chosen == "" {
+		name := filepath.Base(directory)
+		if filepath.Dir(directory) == directory {
+			name = "root"
+		}
+
+		return filepath.Join(filepath.Dir(directory), name+"-mediasim-review"), nil
+	}
+
+	chosen, err = filepath.Abs(chosen)
+	if err != nil {
+		return "", err
+	}
+
+	if rel, relErr := filepath.Rel(directory, chosen); relErr == nil && filepath.IsLocal(rel) {
+		return "", fmt.Errorf("the review tree %s can't be inside the scanned directory %s", chosen, directory)
+	}
+
+	return chosen, nil
+}
+
+// sizeUnits are the multipliers of the size suffixes accepted by parseSize, longest first.
+var sizeUnits = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"TB", 1 << 40},
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"T", 1 << 40},
+	{"G", 1 << 30},
+	{"M", 1 << 20},
+	{"K", 1 << 10},
+	{"B", 1},
+}
+
+// parseSize parses a file size like "500", "10KB" or "1.5GB" into bytes, using binary multiples. An empty string is 0.
+func parseSize(s string) (int64, error) {
+	value := strings.ToUpper(strings.TrimSpace(s))
+	if value == "" {
+		return 0, nil
+	}
+
+	multiplier := int64(1)
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(value, unit.suffix) {
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			multiplier = unit.multiplier
+			break
+		}
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("invalid size %q; use a number of bytes or a unit like 10KB, 5MB, 1GB", s)
+	}
+
+	return int64(number * float64(multiplier)), nil
+}
+
+// parseDate parses a date in the format YYYY-MM-DD (local time) or RFC 3339. An empty string is the zero time.
+func parseDate(s string) (time.Time, error) {
+	value := strings.TrimSpace(s)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q; use YYYY-MM-DD or RFC 3339", s)
+	}
+
+	return t, nil
+}
+
+// fileOutputs are the output formats that can be written to the files of --output-file.
+var fileOutputs = []string{"json", "ndjson", "csv", "html"}
+
+// outputExts are the output formats of the files of --output-file, by the extension of the file.
+var outputExts = map[string]string{
+	".json":   "json",
+	".ndjson": "ndjson",
+	".jsonl":  "ndjson",
+	".csv":    "csv",
+	".html":   "html",
+	".htm":    "html",
+}
+
+// parseOutputFile parses an output file in the format "format=path", like "ndjson=results.txt", or just "path", where
+// the format comes from the extension of the file.
+func parseOutputFile(s string) (string, string, error) {
+	if format, path, found := strings.Cut(s, "="); found && slices.Contains(fileOutputs, format) {
+		if path == "" {
+			return "", "", fmt.Errorf("the output file of %s is missing", format)
+		}
+
+		return format, path, nil
+	}
+
+	format, ok := outputExts[strings.ToLower(filepath.Ext(s))]
+	if !ok {
+		return "", "", fmt.Errorf("unknown format of the output file %q; use <format>=<path>, where the format is "+
+			"json, ndjson, csv or html", s)
+	}
+
+	return format, s, nil
+}
+
+// savePng writes the image to a PNG file.
+func savePng(path string, img image.Image) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create the image file: %w", err)
+	}
+
+	if err = png.Encode(file, img); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to encode the image %s: %w", path, err)
+	}
+
+	return file.Close()
+}
+
+// readLabels reads the pairs of files labelled as duplicates or not, one pair per line in the format
+// "file1,file2,label", where the label is 1, true, yes or duplicate for duplicates, and 0, false, no or different for
+// the others. Empty lines and lines starting with # are ignored, and relative paths are relative to the directory.
+func readLabels(path string, directory string) ([]mediasim.LabeledPair, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the labels: %w", err)
+	}
+
+	resolve := func(name string) string {
+		name = strings.TrimSpace(name)
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(directory, name)
+		}
+
+		return filepath.Clean(name)
+	}
+
+	pairs := make([]mediasim.LabeledPair, 0)
+	for i, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
 
-	for i, group := range groups {
-		for _, media := range group {
-			dir, file := filepath.Split(media.Name)
-			newName := fmt.Sprintf("group%0*d_%s", width, i+1, file)
-			newPath := filepath.Join(dir, newName)
+		fields := strings.Split(line, ",")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("invalid label in line %d; use file1,file2,label", i+1)
+		}
 
-			if err := os.Rename(media.Name, newPath); err != nil {
-				return fmt.Errorf("failed to rename file %s to %s: %w", media.Name, newPath, err)
-			}
+		pair := mediasim.LabeledPair{Name1: resolve(fields[0]), Name2: resolve(fields[1])}
+		switch strings.ToLower(strings.TrimSpace(fields[2])) {
+		case "1", "true", "yes", "duplicate":
+			pair.Duplicate = true
+		case "0", "false", "no", "different":
+			pair.Duplicate = false
+		default:
+			return nil, fmt.Errorf("invalid label %q in line %d; use 1 (duplicate) or 0 (different)", fields[2], i+1)
 		}
+
+		pairs = append(pairs, pair)
+	}
+
+	return pairs, nil
+}
+
+// readClusters reads the ground-truth clusters of duplicates, where relative paths are relative to the directory.
+func readClusters(path string, directory string) ([][]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the ground truth: %w", err)
+	}
+	defer file.Close()
+
+	return evaluation.ReadClusters(file, directory)
+}
+
+// runHook runs the command with the shell for an event with matches. The event is written to its standard input, as
+// in the JSON output, and the environment has the path of the media in MEDIASIM_FILE, and the path and score of its most similar
+// media in MEDIASIM_MATCH and MEDIASIM_SCORE. The output of the command is shown in the terminal, except with
+// machine-readable outputs, where it goes to the standard error so it doesn't mix with the events.
+func runHook(ctx context.Context, command string, event watch.Event, toStdout bool) error {
+	input, err := json.Marshal(charm.ToWatchJson(event))
+	if err != nil {
+		return fmt.Errorf("failed to marshal the event to JSON: %w", err)
+	}
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if toStdout {
+		cmd.Stdout = os.Stdout
+	}
+
+	cmd.Env = append(os.Environ(),
+		"MEDIASIM_FILE="+event.Path,
+		"MEDIASIM_MATCH="+event.Matches[0].Media.Name,
+		"MEDIASIM_SCORE="+strconv.FormatFloat(event.Matches[0].Score, 'f', 5, 64),
+	)
+
+	if err = cmd.Run(); err != nil {
+		return fmt.Errorf("the command of --exec failed for %s: %w", event.Path, err)
 	}
 
 	return nil
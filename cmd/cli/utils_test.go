@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vegidio/mediasim"
+	"github.com/vegidio/mediasim/watch"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]int64{
+		"":      0,
+		"500":   500,
+		"500B":  500,
+		"10KB":  10 * 1024,
+		"10kb":  10 * 1024,
+		"5M":    5 * 1024 * 1024,
+		"1.5GB": 1536 * 1024 * 1024,
+		"2 TB":  2 << 40,
+	}
+
+	for input, expected := range tests {
+		size, err := parseSize(input)
+		assert.NoError(t, err, input)
+		assert.Equal(t, expected, size, input)
+	}
+
+	for _, input := range []string{"abc", "-1KB", "10XB"} {
+		_, err := parseSize(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	date, err := parseDate("2024-03-15")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 3, 15, 0, 0, 0, 0, time.Local), date)
+
+	date, err = parseDate("2024-03-15T10:30:00Z")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC), date)
+
+	date, err = parseDate("")
+	assert.NoError(t, err)
+	assert.True(t, date.IsZero())
+
+	_, err = parseDate("15/03/2024")
+	assert.Error(t, err)
+}
+
+func TestReviewDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "photos")
+
+	review, err := reviewDir(dir, "")
+	assert.NoError(t, err)
+	assert.Equal(t, dir+"-mediasim-review", review)
+
+	review, err = reviewDir(dir, filepath.Join(dir, "..", "review"))
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(filepath.Dir(dir), "review"), review)
+
+	for _, inside := range []string{dir, filepath.Join(dir, "review"), filepath.Join(dir, "a", "..", "b")} {
+		_, err = reviewDir(dir, inside)
+		assert.ErrorContains(t, err, "can't be inside the scanned directory", inside)
+	}
+}
+
+func TestReadLabels(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "labels.csv")
+	content := "# file1,file2,label\na.jpg,b.jpg,1\n\nsub/c.jpg, /abs/d.jpg , different\n"
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+	pairs, err := readLabels(path, "/photos")
+	assert.NoError(t, err)
+	assert.Equal(t, []mediasim.LabeledPair{
+		{Name1: filepath.Join("/photos", "a.jpg"), Name2: filepath.Join("/photos", "b.jpg"), Duplicate: true},
+		{Name1: filepath.Join("/photos", "sub", "c.jpg"), Name2: filepath.Clean("/abs/d.jpg"), Duplicate: false},
+	}, pairs)
+
+	assert.NoError(t, os.WriteFile(path, []byte("a.jpg,b.jpg,maybe\n"), 0o644))
+	_, err = readLabels(path, "/photos")
+	assert.ErrorContains(t, err, "line 1")
+}
+
+func TestRunHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the hook is a POSIX shell command")
+	}
+
+	output := filepath.Join(t.TempDir(), "hook.txt")
+	event := watch.Event{
+		Kind:    watch.KindAdded,
+		Path:    "/photos/new.jpg",
+		Matches: []watch.Match{{Media: mediasim.Media{Name: "/photos/old.jpg"}, Score: 0.95}},
+	}
+
+	command := `echo "$MEDIASIM_FILE,$MEDIASIM_MATCH,$MEDIASIM_SCORE" > "` + output + `"; cat >> "` + output + `"`
+	assert.NoError(t, runHook(context.Background(), command, event, false))
+
+	content, err := os.ReadFile(output)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(content), "/photos/new.jpg,/photos/old.jpg,0.95000\n{\"version\":1,\"kind\":\"added\""))
+
+	assert.ErrorContains(t, runHook(context.Background(), "exit 3", event, false), "exit status 3")
+}
+
+func TestParseOutputFile(t *testing.T) {
+	tests := map[string][2]string{
+		"results.json":       {"json", "results.json"},
+		"out/results.JSONL":  {"ndjson", "out/results.JSONL"},
+		"report.html":        {"html", "report.html"},
+		"ndjson=results.txt": {"ndjson", "results.txt"},
+		"csv=a=b.csv":        {"csv", "a=b.csv"},
+		"dir/x=1.csv":        {"csv", "dir/x=1.csv"},
+	}
+
+	for input, expected := range tests {
+		format, path, err := parseOutputFile(input)
+		assert.NoError(t, err, input)
+		assert.Equal(t, expected, [2]string{format, path}, input)
+	}
+
+	for _, input := range []string{"results.txt", "report=report.txt", "json="} {
+		_, _, err := parseOutputFile(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestSavePng(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "heatmap.png")
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 3))
+
+	assert.NoError(t, savePng(path, img))
+
+	file, err := os.Open(path)
+	assert.NoError(t, err)
+	defer file.Close()
+
+	config, err := png.DecodeConfig(file)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, config.Width)
+	assert.Equal(t, 3, config.Height)
+
+	assert.Error(t, savePng(filepath.Join(t.TempDir(), "missing", "heatmap.png"), img))
+}
@@ -18,6 +18,17 @@ export function StartComparison(directory: string, includeImages: boolean, inclu
     });
 }
 
+/**
+ * StartCrossComparison loads media from the reference and candidate directories and compares only the candidates
+ * against the references (and, optionally, against each other), emitting progress events. The media of the reference
+ * directories are flagged in the returned groups.
+ */
+export function StartCrossComparison(references: string[], candidates: string[], compareCandidates: boolean, includeImages: boolean, includeVideos: boolean, frameFlip: boolean, frameRotate: boolean, threshold: number): $CancellablePromise<$models.ComparisonGroup[]> {
+    return $Call.ByID(3306642058, references, candidates, compareCandidates, includeImages, includeVideos, frameFlip, frameRotate, threshold).then(($result: any) => {
+        return $$createType1($result);
+    });
+}
+
 // Private type creation functions
 const $$createType0 = $models.ComparisonGroup.createFrom;
 const $$createType1 = $Create.Array($$createType0);
@@ -9,10 +9,17 @@ import { Create as $Create } from "@wailsio/runtime";
  * ComparisonGroup is a DTO representing a group of similar media items.
  */
 export class ComparisonGroup {
+    /**
+     * ID identifies the group across comparisons; it's derived from the content of its media.
+     */
+    "id": string;
     "media": ComparisonMedia[];
 
     /** Creates a new ComparisonGroup instance. */
     constructor($$source: Partial<ComparisonGroup> = {}) {
+        if (!("id" in $$source)) {
+            this["id"] = "";
+        }
         if (!("media" in $$source)) {
             this["media"] = [];
         }
@@ -24,10 +31,10 @@ export class ComparisonGroup {
      * Creates a new ComparisonGroup instance from a string or object.
      */
     static createFrom($$source: any = {}): ComparisonGroup {
-        const $$createField0_0 = $$createType1;
+        const $$createField1_0 = $$createType1;
         let $$parsedSource = typeof $$source === 'string' ? JSON.parse($$source) : $$source;
         if ("media" in $$parsedSource) {
-            $$parsedSource["media"] = $$createField0_0($$parsedSource["media"]);
+            $$parsedSource["media"] = $$createField1_0($$parsedSource["media"]);
         }
         return new ComparisonGroup($$parsedSource as Partial<ComparisonGroup>);
     }
@@ -43,6 +50,7 @@ export class ComparisonMedia {
     "height": number;
     "size": number;
     "length": number;
+    "reference": boolean;
 
     /** Creates a new ComparisonMedia instance. */
     constructor($$source: Partial<ComparisonMedia> = {}) {
@@ -64,6 +72,9 @@ export class ComparisonMedia {
         if (!("length" in $$source)) {
             this["length"] = 0;
         }
+        if (!("reference" in $$source)) {
+            this["reference"] = false;
+        }
 
         Object.assign(this, $$source);
     }
@@ -15,7 +15,7 @@ export const ComparisonGrid = () => {
     return (
         <div ref={scrollRef} className='overflow-y-auto h-full p-4'>
             {groups.map((group, index) => (
-                <div key={index} className='mb-6'>
+                <div key={group.id} className='mb-6'>
                     <h3 className='text-sm font-medium text-gray-300 mb-2'>Group {index + 1}</h3>
 
                     <div
@@ -5,6 +5,30 @@ import { Events } from '@wailsio/runtime';
 import { ModalTitle } from '@/components/molecules';
 import { useAppStore, useComparisonStore, useSettingsStore } from '@/stores';
 
+type ComparisonProgress = {
+    phase: 'listing' | 'loading' | 'comparing';
+    current: number;
+    total: number;
+    failed: number;
+    file: string;
+    bytes: number;
+    compared: number;
+    pruned: number;
+};
+
+const formatBytes = (bytes: number) => {
+    const units = ['B', 'KB', 'MB', 'GB', 'TB'];
+    let value = bytes;
+    let unit = 0;
+
+    while (value >= 1024 && unit < units.length - 1) {
+        value /= 1024;
+        unit++;
+    }
+
+    return `${value.toFixed(unit === 0 ? 0 : 1)} ${units[unit]}`;
+};
+
 type ProgressDialogProps = {
     open: boolean;
     onClose?: () => void;
@@ -18,8 +42,7 @@ export const ProgressDialog = ({ open, onClose }: ProgressDialogProps) => {
     const frameRotate = useSettingsStore((s) => s.frameRotate);
     const setGroups = useComparisonStore((s) => s.setGroups);
 
-    const [current, setCurrent] = useState(0);
-    const [total, setTotal] = useState(0);
+    const [progress, setProgress] = useState<ComparisonProgress | undefined>(undefined);
     const promiseRef = useRef<{ cancel: () => void } | undefined>(undefined);
 
     // Only re-run when `open` changes — other values are set before the dialog opens
@@ -28,13 +51,10 @@ export const ProgressDialog = ({ open, onClose }: ProgressDialogProps) => {
     useEffect(() => {
         if (!open) return;
 
-        setCurrent(0);
-        setTotal(0);
+        setProgress(undefined);
 
         const offProgress = Events.On('comparison:progress', (event: { data: unknown }) => {
-            const { current: c, total: t } = event.data as { current: number; total: number };
-            setCurrent(c);
-            setTotal(t);
+            setProgress(event.data as ComparisonProgress);
         });
 
         const includeImages = mediaType === 'all' || mediaType === 'images';
@@ -63,6 +83,8 @@ export const ProgressDialog = ({ open, onClose }: ProgressDialogProps) => {
         onClose?.();
     };
 
+    const current = progress?.current ?? 0;
+    const total = progress?.total ?? 0;
     const percent = total > 0 ? Math.round((current / total) * 100) : 0;
 
     return (
@@ -94,6 +116,22 @@ export const ProgressDialog = ({ open, onClose }: ProgressDialogProps) => {
                         </Typography>
                     </div>
                 </div>
+
+                {progress && (
+                    <div className='flex flex-col gap-1'>
+                        <Typography variant='body2' color='textSecondary'>
+                            {progress.phase === 'listing'
+                                ? 'Listing the files...'
+                                : `Decoded ${formatBytes(progress.bytes)}; ${progress.failed} failed`}
+                        </Typography>
+                        <Typography variant='body2' color='textSecondary'>
+                            {progress.compared} pairs compared, {progress.pruned} pruned
+                        </Typography>
+                        <Typography variant='body2' color='textSecondary' noWrap title={progress.file}>
+                            {progress.file}
+                        </Typography>
+                    </div>
+                )}
             </DialogContent>
 
             <DialogActions className='px-6 pb-4'>
@@ -20,14 +20,15 @@ var assets embed.FS
 
 func main() {
 	// Add support for AVIF and HEIC images
-	mediasim.AddImageType(".avif", ".heic")
+	types := mediasim.NewTypeRegistry()
+	types.AddImageType(".avif", ".heic")
 
 	// Remove leftover temp dirs from previous sessions (crash, force quit, etc.)
 	shared.CleanupTempDirs()
 
 	// Create services needed before app init for middleware.
 	streamerService := &services.StreamerService{}
-	thumbService := &services.ThumbnailService{}
+	thumbService := &services.ThumbnailService{Types: types}
 
 	// Create a new Wails application by providing the necessary options.
 	app := application.New(application.Options{
@@ -49,8 +50,8 @@ func main() {
 
 	// Register services
 	app.RegisterService(application.NewService(&services.AppService{}))
-	app.RegisterService(application.NewService(&services.MediaService{}))
-	app.RegisterService(application.NewService(&services.ComparisonService{}))
+	app.RegisterService(application.NewService(&services.MediaService{Types: types}))
+	app.RegisterService(application.NewService(&services.ComparisonService{Types: types}))
 	app.RegisterService(application.NewService(thumbService))
 	app.RegisterService(application.NewService(streamerService))
 
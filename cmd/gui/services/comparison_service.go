@@ -3,28 +3,48 @@ package services
 import (
 	"context"
 	"runtime"
+	"sync"
 
 	"github.com/vegidio/mediasim"
 	"github.com/wailsapp/wails/v3/pkg/application"
 )
 
-type ComparisonService struct{}
+type ComparisonService struct {
+	// Types is the registry of the extensions recognised as images and videos.
+	Types *mediasim.TypeRegistry
+}
 
 // ComparisonMedia is a DTO representing a media item in a comparison group.
 type ComparisonMedia struct {
-	Path   string `json:"path"`
-	Type   string `json:"type"`
-	Width  int    `json:"width"`
-	Height int    `json:"height"`
-	Size   int64  `json:"size"`
-	Length int    `json:"length"`
+	Path      string `json:"path"`
+	Type      string `json:"type"`
+	Width     int    `json:"width"`
+	Height    int    `json:"height"`
+	Size      int64  `json:"size"`
+	Length    int    `json:"length"`
+	Reference bool   `json:"reference"`
 }
 
 // ComparisonGroup is a DTO representing a group of similar media items.
 type ComparisonGroup struct {
+	// ID identifies the group across comparisons; it's derived from the content of its media.
+	ID    string            `json:"id"`
 	Media []ComparisonMedia `json:"media"`
 }
 
+// ComparisonProgress is a DTO with the progress of a comparison, sent in the comparison:progress events.
+type ComparisonProgress struct {
+	// Phase is what the comparison is doing: "listing", "loading" or "comparing".
+	Phase    string `json:"phase"`
+	Current  int    `json:"current"`
+	Total    int    `json:"total"`
+	Failed   int    `json:"failed"`
+	File     string `json:"file"`
+	Bytes    int64  `json:"bytes"`
+	Compared int    `json:"compared"`
+	Pruned   int    `json:"pruned"`
+}
+
 // StartComparison loads media from a directory and groups them by similarity, emitting progress events.
 func (c *ComparisonService) StartComparison(
 	ctx context.Context,
@@ -35,6 +55,8 @@ func (c *ComparisonService) StartComparison(
 	frameRotate bool,
 	threshold float64,
 ) ([]ComparisonGroup, error) {
+	progress := &progressObserver{app: application.Get()}
+
 	mediaCh, total := mediasim.LoadMediaFromDirectory(directory, mediasim.DirectoryOptions{
 		IncludeImages: includeImages,
 		IncludeVideos: includeVideos,
@@ -44,12 +66,16 @@ func (c *ComparisonService) StartComparison(
 			FrameFlip:   frameFlip,
 			FrameRotate: frameRotate,
 		},
+		LoadOptions: mediasim.LoadOptions{
+			Types:    c.Types,
+			Observer: progress,
+		},
 	})
 
-	app := application.Get()
-	app.Event.Emit("comparison:progress", map[string]int{"current": 0, "total": total})
-
-	resultCh := mediasim.LoadAndGroupMedia(mediaCh, total, threshold, false)
+	resultCh := mediasim.LoadAndGroupMediaWithOptions(mediaCh, total, mediasim.GroupOptions{
+		Threshold: threshold,
+		Observer:  progress,
+	})
 
 	for result := range resultCh {
 		select {
@@ -70,33 +96,164 @@ func (c *ComparisonService) StartComparison(
 		}
 
 		if result.Done {
-			groups := make([]ComparisonGroup, len(result.Groups))
-
-			for i, g := range result.Groups {
-				media := make([]ComparisonMedia, len(g))
-
-				for j, m := range g {
-					media[j] = ComparisonMedia{
-						Path:   m.Name,
-						Type:   m.Type,
-						Width:  m.Width,
-						Height: m.Height,
-						Size:   m.Size,
-						Length: m.Length,
+			return toComparisonGroups(result.Groups, nil), nil
+		}
+	}
+
+	return nil, nil
+}
+
+// StartCrossComparison loads media from the reference and candidate directories and compares only the candidates
+// against the references (and, optionally, against each other), emitting progress events. The media of the reference
+// directories are flagged in the returned groups.
+func (c *ComparisonService) StartCrossComparison(
+	ctx context.Context,
+	references []string,
+	candidates []string,
+	compareCandidates bool,
+	includeImages bool,
+	includeVideos bool,
+	frameFlip bool,
+	frameRotate bool,
+	threshold float64,
+) ([]ComparisonGroup, error) {
+	progress := &progressObserver{app: application.Get()}
+
+	options := mediasim.DirectoryOptions{
+		IncludeImages: includeImages,
+		IncludeVideos: includeVideos,
+		IsRecursive:   false,
+		Parallel:      runtime.NumCPU(),
+		FrameOptions: mediasim.FrameOptions{
+			FrameFlip:   frameFlip,
+			FrameRotate: frameRotate,
+		},
+		LoadOptions: mediasim.LoadOptions{
+			Types:    c.Types,
+			Observer: progress,
+		},
+	}
+
+	referenceMedia, err := loadDirectories(ctx, references, options)
+	if err != nil {
+		return nil, err
+	}
+
+	candidateMedia, err := loadDirectories(ctx, candidates, options)
+	if err != nil {
+		return nil, err
+	}
+
+	result := mediasim.CrossCompareMedia(referenceMedia, candidateMedia, mediasim.CrossOptions{
+		GroupOptions:      mediasim.GroupOptions{Threshold: threshold, Observer: progress},
+		CompareCandidates: compareCandidates,
+	})
+
+	isReference := make(map[string]bool, len(referenceMedia))
+	for _, m := range referenceMedia {
+		isReference[m.Name] = true
+	}
+
+	return toComparisonGroups(result.Groups, isReference), nil
+}
+
+// loadDirectories loads the media of several directories; the progress is reported by the observer in the options.
+func loadDirectories(
+	ctx context.Context,
+	directories []string,
+	options mediasim.DirectoryOptions,
+) ([]mediasim.Media, error) {
+	media := make([]mediasim.Media, 0)
+
+	for _, directory := range directories {
+		mediaCh, _ := mediasim.LoadMediaFromDirectory(directory, options)
+
+		for result := range mediaCh {
+			select {
+			case <-ctx.Done():
+				go func() {
+					for range mediaCh {
 					}
-				}
+				}()
+				return nil, ctx.Err()
+			default:
+			}
 
-				groups[i] = ComparisonGroup{Media: media}
+			if result.Err != nil {
+				go func() {
+					for range mediaCh {
+					}
+				}()
+				return nil, result.Err
 			}
 
-			return groups, nil
+			media = append(media, result.Data)
 		}
+	}
 
-		app.Event.Emit("comparison:progress", map[string]int{
-			"current": result.Loaded,
-			"total":   total,
-		})
+	return media, nil
+}
+
+// progressObserver keeps the running totals of the events of a comparison and emits them as comparison:progress
+// events. The totals of several directories are added up, so it can be shared by all the directories of a comparison.
+type progressObserver struct {
+	app      *application.App
+	mu       sync.Mutex
+	progress ComparisonProgress
+}
+
+func (p *progressObserver) OnEvent(event mediasim.Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch e := event.(type) {
+	case mediasim.ListingEvent:
+		p.progress.Phase = "listing"
+		if e.Done {
+			p.progress.Total += e.Found
+		}
+	case mediasim.FileStartedEvent:
+		return
+	case mediasim.FileFinishedEvent:
+		p.progress.Phase = "loading"
+		p.progress.Current++
+		p.progress.File = e.Name
+		p.progress.Bytes += e.Bytes
+		if e.Err != nil {
+			p.progress.Failed++
+		}
+	case mediasim.ComparisonEvent:
+		p.progress.Phase = "comparing"
+		p.progress.Compared += e.Compared
+		p.progress.Pruned += e.Pruned
+	case mediasim.FinishedEvent:
+		return
 	}
 
-	return nil, nil
+	p.app.Event.Emit("comparison:progress", p.progress)
+}
+
+// toComparisonGroups converts the groups to DTOs, flagging the media whose names are in isReference.
+func toComparisonGroups(groups [][]mediasim.Media, isReference map[string]bool) []ComparisonGroup {
+	result := make([]ComparisonGroup, len(groups))
+
+	for i, g := range groups {
+		media := make([]ComparisonMedia, len(g))
+
+		for j, m := range g {
+			media[j] = ComparisonMedia{
+				Path:      m.Name,
+				Type:      m.Type,
+				Width:     m.Width,
+				Height:    m.Height,
+				Size:      m.Size,
+				Length:    m.Length,
+				Reference: isReference[m.Name],
+			}
+		}
+
+		result[i] = ComparisonGroup{ID: mediasim.GroupID(g), Media: media}
+	}
+
+	return result
 }
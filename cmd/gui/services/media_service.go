@@ -5,9 +5,8 @@ import (
 	"os"
 	"sync"
 
-	"shared"
-
 	"github.com/vegidio/go-sak/fs"
+	"github.com/vegidio/mediasim"
 )
 
 type MediaInfo struct {
@@ -16,11 +15,14 @@ type MediaInfo struct {
 	FileSize int64  `json:"fileSize"`
 }
 
-type MediaService struct{}
+type MediaService struct {
+	// Types is the registry of the extensions recognised as images and videos.
+	Types *mediasim.TypeRegistry
+}
 
 // ListMedia returns metadata for all image and video files in the given directory (non-recursive).
 func (m *MediaService) ListMedia(directory string) ([]MediaInfo, error) {
-	filePaths, err := fs.ListPath(directory, fs.LpFile, append(shared.ValidImageTypes, shared.ValidVideoTypes...))
+	filePaths, err := fs.ListPath(directory, fs.LpFile, append(m.Types.ImageTypes(), m.Types.VideoTypes()...))
 	if err != nil {
 		return nil, fmt.Errorf("error listing directory: %w", err)
 	}
@@ -58,6 +60,10 @@ func (m *MediaService) ListMedia(directory string) ([]MediaInfo, error) {
 
 // DeleteFiles permanently removes the given file paths from disk.
 // It returns the list of paths that were successfully deleted.
+//
+// The files are chosen one by one by the user, so, unlike the dedupe command, there's no kept media to check them
+// against, and the trash of the actions package is the freedesktop.org one, which Windows and macOS don't use; the
+// files are removed directly.
 func (m *MediaService) DeleteFiles(paths []string) []string {
 	deleted := make([]string, 0, len(paths))
 
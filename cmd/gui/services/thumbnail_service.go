@@ -5,7 +5,7 @@ import (
 	"fmt"
 	"image"
 	"image/jpeg"
-	_ "image/png"
+	"image/png"
 	"net/http"
 	"net/url"
 	"os"
@@ -19,6 +19,7 @@ import (
 	"github.com/disintegration/imaging"
 	ffmpeg "github.com/u2takey/ffmpeg-go"
 	"github.com/vegidio/go-sak/crypto"
+	"github.com/vegidio/mediasim"
 	"github.com/wailsapp/wails/v3/pkg/application"
 	"golang.org/x/sync/singleflight"
 )
@@ -38,6 +39,9 @@ var browserNativeFormats = map[string]bool{
 var thumbSem = make(chan struct{}, 4)
 
 type ThumbnailService struct {
+	// Types is the registry of the extensions recognised as images and videos; nil means the default registry.
+	Types *mediasim.TypeRegistry
+
 	mu         sync.Mutex
 	cacheDir   string
 	ffmpegPath string
@@ -64,9 +68,19 @@ func (t *ThumbnailService) ServiceShutdown() error {
 	return nil
 }
 
+// isVideo reports whether the file is a video, according to the type registry of the service.
+func (t *ThumbnailService) isVideo(filePath string) bool {
+	types := t.Types
+	if types == nil {
+		types = mediasim.DefaultTypeRegistry()
+	}
+
+	return types.TypeOf(filePath) == "video"
+}
+
 // GetDimensions returns the original width and height of an image or video without fully decoding it.
 func (t *ThumbnailService) GetDimensions(filePath string) (int, int, error) {
-	if shared.IsVideoFile(filePath) {
+	if t.isVideo(filePath) {
 		// For videos, extract a frame then read its dimensions.
 		framePath, err := t.ensureVideoFrame(filePath)
 		if err != nil {
@@ -107,7 +121,7 @@ func (t *ThumbnailService) GetDimensions(filePath string) (int, int, error) {
 func (t *ThumbnailService) ensureThumbnail(filePath string, maxSize int) (string, error) {
 	ext := strings.ToLower(filepath.Ext(filePath))
 	isNative := browserNativeFormats[ext]
-	isVideo := shared.IsVideoFile(filePath)
+	isVideo := t.isVideo(filePath)
 
 	// Full-size browser-native image: serve original directly.
 	if maxSize == 0 && isNative && !isVideo {
@@ -213,10 +227,98 @@ func (t *ThumbnailService) ensureVideoFrame(videoPath string) (string, error) {
 	return framePath, nil
 }
 
-// NewThumbMiddleware returns a Wails asset middleware that serves thumbnails via /thumb endpoint.
+// ensureDiff returns the filesystem path to a cached PNG heatmap of the differences between two images.
+func (t *ThumbnailService) ensureDiff(filePath1, filePath2 string, frameOptions mediasim.FrameOptions) (string, error) {
+	cacheKey := fmt.Sprintf("diff:%s:%s:%t:%t", filePath1, filePath2, frameOptions.FrameFlip, frameOptions.FrameRotate)
+	hash, err := crypto.Xxh3String(cacheKey)
+	if err != nil {
+		return "", fmt.Errorf("error hashing cache key: %w", err)
+	}
+	cachedPath := filepath.Join(t.cacheDir, "diff-"+hash+".png")
+
+	// Cache hit: return immediately.
+	if _, err := os.Stat(cachedPath); err == nil {
+		return cachedPath, nil
+	}
+
+	// Deduplicate concurrent generation for the same pair of images.
+	result, err, _ := t.group.Do(cacheKey, func() (any, error) {
+		thumbSem <- struct{}{}
+		defer func() { <-thumbSem }()
+
+		return t.generateDiff(filePath1, filePath2, frameOptions, cachedPath)
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	return result.(string), nil
+}
+
+// generateDiff compares the two images and writes the heatmap of their differences to cachedPath.
+func (t *ThumbnailService) generateDiff(
+	filePath1, filePath2 string,
+	frameOptions mediasim.FrameOptions,
+	cachedPath string,
+) (string, error) {
+	difference, err := mediasim.DiffFiles(filePath1, filePath2, mediasim.DiffOptions{
+		LoadOptions: mediasim.LoadOptions{Types: t.Types, FFmpegPath: t.ffmpegPath, FrameOptions: frameOptions},
+	})
+	if err != nil {
+		return "", fmt.Errorf("error comparing images: %w", err)
+	}
+
+	f, err := os.Create(cachedPath)
+	if err != nil {
+		return "", fmt.Errorf("error creating cached heatmap: %w", err)
+	}
+	defer f.Close()
+
+	if err = png.Encode(f, difference.Heatmap); err != nil {
+		os.Remove(cachedPath)
+		return "", fmt.Errorf("error encoding heatmap: %w", err)
+	}
+
+	return cachedPath, nil
+}
+
+// serveDiff serves the heatmap of the differences between the images in the "a" and "b" parameters. The "flip" and
+// "rotate" parameters align the second image with its flipped or rotated variants, like in the comparison.
+func (t *ThumbnailService) serveDiff(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	filePath1, err1 := url.QueryUnescape(query.Get("a"))
+	filePath2, err2 := url.QueryUnescape(query.Get("b"))
+	if err1 != nil || err2 != nil || filePath1 == "" || filePath2 == "" {
+		http.Error(w, "missing or invalid a and b parameters", http.StatusBadRequest)
+		return
+	}
+
+	frameOptions := mediasim.FrameOptions{
+		FrameFlip:   query.Get("flip") == "true",
+		FrameRotate: query.Get("rotate") == "true",
+	}
+
+	cachedPath, err := t.ensureDiff(filePath1, filePath2, frameOptions)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.ServeFile(w, r, cachedPath)
+}
+
+// NewThumbMiddleware returns a Wails asset middleware that serves thumbnails via /thumb endpoint, and the heatmaps of
+// the differences between two images via /diff endpoint.
 func NewThumbMiddleware(t *ThumbnailService) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/diff" {
+				t.serveDiff(w, r)
+				return
+			}
+
 			if r.URL.Path != "/thumb" {
 				next.ServeHTTP(w, r)
 				return
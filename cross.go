@@ -0,0 +1,98 @@
+package mediasim
+
+import (
+	"time"
+
+	"github.com/vegidio/mediasim/internal/dsu"
+)
+
+// CrossMatch is the best reference match of a candidate media.
+type CrossMatch struct {
+	// Candidate is the compared candidate.
+	Candidate Media `json:"candidate"`
+	// Reference is the most similar reference with a score at or above the threshold; nil if there is none.
+	Reference *Media `json:"reference"`
+	// Score is the similarity between the candidate and the reference; 0 if there is no reference.
+	Score float64 `json:"score"`
+}
+
+// CrossResult is the result of comparing candidate media against reference media.
+type CrossResult struct {
+	// Matches has one entry for each candidate, in the same order as the candidates.
+	Matches []CrossMatch `json:"matches"`
+	// Groups contains the candidates that matched, together with the references they matched and, when candidates are
+	// compared with each other, the other similar candidates. Groups are sorted by quality like in GroupMedia.
+	Groups [][]Media `json:"groups"`
+	// Stats contains the number of pairs compared and pruned.
+	Stats GroupStats `json:"stats"`
+}
+
+// CrossCompareMedia compares candidate media against reference media, answering which candidates already exist among
+// the references.
+//
+// Only candidate-vs-reference pairs are compared (plus candidate-vs-candidate pairs when CompareCandidates is set), so
+// two references are never compared with each other and the number of comparisons is much smaller than in GroupMedia.
+//
+// # Parameters:
+//   - references: []Media The media that the candidates are compared against; e.g. an existing archive.
+//   - candidates: []Media The media to be checked; e.g. new incoming files.
+//   - options: CrossOptions The configuration options for the comparison.
+//
+// # Returns:
+//   - CrossResult The best reference match of each candidate, the groups of similar media and the statistics.
+func CrossCompareMedia(references []Media, candidates []Media, options CrossOptions) CrossResult {
+	// References take the first indexes of the DSU and candidates the following ones
+	all := make([]Media, 0, len(references)+len(candidates))
+	all = append(all, references...)
+	all = append(all, candidates...)
+
+	start := time.Now()
+	d := dsu.NewDSU(len(all))
+	stats := GroupStats{}
+	matches := make([]CrossMatch, len(candidates))
+
+	for i, candidate := range candidates {
+		match := CrossMatch{Candidate: candidate}
+		ci := len(references) + i
+		previous := stats
+
+		for j := range references {
+			score, ok := stats.score(candidate, references[j], options.Prefilter)
+			if !ok || score < options.Threshold {
+				continue
+			}
+
+			stats.union(d, ci, j)
+
+			if match.Reference == nil || score > match.Score {
+				match.Reference = &references[j]
+				match.Score = score
+			}
+		}
+
+		if options.CompareCandidates {
+			for j := range i {
+				if stats.compare(candidates[j], candidate, options.GroupOptions) {
+					stats.union(d, ci, len(references)+j)
+				}
+			}
+		}
+
+		matches[i] = match
+		notify(options.Observer, stats.comparisonEvent(candidate.Name, previous))
+	}
+
+	groups := extractGroups(all, d, options.Order)
+	notify(options.Observer, FinishedEvent{
+		Loaded:   len(all),
+		Groups:   len(groups),
+		Stats:    stats,
+		Duration: time.Since(start),
+	})
+
+	return CrossResult{
+		Matches: matches,
+		Groups:  groups,
+		Stats:   stats,
+	}
+}
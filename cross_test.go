@@ -0,0 +1,79 @@
+package mediasim
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCrossCompareMedia(t *testing.T) {
+	whiteIcon := iconFromImage(createSolidImage(color.White, 100, 100))
+	blackIcon := iconFromImage(createSolidImage(color.Black, 100, 100))
+	halfIcon := iconFromImage(createHalfImage(100, 100))
+
+	references := []Media{
+		{Name: "archive/white.jpg", Type: "image", Size: 100, frames: frames{icons: []icon{whiteIcon}}},
+		{Name: "archive/white-copy.jpg", Type: "image", Size: 50, frames: frames{icons: []icon{whiteIcon}}},
+		{Name: "archive/black.jpg", Type: "image", frames: frames{icons: []icon{blackIcon}}},
+	}
+
+	candidates := []Media{
+		{Name: "incoming/black.jpg", Type: "image", frames: frames{icons: []icon{blackIcon}}},
+		{Name: "incoming/half.jpg", Type: "image", frames: frames{icons: []icon{halfIcon}}},
+		{Name: "incoming/half-copy.jpg", Type: "image", frames: frames{icons: []icon{halfIcon}}},
+	}
+
+	t.Run("reports the best reference match of each candidate", func(t *testing.T) {
+		result := CrossCompareMedia(references, candidates, CrossOptions{GroupOptions: GroupOptions{Threshold: 0.9}})
+
+		assert.Len(t, result.Matches, 3)
+		assert.Equal(t, "incoming/black.jpg", result.Matches[0].Candidate.Name)
+		assert.Equal(t, "archive/black.jpg", result.Matches[0].Reference.Name)
+		assert.Equal(t, 1.0, result.Matches[0].Score)
+		assert.Nil(t, result.Matches[1].Reference)
+		assert.Nil(t, result.Matches[2].Reference)
+	})
+
+	t.Run("only candidate-vs-reference pairs are compared", func(t *testing.T) {
+		result := CrossCompareMedia(references, candidates, CrossOptions{GroupOptions: GroupOptions{Threshold: 0.9}})
+
+		// The two white references are identical, but references are never compared with each other
+		assert.Equal(t, 9, result.Stats.Compared)
+		assert.Len(t, result.Groups, 1)
+		assert.ElementsMatch(t, []string{"archive/black.jpg", "incoming/black.jpg"}, names(result.Groups[0]))
+	})
+
+	t.Run("candidates can be compared with each other", func(t *testing.T) {
+		result := CrossCompareMedia(references, candidates, CrossOptions{
+			GroupOptions:      GroupOptions{Threshold: 0.9},
+			CompareCandidates: true,
+		})
+
+		assert.Equal(t, 12, result.Stats.Compared)
+		assert.Len(t, result.Groups, 2)
+		assert.Nil(t, result.Matches[1].Reference)
+	})
+
+	t.Run("prefilters prune pairs", func(t *testing.T) {
+		reference := Media{Name: "archive/small.jpg", Type: "image", Width: 100, Height: 100, frames: frames{icons: []icon{whiteIcon}}}
+		result := CrossCompareMedia([]Media{reference}, []Media{
+			{Name: "incoming/wide.jpg", Type: "image", Width: 3000, Height: 1000, frames: frames{icons: []icon{whiteIcon}}},
+		}, CrossOptions{GroupOptions: GroupOptions{
+			Threshold: 0.9,
+			Prefilter: PrefilterOptions{MaxResolutionRatio: 2},
+		}})
+
+		assert.Equal(t, 1, result.Stats.Pruned)
+		assert.Nil(t, result.Matches[0].Reference)
+	})
+}
+
+func names(media []Media) []string {
+	result := make([]string, len(media))
+	for i, m := range media {
+		result[i] = m.Name
+	}
+
+	return result
+}
@@ -0,0 +1,327 @@
+package mediasim
+
+import (
+	"cmp"
+	"image"
+	"image/color"
+	"math"
+	"slices"
+
+	"github.com/disintegration/imaging"
+	"github.com/vitali-fedulov/images4"
+)
+
+// Transform is a flip or a rotation applied to an image.
+type Transform string
+
+const (
+	TransformNone           Transform = "none"
+	TransformFlipVertical   Transform = "flip-vertical"
+	TransformFlipHorizontal Transform = "flip-horizontal"
+	// TransformRotate90 rotates the image 90° clockwise.
+	TransformRotate90  Transform = "rotate-90"
+	TransformRotate180 Transform = "rotate-180"
+	// TransformRotate270 rotates the image 270° clockwise, or 90° counter-clockwise.
+	TransformRotate270 Transform = "rotate-270"
+)
+
+// Region is a rectangle where two images are different, in the pixels of the first image.
+//
+// # Fields:
+//   - X, Y: The top left corner of the rectangle.
+//   - Width, Height: The size of the rectangle.
+//   - Difference: The mean difference of the changed areas inside the rectangle, between 0 and 1.
+type Region struct {
+	X          int     `json:"x"`
+	Y          int     `json:"y"`
+	Width      int     `json:"width"`
+	Height     int     `json:"height"`
+	Difference float64 `json:"difference"`
+}
+
+// Difference is the result of comparing two images pixel by pixel, after they are aligned.
+type Difference struct {
+	// Heatmap is the first image, in dark gray, with the differences painted from red (small) to yellow (large). Its
+	// longest side is DiffOptions.Size, unless the first image is smaller.
+	Heatmap image.Image `json:"-"`
+	// Transform is the flip or rotation applied to the second image to align it with the first.
+	Transform Transform `json:"transform"`
+	// Score is the similarity score of the aligned images, the same as CalculateSimilarity with the same FrameOptions.
+	Score float64 `json:"score"`
+	// Changed is the fraction of the area of the images that changed, between 0 and 1.
+	Changed float64 `json:"changed"`
+	// Regions are the areas that changed, the largest first.
+	Regions []Region `json:"regions"`
+}
+
+// DiffImages finds what is different between two similar images, like a watermark, a crop or a color grade.
+//
+// The second image is first aligned with the first, using the flipped or rotated variant enabled in the FrameOptions
+// that is the most similar, and scaled to the size of the first. The images are then compared in a grid of small
+// cells, and the neighbouring cells whose difference is above the threshold are merged into regions.
+//
+// # Parameters:
+//   - img1: The first image; the regions are in its pixels.
+//   - img2: The second image.
+//   - options: The configuration options for the comparison.
+//
+// # Returns:
+//   - The heatmap and the regions of the differences.
+func DiffImages(img1, img2 image.Image, options DiffOptions) Difference {
+	options.SetDefaults()
+
+	transform, score := alignImage(img1, img2, options.FrameOptions)
+
+	first := imaging.Fit(img1, options.Size, options.Size, imaging.Lanczos)
+	width, height := first.Bounds().Dx(), first.Bounds().Dy()
+	second := imaging.Resize(transform.apply(img2), width, height, imaging.Lanczos)
+
+	// The images are blurred a little, so the noise of the compression and the scaling isn't a difference
+	differences := pixelDifferences(imaging.Blur(first, 1), imaging.Blur(second, 1))
+	regions, changed := changedRegions(differences, width, height, options)
+
+	// The regions are scaled back to the pixels of the first image
+	scaleX := float64(img1.Bounds().Dx()) / float64(width)
+	scaleY := float64(img1.Bounds().Dy()) / float64(height)
+	for i, r := range regions {
+		x, y := int(math.Round(float64(r.X)*scaleX)), int(math.Round(float64(r.Y)*scaleY))
+		regions[i].Width = int(math.Round(float64(r.X+r.Width)*scaleX)) - x
+		regions[i].Height = int(math.Round(float64(r.Y+r.Height)*scaleY)) - y
+		regions[i].X, regions[i].Y = x, y
+	}
+
+	return Difference{
+		Heatmap:   heatmap(first, differences, options.Threshold),
+		Transform: transform,
+		Score:     score,
+		Changed:   changed,
+		Regions:   regions,
+	}
+}
+
+// DiffFiles finds what is different between two similar image files, as DiffImages does.
+//
+// The images are decoded the same way as by LoadMediaFromFile, so the same formats, limits and FFmpeg fallback apply,
+// except FastDecode: the images are always decoded at their full resolution, so the regions are in their pixels and the
+// edits missing from a stale EXIF thumbnail are found.
+//
+// # Parameters:
+//   - filePath1: The path to the first image; the regions are in its pixels.
+//   - filePath2: The path to the second image.
+//   - options: The configuration options for decoding and comparing the images.
+//
+// # Returns:
+//   - The heatmap and the regions of the differences.
+//   - An error if any of the images can't be decoded; it's a *MediaError, where videos are ErrUnsupportedFormat.
+func DiffFiles(filePath1, filePath2 string, options DiffOptions) (Difference, error) {
+	options.SetDefaults()
+
+	img1, err := loadImage(filePath1, options.LoadOptions)
+	if err != nil {
+		return Difference{}, err
+	}
+
+	img2, err := loadImage(filePath2, options.LoadOptions)
+	if err != nil {
+		return Difference{}, err
+	}
+
+	return DiffImages(img1, img2, options), nil
+}
+
+// region - Private functions
+
+// diffCells is the number of cells of the grid along the longest side of the images, when the regions are found.
+const diffCells = 64
+
+// apply returns the image with the transformation applied.
+func (t Transform) apply(img image.Image) image.Image {
+	switch t {
+	case TransformFlipVertical:
+		return imaging.FlipV(img)
+	case TransformFlipHorizontal:
+		return imaging.FlipH(img)
+	case TransformRotate90:
+		// The rotations of imaging are counter-clockwise
+		return imaging.Rotate270(img)
+	case TransformRotate180:
+		return imaging.Rotate180(img)
+	case TransformRotate270:
+		return imaging.Rotate90(img)
+	}
+
+	return img
+}
+
+// alignImage returns the transformation of the second image that makes it the most similar to the first, and the
+// similarity score of the transformed image. The icons are compared like in CalculateSimilarity.
+func alignImage(img1, img2 image.Image, options FrameOptions) (Transform, float64) {
+	icon1 := newIcon(images4.Icon(img1))
+	f := frames{icons: []icon{newIcon(images4.Icon(img2))}, options: options}
+	transform, score := TransformNone, -1.0
+
+	for _, t := range f.transforms() {
+		if s := calculateImageSimilarity(&icon1, &f.icons[0], t); s > score {
+			transform, score = t, s
+		}
+	}
+
+	return transform, score
+}
+
+// pixelDifferences returns the difference of each pixel of the images, which have the same size, between 0 and 1.
+func pixelDifferences(img1, img2 *image.NRGBA) []float64 {
+	width, height := img1.Bounds().Dx(), img1.Bounds().Dy()
+	differences := make([]float64, width*height)
+
+	for y := range height {
+		row1 := img1.Pix[y*img1.Stride:]
+		row2 := img2.Pix[y*img2.Stride:]
+
+		for x := range width {
+			sum := 0
+			for c := range 3 {
+				sum += abs(int(row1[x*4+c]) - int(row2[x*4+c]))
+			}
+
+			differences[y*width+x] = float64(sum) / (3 * 255)
+		}
+	}
+
+	return differences
+}
+
+// changedRegions divides the images in a grid of cells, and merges the neighbouring cells whose mean difference is
+// above the threshold into regions, in the pixels of the compared images. It also returns the fraction of the area
+// that changed.
+func changedRegions(differences []float64, width, height int, options DiffOptions) ([]Region, float64) {
+	cell := max(1, options.Size/diffCells)
+	cols, rows := (width+cell-1)/cell, (height+cell-1)/cell
+
+	// The mean difference of each cell, and its area in pixels
+	means := make([]float64, cols*rows)
+	areas := make([]int, cols*rows)
+	for y := range height {
+		for x := range width {
+			c := (y/cell)*cols + x/cell
+			means[c] += differences[y*width+x]
+			areas[c]++
+		}
+	}
+
+	changedArea := 0
+	changed := make([]bool, len(means))
+	for c := range means {
+		means[c] /= float64(areas[c])
+		if means[c] >= options.Threshold {
+			changed[c] = true
+			changedArea += areas[c]
+		}
+	}
+
+	// The regions are the connected changed cells, including the diagonal neighbours
+	regions := make([]Region, 0)
+	visited := make([]bool, len(means))
+
+	for start := range changed {
+		if !changed[start] || visited[start] {
+			continue
+		}
+
+		minCol, minRow, maxCol, maxRow := cols, rows, 0, 0
+		sum, count := 0.0, 0
+		queue := []int{start}
+		visited[start] = true
+
+		for len(queue) > 0 {
+			c := queue[0]
+			queue = queue[1:]
+			col, row := c%cols, c/cols
+
+			minCol, minRow = min(minCol, col), min(minRow, row)
+			maxCol, maxRow = max(maxCol, col), max(maxRow, row)
+			sum += means[c]
+			count++
+
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					nc, nr := col+dx, row+dy
+					if nc < 0 || nr < 0 || nc >= cols || nr >= rows {
+						continue
+					}
+
+					if n := nr*cols + nc; changed[n] && !visited[n] {
+						visited[n] = true
+						queue = append(queue, n)
+					}
+				}
+			}
+		}
+
+		regions = append(regions, Region{
+			X:          minCol * cell,
+			Y:          minRow * cell,
+			Width:      min((maxCol+1)*cell, width) - minCol*cell,
+			Height:     min((maxRow+1)*cell, height) - minRow*cell,
+			Difference: sum / float64(count),
+		})
+	}
+
+	slices.SortStableFunc(regions, func(a, b Region) int {
+		return cmp.Compare(b.Width*b.Height, a.Width*a.Height)
+	})
+
+	return regions, float64(changedArea) / float64(width*height)
+}
+
+// heatmap paints the differences over the image in dark gray, from red to yellow; the differences at twice the
+// threshold, or larger, are fully opaque.
+func heatmap(img *image.NRGBA, differences []float64, threshold float64) *image.NRGBA {
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+	out := image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	for y := range height {
+		for x := range width {
+			gray := float64(color.GrayModel.Convert(img.NRGBAAt(x, y)).(color.Gray).Y) * 0.35
+			alpha := min(1, differences[y*width+x]/(2*threshold))
+
+			out.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(gray*(1-alpha) + 255*alpha),
+				G: uint8(gray*(1-alpha) + 255*alpha*alpha),
+				B: uint8(gray * (1 - alpha)),
+				A: 255,
+			})
+		}
+	}
+
+	return out
+}
+
+// loadImage decodes the image file at its full resolution, never from its EXIF thumbnail; videos and other files are
+// ErrUnsupportedFormat.
+func loadImage(filePath string, options LoadOptions) (image.Image, error) {
+	options.FastDecode = false
+
+	file, mediaType, err := openMedia(filePath, options)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	if mediaType != "image" {
+		return nil, newMediaError(filePath, ErrUnsupportedFormat, nil)
+	}
+
+	return decodeImage(file, filePath, options)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
+
+// endregion
@@ -0,0 +1,143 @@
+package mediasim
+
+import (
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/draw"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/disintegration/imaging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createSmoothImage creates an image with a smooth gradient, which is different in every direction, so the flipped and
+// rotated images are never alike, and scaling it doesn't add noise.
+func createSmoothImage(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x * 255 / w), G: uint8(y * 255 / h), B: 128, A: 255})
+		}
+	}
+
+	return img
+}
+
+func TestDiffImages(t *testing.T) {
+	original := createSmoothImage(800, 600)
+
+	t.Run("identical images have no differences", func(t *testing.T) {
+		diff := DiffImages(original, imaging.Resize(original, 400, 300, imaging.Lanczos), DiffOptions{})
+
+		assert.Equal(t, TransformNone, diff.Transform)
+		assert.Greater(t, diff.Score, 0.99)
+		assert.Zero(t, diff.Changed)
+		assert.Empty(t, diff.Regions)
+		assert.Equal(t, image.Pt(512, 384), diff.Heatmap.Bounds().Size())
+	})
+
+	t.Run("the changed areas are regions in the pixels of the first image", func(t *testing.T) {
+		watermarked := imaging.Clone(original)
+		draw.Draw(watermarked, image.Rect(500, 400, 700, 500), image.NewUniform(color.White), image.Point{}, draw.Src)
+
+		diff := DiffImages(original, watermarked, DiffOptions{})
+
+		require.Len(t, diff.Regions, 1)
+		region := diff.Regions[0]
+
+		// The regions are aligned to the cells of the grid, so they can be a little larger than the watermark
+		assert.InDelta(t, 500, region.X, 20)
+		assert.InDelta(t, 400, region.Y, 20)
+		assert.InDelta(t, 200, region.Width, 40)
+		assert.InDelta(t, 100, region.Height, 40)
+		assert.Greater(t, region.Difference, 0.2)
+		assert.InDelta(t, 200.0*100/(800*600), diff.Changed, 0.02)
+	})
+
+	t.Run("the second image is aligned with the variant that matched", func(t *testing.T) {
+		options := DiffOptions{LoadOptions: LoadOptions{FrameOptions: FrameOptions{FrameFlip: true, FrameRotate: true}}}
+
+		// The rotations of imaging are counter-clockwise, so the clockwise rotation of 90° undoes its Rotate90
+		tests := map[Transform]image.Image{
+			TransformFlipVertical:   imaging.FlipV(original),
+			TransformFlipHorizontal: imaging.FlipH(original),
+			TransformRotate90:       imaging.Rotate90(original),
+			TransformRotate180:      imaging.Rotate180(original),
+			TransformRotate270:      imaging.Rotate270(original),
+		}
+
+		for expected, img := range tests {
+			diff := DiffImages(original, img, options)
+
+			assert.Equal(t, expected, diff.Transform)
+			assert.Empty(t, diff.Regions, expected)
+			assert.Equal(t, CalculateSimilarity(
+				LoadMediaFromImages("a", []image.Image{original}, options.FrameOptions),
+				LoadMediaFromImages("b", []image.Image{img}, options.FrameOptions),
+			), diff.Score, expected)
+		}
+	})
+
+	t.Run("without the variants, the flipped image is different", func(t *testing.T) {
+		diff := DiffImages(original, imaging.FlipH(original), DiffOptions{})
+
+		assert.Equal(t, TransformNone, diff.Transform)
+		assert.NotEmpty(t, diff.Regions)
+		assert.Greater(t, diff.Changed, 0.5)
+	})
+}
+
+func TestDiffFiles(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.png")
+	second := filepath.Join(dir, "second.png")
+	require.NoError(t, os.WriteFile(first, encodePng(t, createSmoothImage(160, 120)), 0o644))
+	require.NoError(t, os.WriteFile(second, encodePng(t, createSmoothImage(80, 60)), 0o644))
+
+	diff, err := DiffFiles(first, second, DiffOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, diff.Regions)
+	assert.Equal(t, image.Pt(160, 120), diff.Heatmap.Bounds().Size(), "small images aren't scaled up")
+
+	t.Run("images are decoded at their full resolution, never from their EXIF thumbnail", func(t *testing.T) {
+		original := createSmoothImage(600, 400)
+		thumbnail := encodeJpeg(t, imaging.Resize(original, 240, 160, imaging.Lanczos))
+
+		// The edited copy keeps the stale thumbnail of the original
+		edited := imaging.Clone(original)
+		draw.Draw(edited, image.Rect(300, 100, 400, 200), image.NewUniform(color.Black), image.Point{}, draw.Src)
+
+		originalPath := filepath.Join(dir, "original.jpg")
+		editedPath := filepath.Join(dir, "edited.jpg")
+		require.NoError(t, os.WriteFile(originalPath, withExifThumbnail(encodeJpeg(t, original), thumbnail,
+			binary.LittleEndian), 0o644))
+		require.NoError(t, os.WriteFile(editedPath, withExifThumbnail(encodeJpeg(t, edited), thumbnail,
+			binary.BigEndian), 0o644))
+
+		diff, err := DiffFiles(editedPath, originalPath, DiffOptions{LoadOptions: LoadOptions{FastDecode: true}})
+		require.NoError(t, err)
+		assert.Equal(t, image.Pt(512, 341), diff.Heatmap.Bounds().Size(), "the heatmap is scaled from the full image")
+		require.Len(t, diff.Regions, 1)
+		region := diff.Regions[0]
+		assert.InDelta(t, 300, region.X, 15)
+		assert.InDelta(t, 100, region.Y, 15)
+		assert.InDelta(t, 100, region.Width, 30)
+		assert.InDelta(t, 100, region.Height, 30)
+	})
+
+	t.Run("only images can be compared", func(t *testing.T) {
+		video := filepath.Join(dir, "clip.mp4")
+		require.NoError(t, os.WriteFile(video, []byte("video"), 0o644))
+
+		_, err = DiffFiles(first, video, DiffOptions{})
+		requireMediaError(t, err, ErrUnsupportedFormat, video)
+
+		missing := filepath.Join(dir, "missing.png")
+		_, err = DiffFiles(missing, second, DiffOptions{})
+		requireMediaError(t, err, ErrUnreadable, missing)
+	})
+}
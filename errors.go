@@ -0,0 +1,149 @@
+package mediasim
+
+import (
+	"errors"
+	"fmt"
+	"image"
+
+	iffmpeg "github.com/vegidio/mediasim/internal/ffmpeg"
+)
+
+var (
+	// ErrUnreadable is the kind of error returned when the file can't be opened or read.
+	ErrUnreadable = errors.New("unreadable file")
+	// ErrUnsupportedFormat is the kind of error returned when the media is not in one of the supported formats.
+	ErrUnsupportedFormat = errors.New("unsupported format")
+	// ErrDecode is the kind of error returned when the media is in a supported format, but can't be decoded; e.g., it's
+	// truncated or corrupted.
+	ErrDecode = errors.New("decoding failed")
+	// ErrFFmpegNotFound is the kind of error returned when FFmpeg is needed, but can't be found or started.
+	ErrFFmpegNotFound = errors.New("FFmpeg not found")
+	// ErrFFmpegFailed is the kind of error returned when FFmpeg runs but fails; its output is in MediaError.Stderr.
+	ErrFFmpegFailed = errors.New("FFmpeg failed")
+	// ErrNoVideoStream is the kind of error returned when a video has no video stream to extract frames from.
+	ErrNoVideoStream = errors.New("no video stream")
+	// ErrFFmpegTimeout is the kind of error returned when FFmpeg was killed because it exceeded the timeout in
+	// LoadOptions.FFmpegTimeout.
+	ErrFFmpegTimeout = errors.New("FFmpeg timed out")
+	// ErrTooManyPixels is the kind of error returned when an image has more pixels than LoadOptions.MaxPixels; it's
+	// rejected before being decoded.
+	ErrTooManyPixels = errors.New("too many pixels")
+	// ErrFileTooLarge is the kind of error returned when an image is larger than LoadOptions.MaxFileSize.
+	ErrFileTooLarge = errors.New("file too large")
+)
+
+// MediaError is the error returned when a media fails to load. It matches its kind with errors.Is, and the error that
+// caused it with errors.Is and errors.As:
+//
+//	var mediaErr *mediasim.MediaError
+//	if errors.As(err, &mediaErr) && errors.Is(err, mediasim.ErrFFmpegFailed) {
+//	    fmt.Println(mediaErr.Path, mediaErr.Stderr)
+//	}
+//
+// # Fields:
+//   - Path: The path, or name, of the media that failed to load.
+//   - Kind: What went wrong; one of the Err* variables of the package, like ErrDecode.
+//   - Stderr: The end of the output of FFmpeg, when it was run; it usually explains why it failed.
+//   - Err: The error that caused the failure; it may be nil.
+type MediaError struct {
+	Path   string
+	Kind   error
+	Stderr string
+	Err    error
+}
+
+func (e *MediaError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("%v '%s'", e.Kind, e.Path)
+	}
+
+	return fmt.Sprintf("%v '%s': %v", e.Kind, e.Path, e.Err)
+}
+
+func (e *MediaError) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+
+	return []error{e.Kind, e.Err}
+}
+
+// ErrorCategory returns a short, stable name for the kind of the error, to be used in reports and machine-readable
+// output.
+//
+// # Parameters:
+//   - err: The error returned when loading a media.
+//
+// # Returns:
+//   - One of "unreadable", "unsupported_format", "decode", "too_many_pixels", "file_too_large", "ffmpeg_not_found",
+//     "ffmpeg_failed", "ffmpeg_timeout" or "no_video_stream"; "other" for errors of other kinds, or an empty string
+//     when err is nil.
+func ErrorCategory(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrUnreadable):
+		return "unreadable"
+	case errors.Is(err, ErrUnsupportedFormat):
+		return "unsupported_format"
+	case errors.Is(err, ErrDecode):
+		return "decode"
+	case errors.Is(err, ErrTooManyPixels):
+		return "too_many_pixels"
+	case errors.Is(err, ErrFileTooLarge):
+		return "file_too_large"
+	case errors.Is(err, ErrFFmpegNotFound):
+		return "ffmpeg_not_found"
+	case errors.Is(err, ErrFFmpegTimeout):
+		return "ffmpeg_timeout"
+	case errors.Is(err, ErrNoVideoStream):
+		return "no_video_stream"
+	case errors.Is(err, ErrFFmpegFailed):
+		return "ffmpeg_failed"
+	default:
+		return "other"
+	}
+}
+
+// region - Private functions
+
+// newMediaError creates the error of a media that failed to load.
+func newMediaError(path string, kind error, err error) *MediaError {
+	return &MediaError{Path: path, Kind: kind, Err: err}
+}
+
+// decodeError creates the error of an image that the Go decoders failed to decode. Images whose format isn't
+// recognised by any registered decoder are reported as unsupported.
+func decodeError(path string, err error) *MediaError {
+	if errors.Is(err, image.ErrFormat) {
+		return newMediaError(path, ErrUnsupportedFormat, err)
+	}
+
+	return newMediaError(path, ErrDecode, err)
+}
+
+// ffmpegError creates the error of a media that FFmpeg failed to decode, keeping the output of FFmpeg.
+func ffmpegError(path string, err error) *MediaError {
+	mediaErr := newMediaError(path, ErrFFmpegFailed, err)
+
+	var execErr *iffmpeg.ExecError
+	if errors.As(err, &execErr) {
+		mediaErr.Stderr = execErr.Stderr
+	}
+
+	switch {
+	case errors.Is(err, iffmpeg.ErrNotFound):
+		mediaErr.Kind = ErrFFmpegNotFound
+	case errors.Is(err, iffmpeg.ErrTimeout):
+		mediaErr.Kind = ErrFFmpegTimeout
+	case errors.Is(err, iffmpeg.ErrNoVideoStream):
+		mediaErr.Kind = ErrNoVideoStream
+	case execErr == nil:
+		// FFmpeg succeeded, but its output couldn't be loaded
+		mediaErr.Kind = ErrDecode
+	}
+
+	return mediaErr
+}
+
+// endregion
@@ -0,0 +1,138 @@
+package mediasim
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scriptFFmpeg replaces the FFmpeg binary with a shell script with the given body.
+func scriptFFmpeg(t *testing.T, body string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the FFmpeg stub is a shell script")
+	}
+
+	scriptPath := filepath.Join(t.TempDir(), "ffmpeg")
+	require.NoError(t, os.WriteFile(scriptPath, []byte("#!/bin/sh\n"+body+"\n"), 0o755))
+
+	SetFFmpegPath(scriptPath)
+	t.Cleanup(func() { SetFFmpegPath("") })
+}
+
+// requireMediaError asserts that err is a *MediaError of the given kind and path.
+func requireMediaError(t *testing.T, err error, kind error, path string) *MediaError {
+	t.Helper()
+
+	var mediaErr *MediaError
+	require.ErrorAs(t, err, &mediaErr)
+	assert.ErrorIs(t, err, kind)
+	assert.Equal(t, path, mediaErr.Path)
+
+	return mediaErr
+}
+
+func TestLoadMediaFromFile_Errors(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, content []byte) string {
+		filePath := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(filePath, content, 0o644))
+		return filePath
+	}
+
+	t.Run("missing files are unreadable", func(t *testing.T) {
+		filePath := filepath.Join(dir, "missing.jpg")
+		_, err := LoadMediaFromFileWithOptions(filePath, LoadOptions{})
+
+		requireMediaError(t, err, ErrUnreadable, filePath)
+		assert.ErrorIs(t, err, iofs.ErrNotExist)
+		assert.Equal(t, "unreadable", ErrorCategory(err))
+	})
+
+	t.Run("unknown content is an unsupported format", func(t *testing.T) {
+		filePath := write("text.png", []byte("this is not an image"))
+		_, err := LoadMediaFromFileWithOptions(filePath, LoadOptions{})
+
+		requireMediaError(t, err, ErrUnsupportedFormat, filePath)
+	})
+
+	t.Run("unknown extensions are an unsupported format", func(t *testing.T) {
+		filePath := write("notes.txt", []byte("text"))
+		_, err := LoadMediaFromFileWithOptions(filePath, LoadOptions{})
+
+		requireMediaError(t, err, ErrUnsupportedFormat, filePath)
+		assert.EqualError(t, err, fmt.Sprintf("unsupported format '%s'", filePath))
+	})
+
+	t.Run("truncated images fail to decode", func(t *testing.T) {
+		content := encodePng(t, createSolidImage(color.White, 50, 50))
+		filePath := write("truncated.png", content[:len(content)/2])
+		_, err := LoadMediaFromFileWithOptions(filePath, LoadOptions{})
+
+		requireMediaError(t, err, ErrDecode, filePath)
+		assert.Equal(t, "decode", ErrorCategory(err))
+	})
+
+	t.Run("missing FFmpeg", func(t *testing.T) {
+		filePath := write("video.mp4", []byte("video"))
+		_, err := LoadMediaFromFileWithOptions(filePath, LoadOptions{FFmpegPath: filepath.Join(dir, "no-ffmpeg")})
+
+		requireMediaError(t, err, ErrFFmpegNotFound, filePath)
+		assert.Equal(t, "ffmpeg_not_found", ErrorCategory(err))
+	})
+
+	t.Run("FFmpeg failures keep its output", func(t *testing.T) {
+		scriptFFmpeg(t, "echo 'moov atom not found' >&2\nexit 1")
+
+		filePath := write("broken.mp4", []byte("video"))
+		_, err := LoadMediaFromFileWithOptions(filePath, LoadOptions{})
+
+		mediaErr := requireMediaError(t, err, ErrFFmpegFailed, filePath)
+		assert.Contains(t, mediaErr.Stderr, "moov atom not found")
+		assert.Contains(t, err.Error(), "moov atom not found")
+		assert.Equal(t, "ffmpeg_failed", ErrorCategory(err))
+	})
+
+	t.Run("audio files have no video stream", func(t *testing.T) {
+		scriptFFmpeg(t, "echo 'Output file #0 does not contain any stream' >&2\nexit 1")
+
+		filePath := write("audio.mp4", []byte("audio"))
+		_, err := LoadMediaFromFileWithOptions(filePath, LoadOptions{})
+
+		requireMediaError(t, err, ErrNoVideoStream, filePath)
+		assert.Equal(t, "no_video_stream", ErrorCategory(err))
+	})
+
+	t.Run("FFmpeg exporting no frames means no video stream", func(t *testing.T) {
+		scriptFFmpeg(t, "exit 0")
+
+		filePath := write("empty.mp4", []byte("video"))
+		_, err := LoadMediaFromFileWithOptions(filePath, LoadOptions{})
+
+		requireMediaError(t, err, ErrNoVideoStream, filePath)
+	})
+
+	t.Run("failed fallbacks report the FFmpeg error", func(t *testing.T) {
+		scriptFFmpeg(t, "echo 'Invalid data found when processing input' >&2\nexit 1")
+
+		filePath := write("photo.jxl", []byte("jpeg xl"))
+		_, err := LoadMediaFromFileWithOptions(filePath, LoadOptions{FFmpegFallback: []string{".jxl"}})
+
+		mediaErr := requireMediaError(t, err, ErrFFmpegFailed, filePath)
+		assert.Contains(t, mediaErr.Stderr, "Invalid data")
+	})
+}
+
+func TestErrorCategory(t *testing.T) {
+	assert.Equal(t, "", ErrorCategory(nil))
+	assert.Equal(t, "other", ErrorCategory(errors.New("boom")))
+	assert.Equal(t, "unsupported_format", ErrorCategory(fmt.Errorf("wrapped: %w", newMediaError("a", ErrUnsupportedFormat, nil))))
+}
@@ -0,0 +1,270 @@
+// Package evaluation measures how well the grouping of mediasim matches a labelled dataset, so changes to the
+// similarity algorithms, thresholds and prefilters can be compared with numbers.
+package evaluation
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/vegidio/mediasim"
+	"github.com/vegidio/mediasim/internal/dsu"
+)
+
+// Strategy is a named configuration of the grouping, evaluated at every threshold.
+//
+// # Fields:
+//   - Name: The name of the strategy, as shown in the results.
+//   - Prefilter: The metadata checks used to skip pairs before they are scored.
+type Strategy struct {
+	Name      string                    `json:"name"`
+	Prefilter mediasim.PrefilterOptions `json:"prefilter"`
+}
+
+// Options represents the configuration options for evaluating the grouping.
+//
+// # Fields:
+//   - Thresholds: The similarity thresholds to evaluate. Empty means DefaultThresholds.
+//   - Strategies: The configurations of the grouping to evaluate. Empty means a single strategy named "default",
+//     without prefilters.
+type Options struct {
+	Thresholds []float64
+	Strategies []Strategy
+}
+
+// DefaultThresholds are the thresholds evaluated when Options.Thresholds is empty.
+var DefaultThresholds = []float64{0.5, 0.55, 0.6, 0.65, 0.7, 0.75, 0.8, 0.85, 0.9, 0.95}
+
+// SetDefaults sets the default values of the options that weren't set.
+func (o *Options) SetDefaults() {
+	if len(o.Thresholds) == 0 {
+		o.Thresholds = DefaultThresholds
+	}
+
+	if len(o.Strategies) == 0 {
+		o.Strategies = []Strategy{{Name: "default"}}
+	}
+}
+
+// Result is the quality of the groups found by a strategy with a threshold.
+//
+// The pairwise metrics consider every pair of media: a pair is a true positive when both media are in the same group
+// and in the same ground-truth cluster. Purity is the fraction of the grouped media that belong to the most common
+// cluster of their group; it's 0 when there are no groups.
+type Result struct {
+	Strategy string `json:"strategy"`
+	mediasim.ThresholdMetrics
+	Purity float64 `json:"purity"`
+	Groups int     `json:"groups"`
+}
+
+// Report is the evaluation of the grouping of a labelled dataset.
+type Report struct {
+	// Media is the number of media evaluated.
+	Media int `json:"media"`
+	// Clusters is the number of ground-truth clusters with at least one loaded media.
+	Clusters int `json:"clusters"`
+	// Missing are the files of the ground truth that aren't in the media; they are ignored.
+	Missing []string `json:"missing"`
+	// Results has the metrics of every strategy and threshold, in the order of the options.
+	Results []Result `json:"results"`
+	// Best has the result with the best F1 score of each strategy; ties go to the highest threshold.
+	Best []Result `json:"best"`
+}
+
+// Evaluate groups the media with every strategy and threshold, and compares the groups with the ground-truth clusters.
+//
+// The similarity of each pair of media is calculated only once, and the groups of each threshold are the same as those
+// of mediasim.GroupMediaWithOptions with the strategy's prefilters. Media that aren't in any cluster are considered
+// unique, so grouping them with any other media is a false positive.
+//
+// # Parameters:
+//   - media: The media of the dataset.
+//   - clusters: The names of the media of each ground-truth cluster, as in Media.Name.
+//   - options: The thresholds and strategies to evaluate.
+//
+// # Returns:
+//   - The metrics of every strategy and threshold.
+func Evaluate(media []mediasim.Media, clusters [][]string, options Options) Report {
+	options.SetDefaults()
+
+	truth, missing := labelMedia(media, clusters)
+	report := Report{Media: len(media), Missing: missing, Results: make([]Result, 0)}
+
+	distinct := make(map[int]bool)
+	for _, label := range truth {
+		if label < len(clusters) {
+			distinct[label] = true
+		}
+	}
+
+	report.Clusters = len(distinct)
+
+	lowest := slices.Min(options.Thresholds)
+	pairs := scorePairs(media, lowest)
+
+	// The thresholds are visited from the highest to the lowest, so each one only adds pairs to the groups
+	order := make([]int, len(options.Thresholds))
+	for i := range order {
+		order[i] = i
+	}
+
+	slices.SortStableFunc(order, func(a, b int) int {
+		return cmp.Compare(options.Thresholds[b], options.Thresholds[a])
+	})
+
+	for _, strategy := range options.Strategies {
+		results := make([]Result, len(options.Thresholds))
+		d := dsu.NewDSU(len(media))
+		next := 0
+
+		for _, i := range order {
+			threshold := options.Thresholds[i]
+
+			for ; next < len(pairs) && pairs[next].score >= threshold; next++ {
+				p := pairs[next]
+				if strategy.Prefilter.Accept(media[p.i], media[p.j]) {
+					d.Union(p.i, p.j)
+				}
+			}
+
+			results[i] = measure(d, truth)
+			results[i].Strategy = strategy.Name
+			results[i].Threshold = threshold
+		}
+
+		report.Results = append(report.Results, results...)
+		report.Best = append(report.Best, slices.MaxFunc(results, func(a, b Result) int {
+			return cmp.Or(cmp.Compare(a.F1, b.F1), cmp.Compare(a.Threshold, b.Threshold))
+		}))
+	}
+
+	return report
+}
+
+// region - Private functions
+
+// scoredPair is a pair of media, by their indexes, and their similarity.
+type scoredPair struct {
+	i, j  int
+	score float64
+}
+
+// labelMedia returns the ground-truth cluster of each media, and the names of the clusters that aren't in the media.
+// Media that aren't in any cluster get a label of their own, after the labels of the clusters.
+func labelMedia(media []mediasim.Media, clusters [][]string) ([]int, []string) {
+	byName := make(map[string]int, len(media))
+	for i, m := range media {
+		byName[m.Name] = i
+	}
+
+	truth := make([]int, len(media))
+	for i := range truth {
+		truth[i] = -1
+	}
+
+	missing := make([]string, 0)
+	for label, cluster := range clusters {
+		for _, name := range cluster {
+			i, ok := byName[name]
+			if !ok {
+				missing = append(missing, name)
+				continue
+			}
+
+			truth[i] = label
+		}
+	}
+
+	for i := range truth {
+		if truth[i] < 0 {
+			truth[i] = len(clusters) + i
+		}
+	}
+
+	return truth, missing
+}
+
+// scorePairs calculates the similarity of every pair of media, returning the pairs with a score equal to or above the
+// lowest threshold, from the most to the least similar.
+func scorePairs(media []mediasim.Media, lowest float64) []scoredPair {
+	pairs := make([]scoredPair, 0)
+
+	for i := range media {
+		for j := i + 1; j < len(media); j++ {
+			if score := mediasim.CalculateSimilarity(media[i], media[j]); score >= lowest {
+				pairs = append(pairs, scoredPair{i: i, j: j, score: score})
+			}
+		}
+	}
+
+	slices.SortStableFunc(pairs, func(a, b scoredPair) int {
+		return cmp.Compare(b.score, a.score)
+	})
+
+	return pairs
+}
+
+// measure compares the groups of the DSU with the ground-truth labels.
+func measure(d *dsu.DSU, truth []int) Result {
+	groups := make(map[int]int)
+	clusters := make(map[int]int)
+	both := make(map[[2]int]int)
+
+	for i, label := range truth {
+		group := d.Find(i)
+		groups[group]++
+		clusters[label]++
+		both[[2]int{group, label}]++
+	}
+
+	// The number of pairs in the same group, in the same cluster, and in both
+	predicted, actual, matching := 0, 0, 0
+	for _, n := range groups {
+		predicted += n * (n - 1) / 2
+	}
+	for _, n := range clusters {
+		actual += n * (n - 1) / 2
+	}
+	for _, n := range both {
+		matching += n * (n - 1) / 2
+	}
+
+	result := Result{}
+	result.TruePositives = matching
+	result.FalsePositives = predicted - matching
+	result.FalseNegatives = actual - matching
+
+	if predicted > 0 {
+		result.Precision = float64(matching) / float64(predicted)
+	}
+	if actual > 0 {
+		result.Recall = float64(matching) / float64(actual)
+	}
+	if result.Precision+result.Recall > 0 {
+		result.F1 = 2 * result.Precision * result.Recall / (result.Precision + result.Recall)
+	}
+
+	// The purity only considers the groups of 2+ media, as they are reported by the grouping
+	grouped, majority := 0, make(map[int]int)
+	for key, n := range both {
+		if groups[key[0]] >= 2 {
+			majority[key[0]] = max(majority[key[0]], n)
+		}
+	}
+
+	for group, n := range groups {
+		if n >= 2 {
+			result.Groups++
+			grouped += n
+			result.Purity += float64(majority[group])
+		}
+	}
+
+	if grouped > 0 {
+		result.Purity /= float64(grouped)
+	}
+
+	return result
+}
+
+// endregion
@@ -0,0 +1,158 @@
+package evaluation
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vegidio/mediasim"
+	"github.com/vegidio/mediasim/internal/dsu"
+)
+
+// createDataset creates media with random colored rectangles, where the first duplicates have a copy with half the
+// resolution. Each pair of copies is a ground-truth cluster.
+func createDataset(size, duplicates int) ([]mediasim.Media, [][]string) {
+	random := rand.New(rand.NewPCG(3, 3))
+	media := make([]mediasim.Media, 0, size+duplicates)
+	clusters := make([][]string, 0, duplicates)
+
+	for i := range size {
+		render := randomRectangles(random)
+		name := fmt.Sprintf("%02d.png", i)
+		media = append(media, mediasim.LoadMediaFromImages(name, []image.Image{render(160, 120)}, mediasim.FrameOptions{}))
+
+		if i < duplicates {
+			copied := mediasim.LoadMediaFromImages(name+".small.png", []image.Image{render(80, 60)}, mediasim.FrameOptions{})
+			media = append(media, copied)
+			clusters = append(clusters, []string{name, copied.Name})
+		}
+	}
+
+	return media, clusters
+}
+
+// randomRectangles returns a function that draws the same random rectangles in an image of any size.
+func randomRectangles(random *rand.Rand) func(w, h int) image.Image {
+	type rectangle struct {
+		x1, y1, x2, y2 float64
+		color          color.Color
+	}
+
+	randomColor := func() color.Color {
+		return color.RGBA{R: uint8(random.IntN(256)), G: uint8(random.IntN(256)), B: uint8(random.IntN(256)), A: 255}
+	}
+
+	background := randomColor()
+	rectangles := make([]rectangle, 6)
+	for i := range rectangles {
+		x, y := random.Float64(), random.Float64()
+		rectangles[i] = rectangle{x, y, x + random.Float64()/2 + 0.125, y + random.Float64()/2 + 0.125, randomColor()}
+	}
+
+	return func(w, h int) image.Image {
+		img := image.NewRGBA(image.Rect(0, 0, w, h))
+		draw.Draw(img, img.Bounds(), &image.Uniform{C: background}, image.Point{}, draw.Src)
+
+		for _, r := range rectangles {
+			rect := image.Rect(int(r.x1*float64(w)), int(r.y1*float64(h)), int(r.x2*float64(w)), int(r.y2*float64(h)))
+			draw.Draw(img, rect, &image.Uniform{C: r.color}, image.Point{}, draw.Src)
+		}
+
+		return img
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	media, clusters := createDataset(30, 6)
+	thresholds := []float64{0, 0.9, 0.5, 0.99, 0.8}
+
+	t.Run("the metrics are the same as those of the grouping", func(t *testing.T) {
+		report := Evaluate(media, clusters, Options{Thresholds: thresholds})
+
+		assert.Equal(t, len(media), report.Media)
+		assert.Equal(t, 6, report.Clusters)
+		assert.Empty(t, report.Missing)
+		require.Len(t, report.Results, len(thresholds))
+
+		for i, result := range report.Results {
+			groups, _ := mediasim.GroupMediaWithOptions(media, mediasim.GroupOptions{Threshold: thresholds[i]})
+
+			predicted := lo.SumBy(groups, func(group []mediasim.Media) int { return len(group) * (len(group) - 1) / 2 })
+			assert.Equal(t, "default", result.Strategy)
+			assert.Equal(t, thresholds[i], result.Threshold)
+			assert.Equal(t, len(groups), result.Groups)
+			assert.Equal(t, predicted, result.TruePositives+result.FalsePositives, "threshold %v", thresholds[i])
+		}
+
+		// Every media of the same type is grouped with a threshold of 0
+		assert.Equal(t, 1, report.Results[0].Groups)
+		assert.Equal(t, 1.0, report.Results[0].Recall)
+		assert.InDelta(t, 2.0/float64(len(media)), report.Results[0].Purity, 1e-9)
+
+		require.Len(t, report.Best, 1)
+		assert.Equal(t, 1.0, report.Best[0].F1)
+		assert.Equal(t, 1.0, report.Best[0].Purity)
+		assert.Equal(t, 6, report.Best[0].Groups)
+	})
+
+	t.Run("strategies are evaluated separately", func(t *testing.T) {
+		report := Evaluate(media, clusters, Options{
+			Thresholds: []float64{0.9},
+			Strategies: []Strategy{
+				{Name: "exhaustive"},
+				{Name: "same-resolution", Prefilter: mediasim.PrefilterOptions{MaxResolutionRatio: 2}},
+			},
+		})
+
+		require.Len(t, report.Results, 2)
+		assert.Equal(t, "exhaustive", report.Results[0].Strategy)
+		assert.Equal(t, 1.0, report.Results[0].Recall)
+
+		// The copies have a quarter of the pixels, so they are all pruned
+		assert.Equal(t, "same-resolution", report.Results[1].Strategy)
+		assert.Zero(t, report.Results[1].Recall)
+		assert.Equal(t, 6, report.Results[1].FalseNegatives)
+		assert.Equal(t, report.Results, report.Best)
+	})
+
+	t.Run("files of the ground truth that weren't loaded are ignored", func(t *testing.T) {
+		extended := append(clusters, []string{"missing.png", "00.png.small.png.copy"})
+		report := Evaluate(media, extended, Options{Thresholds: []float64{0.9}})
+
+		assert.Equal(t, []string{"missing.png", "00.png.small.png.copy"}, report.Missing)
+		assert.Equal(t, 6, report.Clusters)
+		assert.Equal(t, 1.0, report.Results[0].F1)
+	})
+}
+
+func TestMeasure(t *testing.T) {
+	// Clusters {0, 1, 2} and {3, 4}, and the unique media 5
+	truth := []int{0, 0, 0, 1, 1, 7}
+
+	// Groups {0, 1}, {2, 3, 4, 5}
+	d := dsu.NewDSU(len(truth))
+	d.Union(0, 1)
+	d.Union(2, 3)
+	d.Union(3, 4)
+	d.Union(4, 5)
+
+	result := measure(d, truth)
+
+	// Predicted pairs: 1 + 6; actual pairs: 3 + 1; matching: (0,1) and (3,4)
+	assert.Equal(t, 2, result.TruePositives)
+	assert.Equal(t, 5, result.FalsePositives)
+	assert.Equal(t, 2, result.FalseNegatives)
+	assert.InDelta(t, 2.0/7, result.Precision, 1e-9)
+	assert.InDelta(t, 0.5, result.Recall, 1e-9)
+	assert.InDelta(t, 4.0/11, result.F1, 1e-9)
+
+	// The majorities are 2 of {0, 1} and 2 of {2, 3, 4, 5}
+	assert.Equal(t, 2, result.Groups)
+	assert.InDelta(t, 4.0/6, result.Purity, 1e-9)
+}
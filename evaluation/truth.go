@@ -0,0 +1,69 @@
+package evaluation
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// ReadClusters reads the ground-truth clusters of duplicates, one cluster per line with the paths of its files separated
+// by commas, like "a.jpg,b.jpg,c.jpg". Paths with commas can be quoted, and lines starting with # are ignored.
+//
+// Files that aren't in any cluster are considered unique, so single-file clusters are optional.
+//
+// # Parameters:
+//   - r: The reader of the ground-truth file.
+//   - base: The directory that relative paths are relative to.
+//
+// # Returns:
+//   - The paths of the files of each cluster.
+//   - An error if the file is invalid, or when a file is in more than one cluster.
+func ReadClusters(r io.Reader, base string) ([][]string, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	clusters := make([][]string, 0)
+	lines := make(map[string]int)
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("invalid ground truth: %w", err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		cluster := make([]string, 0, len(record))
+
+		for _, name := range record {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+
+			if !filepath.IsAbs(name) {
+				name = filepath.Join(base, name)
+			}
+
+			name = filepath.Clean(name)
+			if previous, ok := lines[name]; ok {
+				return nil, fmt.Errorf("the file %s is in the clusters of lines %d and %d", name, previous, line)
+			}
+
+			lines[name] = line
+			cluster = append(cluster, name)
+		}
+
+		if len(cluster) > 0 {
+			clusters = append(clusters, cluster)
+		}
+	}
+
+	return clusters, nil
+}
@@ -0,0 +1,43 @@
+package evaluation
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadClusters(t *testing.T) {
+	base := filepath.FromSlash("/data")
+
+	t.Run("one cluster per line", func(t *testing.T) {
+		content := "# The bridge photos\n" +
+			"bridge.jpg, bridge-small.jpg,edited/bridge.png\n" +
+			"\n" +
+			"\"cat, sleeping.jpg\",/other/cat.jpg,\n"
+
+		clusters, err := ReadClusters(strings.NewReader(content), base)
+		require.NoError(t, err)
+
+		assert.Equal(t, [][]string{
+			{
+				filepath.Join(base, "bridge.jpg"),
+				filepath.Join(base, "bridge-small.jpg"),
+				filepath.Join(base, "edited", "bridge.png"),
+			},
+			{filepath.Join(base, "cat, sleeping.jpg"), filepath.Clean("/other/cat.jpg")},
+		}, clusters)
+	})
+
+	t.Run("a file can't be in two clusters", func(t *testing.T) {
+		_, err := ReadClusters(strings.NewReader("a.jpg,b.jpg\nc.jpg,./a.jpg\n"), base)
+		assert.ErrorContains(t, err, "lines 1 and 2")
+	})
+
+	t.Run("invalid quotes", func(t *testing.T) {
+		_, err := ReadClusters(strings.NewReader("\"a.jpg,b.jpg\n"), base)
+		assert.ErrorContains(t, err, "invalid ground truth")
+	})
+}
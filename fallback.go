@@ -0,0 +1,112 @@
+package mediasim
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"path"
+	"slices"
+	"strings"
+
+	iffmpeg "github.com/vegidio/mediasim/internal/ffmpeg"
+)
+
+// FallbackAll enables the FFmpeg fallback for every image type in the registry, when used in LoadOptions.FFmpegFallback.
+const FallbackAll = "*"
+
+// region - Private functions
+
+// usesFallback reports whether images with the extension of the name are decoded with FFmpeg when the Go decoders
+// fail.
+func (o LoadOptions) usesFallback(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	return slices.ContainsFunc(o.FFmpegFallback, func(e string) bool {
+		return e == FallbackAll || strings.ToLower(e) == ext
+	})
+}
+
+// fallbackTypes returns the extensions listed in the FFmpeg fallback, which are handled as images even when they are
+// not in the type registry.
+func (o LoadOptions) fallbackTypes() []string {
+	types := make([]string, 0, len(o.FFmpegFallback))
+	for _, ext := range o.FFmpegFallback {
+		if ext != FallbackAll {
+			types = append(types, strings.ToLower(ext))
+		}
+	}
+
+	return types
+}
+
+// decodeImageFile decodes the image in the file, falling back to FFmpeg when it's enabled for the extension.
+func (o LoadOptions) decodeImageFile(r io.Reader, filePath string) (image.Image, error) {
+	return o.decodeImage(r, filePath, func() (image.Image, error) {
+		return runFFmpeg(o, func(ctx context.Context, config iffmpeg.Config) (image.Image, error) {
+			return iffmpeg.ExtractFrame(ctx, filePath, o.limitFrame(config, filePath))
+		})
+	})
+}
+
+// decodeImageStream decodes the image streamed through the reader, falling back to FFmpeg when it's enabled for the
+// extension. The content consumed by the Go decoders is kept in memory, so it can be streamed to FFmpeg again.
+func (o LoadOptions) decodeImageStream(r io.Reader, name string) (image.Image, error) {
+	if !o.usesFallback(name) {
+		return o.decodeImage(r, name, nil)
+	}
+
+	var consumed bytes.Buffer
+	return o.decodeImage(io.TeeReader(r, &consumed), name, func() (image.Image, error) {
+		return runFFmpeg(o, func(ctx context.Context, config iffmpeg.Config) (image.Image, error) {
+			return iffmpeg.ExtractFrameFromReader(ctx, io.MultiReader(&consumed, r), o.limitFrame(config, name))
+		})
+	})
+}
+
+// limitFrame applies the limits of the options to the frame decoded by FFmpeg: FFmpeg refuses to decode images with more
+// pixels than MaxPixels, and the frame it exports is decoded by decodeLimited, so it's checked again and waits for its
+// share of the memory budget like the images decoded by Go.
+func (o LoadOptions) limitFrame(config iffmpeg.Config, name string) iffmpeg.Config {
+	config.MaxPixels = max(o.MaxPixels, 0)
+	config.DecodeFrame = func(r io.Reader) (image.Image, error) {
+		return o.decodeLimited(r, name)
+	}
+
+	return config
+}
+
+// decodeImage decodes the image with the Go decoders and, if they fail and the fallback is enabled for the extension,
+// with FFmpeg. When both fail, the error has the kind of the FFmpeg failure and the reasons of both decoders.
+func (o LoadOptions) decodeImage(r io.Reader, name string, fallback func() (image.Image, error)) (image.Image, error) {
+	img, err := o.decodeLimited(r, name)
+	if err == nil {
+		return img, nil
+	}
+
+	// Images rejected by the limits aren't decoded with FFmpeg either
+	var limitErr *MediaError
+	if errors.As(err, &limitErr) {
+		return nil, limitErr
+	}
+
+	if fallback == nil || !o.usesFallback(name) {
+		return nil, decodeError(name, err)
+	}
+
+	img, fallbackErr := fallback()
+	if errors.As(fallbackErr, &limitErr) {
+		return nil, limitErr
+	}
+
+	if fallbackErr != nil {
+		mediaErr := ffmpegError(name, fallbackErr)
+		mediaErr.Err = fmt.Errorf("%v; the FFmpeg fallback also failed: %w", err, fallbackErr)
+		return nil, mediaErr
+	}
+
+	return img, nil
+}
+
+// endregion
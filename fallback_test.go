@@ -0,0 +1,124 @@
+package mediasim
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingFFmpeg replaces the FFmpeg binary with a shell script that always fails.
+func failingFFmpeg(t *testing.T) {
+	t.Helper()
+	scriptFFmpeg(t, "echo 'invalid data' >&2\nexit 1")
+}
+
+func TestLoadMediaFromFile_FFmpegFallback(t *testing.T) {
+	img := createGradientImage(64, 48)
+	dir := t.TempDir()
+
+	jxlPath := filepath.Join(dir, "photo.jxl")
+	brokenPath := filepath.Join(dir, "odd.tiff")
+	require.NoError(t, os.WriteFile(jxlPath, []byte("fake jpeg xl content"), 0o644))
+	require.NoError(t, os.WriteFile(brokenPath, []byte("II*\x00 odd tiff variant"), 0o644))
+
+	t.Run("unknown extensions are decoded with FFmpeg", func(t *testing.T) {
+		stubFFmpeg(t, encodeJpeg(t, img))
+
+		_, err := LoadMediaFromFileWithOptions(jxlPath, LoadOptions{})
+		assert.Error(t, err)
+
+		media, err := LoadMediaFromFileWithOptions(jxlPath, LoadOptions{FFmpegFallback: []string{".JXL"}})
+		require.NoError(t, err)
+		assert.Equal(t, "image", media.Type)
+		assert.Equal(t, 64, media.Width)
+	})
+
+	t.Run("undecodable images are decoded with FFmpeg", func(t *testing.T) {
+		stubFFmpeg(t, encodeJpeg(t, img))
+
+		_, err := LoadMediaFromFileWithOptions(brokenPath, LoadOptions{})
+		assert.Error(t, err)
+
+		_, err = LoadMediaFromFileWithOptions(brokenPath, LoadOptions{FFmpegFallback: []string{".jpg"}})
+		assert.Error(t, err)
+
+		media, err := LoadMediaFromFileWithOptions(brokenPath, LoadOptions{FFmpegFallback: []string{FallbackAll}})
+		require.NoError(t, err)
+		assert.Equal(t, 48, media.Height)
+	})
+
+	t.Run("reports both errors when neither decoder works", func(t *testing.T) {
+		failingFFmpeg(t)
+
+		_, err := LoadMediaFromFileWithOptions(brokenPath, LoadOptions{FFmpegFallback: []string{FallbackAll}})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "odd.tiff")
+		assert.Contains(t, err.Error(), "unexpected EOF")
+		assert.Contains(t, err.Error(), "FFmpeg fallback also failed")
+	})
+
+	t.Run("streams the whole content to FFmpeg", func(t *testing.T) {
+		stdinPath := stubFFmpeg(t, encodeJpeg(t, img))
+		content := bytes.Repeat([]byte("not a png "), 1000)
+
+		media, err := LoadMediaFromReaderWithOptions("upload.png", bytes.NewReader(content), LoadOptions{
+			FFmpegFallback: []string{".png"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(content)), media.Size)
+
+		received, err := os.ReadFile(stdinPath)
+		require.NoError(t, err)
+		assert.Equal(t, content, received)
+	})
+
+	t.Run("frames decoded by FFmpeg are checked against the pixel limit", func(t *testing.T) {
+		dir := t.TempDir()
+		framePath := filepath.Join(dir, "frame.jpg")
+		argsPath := filepath.Join(dir, "args.txt")
+		require.NoError(t, os.WriteFile(framePath, encodeJpeg(t, img), 0o644))
+		scriptFFmpeg(t, fmt.Sprintf("for last; do :; done\necho \"$@\" > '%s'\ncp '%s' \"$last\"", argsPath, framePath))
+
+		_, err := LoadMediaFromFileWithOptions(jxlPath, LoadOptions{FFmpegFallback: []string{".jxl"}, MaxPixels: 1000})
+		requireMediaError(t, err, ErrTooManyPixels, jxlPath)
+
+		args, err := os.ReadFile(argsPath)
+		require.NoError(t, err)
+		assert.Contains(t, string(args), "-max_pixels 1000")
+
+		_, err = LoadMediaFromReaderWithOptions("photo.jxl", bytes.NewReader([]byte("fake")), LoadOptions{
+			FFmpegFallback: []string{".jxl"},
+			MaxPixels:      1000,
+		})
+		requireMediaError(t, err, ErrTooManyPixels, "photo.jxl")
+
+		media, err := LoadMediaFromFileWithOptions(jxlPath, LoadOptions{FFmpegFallback: []string{".jxl"}, MaxPixels: -1})
+		require.NoError(t, err)
+		assert.Equal(t, 64, media.Width)
+
+		args, err = os.ReadFile(argsPath)
+		require.NoError(t, err)
+		assert.NotContains(t, string(args), "max_pixels")
+	})
+
+	t.Run("directories list the fallback extensions", func(t *testing.T) {
+		stubFFmpeg(t, encodeJpeg(t, img))
+
+		_, total := LoadMediaFromDirectory(dir, DirectoryOptions{IncludeImages: true})
+		assert.Equal(t, 1, total)
+
+		ch, total := LoadMediaFromDirectory(dir, DirectoryOptions{
+			IncludeImages: true,
+			LoadOptions:   LoadOptions{FFmpegFallback: []string{".jxl", FallbackAll}},
+		})
+		names, errs := collectNames(ch)
+		assert.Equal(t, 2, total)
+		assert.Equal(t, 0, errs)
+		assert.ElementsMatch(t, []string{jxlPath, brokenPath}, names)
+	})
+}
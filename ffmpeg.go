@@ -0,0 +1,133 @@
+package mediasim
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	iffmpeg "github.com/vegidio/mediasim/internal/ffmpeg"
+	"shared"
+)
+
+// FFmpegPathEnv is the environment variable with the path to the FFmpeg executable. It's used when no path was set
+// with SetFFmpegPath or in the options.
+const FFmpegPathEnv = "MEDIASIM_FFMPEG_PATH"
+
+// FFmpegRunner runs FFmpeg, which is used to extract the frames of videos and, with the fallback, to decode images.
+// Implementations must be safe for concurrent use; tests can use them to replace FFmpeg with a fake.
+type FFmpegRunner interface {
+	// Run runs FFmpeg with the arguments, which don't include the name of the executable. The input is read from stdin
+	// when it's not nil, and the diagnostics of FFmpeg must be written to stderr. The outputs are the files named in
+	// the arguments.
+	Run(ctx context.Context, args []string, stdin io.Reader, stderr io.Writer) error
+}
+
+// ExecFFmpeg is the FFmpegRunner that runs an FFmpeg executable.
+//
+// # Fields:
+//   - Path: The path to the executable. When empty, it's the path set with SetFFmpegPath, the path in the
+//     MEDIASIM_FFMPEG_PATH environment variable or, in the absence of both, it's resolved the first time FFmpeg is
+//     needed: the executable in the PATH or one installed by mediasim, which is downloaded if needed.
+type ExecFFmpeg struct {
+	Path string
+}
+
+func (e ExecFFmpeg) Run(ctx context.Context, args []string, stdin io.Reader, stderr io.Writer) error {
+	path := e.Path
+	if path == "" {
+		path = resolveFFmpegPath()
+	}
+
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Stderr = stderr
+	// Children of a killed process may keep its output open; this stops waiting for them
+	cmd.WaitDelay = ffmpegWaitDelay
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+
+	return cmd.Run()
+}
+
+// SetFFmpegPath sets the path to the FFmpeg executable used when the options don't have an FFmpeg runner or path. It
+// takes precedence over the MEDIASIM_FFMPEG_PATH environment variable.
+//
+// # Parameters:
+//   - path: The path to the executable; an empty string restores the default resolution.
+func SetFFmpegPath(path string) {
+	ffmpegMutex.Lock()
+	defer ffmpegMutex.Unlock()
+
+	ffmpegPath = path
+}
+
+// region - Private functions
+
+// ffmpegWaitDelay is how long to wait for the output of FFmpeg to be closed after the process is killed.
+const ffmpegWaitDelay = 2 * time.Second
+
+var (
+	ffmpegMutex sync.Mutex
+	// ffmpegPath is the path set with SetFFmpegPath.
+	ffmpegPath string
+	// resolvedPath is the path found the first time FFmpeg was needed, without a path set.
+	resolvedPath string
+)
+
+// resolveFFmpegPath returns the path to the FFmpeg executable. Looking for an installed FFmpeg, and downloading it,
+// happens only once, and only when no path was set.
+func resolveFFmpegPath() string {
+	ffmpegMutex.Lock()
+	defer ffmpegMutex.Unlock()
+
+	if ffmpegPath != "" {
+		return ffmpegPath
+	}
+
+	if env := os.Getenv(FFmpegPathEnv); env != "" {
+		return env
+	}
+
+	if resolvedPath == "" {
+		// An empty path means FFmpeg is installed in the system, or that it couldn't be downloaded
+		resolvedPath = shared.GetFFmpegPath("mediasim")
+		if resolvedPath == "" {
+			resolvedPath = "ffmpeg"
+		}
+	}
+
+	return resolvedPath
+}
+
+// ffmpeg returns the runner of FFmpeg used to load the media.
+func (o LoadOptions) ffmpeg() FFmpegRunner {
+	if o.FFmpeg != nil {
+		return o.FFmpeg
+	}
+
+	return ExecFFmpeg{Path: o.FFmpegPath}
+}
+
+// runFFmpeg runs the task that uses FFmpeg within the limits of the options: it waits for one of the FFmpeg slots, and
+// the context of the task is cancelled when the timeout expires. The time waiting for a slot doesn't count towards the
+// timeout.
+func runFFmpeg[T any](o LoadOptions, task func(ctx context.Context, config iffmpeg.Config) (T, error)) (T, error) {
+	if o.ffmpegSlots != nil {
+		o.ffmpegSlots <- struct{}{}
+		defer func() { <-o.ffmpegSlots }()
+	}
+
+	ctx := context.Background()
+	if o.FFmpegTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.FFmpegTimeout)
+		defer cancel()
+	}
+
+	return task(ctx, iffmpeg.Config{Run: o.ffmpeg().Run, Threads: o.FFmpegThreads})
+}
+
+// endregion
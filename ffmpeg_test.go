@@ -0,0 +1,174 @@
+package mediasim
+
+import (
+	"context"
+	"fmt"
+	"image/color"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeFFmpeg is an FFmpegRunner that writes the same frame to the output of every command, without running FFmpeg.
+// When the output is a pattern, like frame_%04d.jpg, two frames are written.
+type fakeFFmpeg struct {
+	frame []byte
+	mu    sync.Mutex
+	calls [][]string
+	input []byte
+}
+
+func (f *fakeFFmpeg) Run(_ context.Context, args []string, stdin io.Reader, _ io.Writer) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = append(f.calls, args)
+	if stdin != nil {
+		f.input, _ = io.ReadAll(stdin)
+	}
+
+	// The output path is the last argument
+	output := args[len(args)-1]
+	if !strings.Contains(output, "%") {
+		return os.WriteFile(output, f.frame, 0o644)
+	}
+
+	for i := 1; i <= 2; i++ {
+		if err := os.WriteFile(fmt.Sprintf(output, i), f.frame, 0o644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func TestLoadOptions_FFmpeg(t *testing.T) {
+	frame := encodeJpeg(t, createSolidImage(color.White, 32, 24))
+
+	t.Run("videos are loaded with the runner in the options", func(t *testing.T) {
+		fake := &fakeFFmpeg{frame: frame}
+		filePath := filepath.Join(t.TempDir(), "clip.mp4")
+		require.NoError(t, os.WriteFile(filePath, []byte("video"), 0o644))
+
+		media, err := LoadMediaFromFileWithOptions(filePath, LoadOptions{FFmpeg: fake})
+		require.NoError(t, err)
+		assert.Equal(t, "video", media.Type)
+		assert.Equal(t, 32, media.Width)
+
+		require.Len(t, fake.calls, 1)
+		assert.Contains(t, fake.calls[0], filePath)
+	})
+
+	t.Run("streamed videos are sent to the runner's stdin", func(t *testing.T) {
+		fake := &fakeFFmpeg{frame: frame}
+
+		_, err := LoadMediaFromReaderWithOptions("clip.mkv", strings.NewReader("streamed video"), LoadOptions{FFmpeg: fake})
+		require.NoError(t, err)
+		assert.Equal(t, "streamed video", string(fake.input))
+	})
+
+	t.Run("the runner in the options wins over the path", func(t *testing.T) {
+		options := LoadOptions{FFmpeg: &fakeFFmpeg{}, FFmpegPath: "/usr/bin/ffmpeg"}
+		assert.Equal(t, options.FFmpeg, options.ffmpeg())
+
+		options = LoadOptions{FFmpegPath: "/usr/bin/ffmpeg"}
+		assert.Equal(t, ExecFFmpeg{Path: "/usr/bin/ffmpeg"}, options.ffmpeg())
+	})
+}
+
+func TestResolveFFmpegPath(t *testing.T) {
+	t.Setenv(FFmpegPathEnv, "/env/ffmpeg")
+	assert.Equal(t, "/env/ffmpeg", resolveFFmpegPath())
+
+	SetFFmpegPath("/custom/ffmpeg")
+	t.Cleanup(func() { SetFFmpegPath("") })
+	assert.Equal(t, "/custom/ffmpeg", resolveFFmpegPath(), "the path set explicitly wins over the environment")
+
+	SetFFmpegPath("")
+	assert.Equal(t, "/env/ffmpeg", resolveFFmpegPath())
+}
+
+// slowFFmpeg is an FFmpegRunner that takes a while to write each frame, and records the maximum number of concurrent
+// runs.
+type slowFFmpeg struct {
+	fakeFFmpeg
+	delay   time.Duration
+	running atomic.Int32
+	peak    atomic.Int32
+}
+
+func (s *slowFFmpeg) Run(ctx context.Context, args []string, stdin io.Reader, stderr io.Writer) error {
+	current := s.running.Add(1)
+	defer s.running.Add(-1)
+
+	for {
+		peak := s.peak.Load()
+		if current <= peak || s.peak.CompareAndSwap(peak, current) {
+			break
+		}
+	}
+
+	time.Sleep(s.delay)
+	return s.fakeFFmpeg.Run(ctx, args, stdin, stderr)
+}
+
+func TestLoadOptions_FFmpegLimits(t *testing.T) {
+	dir := t.TempDir()
+	videos := make([]string, 6)
+	for i := range videos {
+		videos[i] = filepath.Join(dir, fmt.Sprintf("clip%d.mp4", i))
+		require.NoError(t, os.WriteFile(videos[i], []byte("video"), 0o644))
+	}
+
+	t.Run("slow FFmpeg processes are killed after the timeout", func(t *testing.T) {
+		scriptFFmpeg(t, "exec sleep 10")
+
+		start := time.Now()
+		_, err := LoadMediaFromFileWithOptions(videos[0], LoadOptions{FFmpegTimeout: 100 * time.Millisecond})
+
+		requireMediaError(t, err, ErrFFmpegTimeout, videos[0])
+		assert.Equal(t, "ffmpeg_timeout", ErrorCategory(err))
+		assert.Less(t, time.Since(start), 5*time.Second)
+	})
+
+	t.Run("fast FFmpeg processes finish within the timeout", func(t *testing.T) {
+		stubFFmpeg(t, encodeJpeg(t, createSolidImage(color.White, 16, 16)))
+
+		_, err := LoadMediaFromFileWithOptions(videos[0], LoadOptions{FFmpegTimeout: 10 * time.Second})
+		assert.NoError(t, err)
+	})
+
+	t.Run("the number of FFmpeg processes is limited", func(t *testing.T) {
+		slow := &slowFFmpeg{
+			fakeFFmpeg: fakeFFmpeg{frame: encodeJpeg(t, createSolidImage(color.White, 16, 16))},
+			delay:      50 * time.Millisecond,
+		}
+
+		channel := LoadMediaFromFiles(videos, FilesOptions{
+			Parallel:    len(videos),
+			LoadOptions: LoadOptions{FFmpeg: slow, FFmpegConcurrency: 2},
+		})
+
+		for result := range channel {
+			assert.NoError(t, result.Err)
+		}
+
+		assert.Equal(t, int32(2), slow.peak.Load())
+	})
+
+	t.Run("the thread cap is passed to FFmpeg", func(t *testing.T) {
+		fake := &fakeFFmpeg{frame: encodeJpeg(t, createSolidImage(color.White, 16, 16))}
+
+		_, err := LoadMediaFromFileWithOptions(videos[0], LoadOptions{FFmpeg: fake, FFmpegThreads: 1})
+		require.NoError(t, err)
+		assert.Contains(t, strings.Join(fake.calls[0], " "), "-threads 1")
+	})
+}
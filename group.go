@@ -0,0 +1,138 @@
+package mediasim
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"slices"
+	"strings"
+)
+
+// GroupOrder is the order of the groups returned by the grouping functions.
+type GroupOrder string
+
+const (
+	// GroupOrderPath sorts the groups by the name of their best media; it's the default order.
+	GroupOrderPath GroupOrder = "path"
+	// GroupOrderSize sorts the groups by their number of media, largest first.
+	GroupOrderSize GroupOrder = "size"
+	// GroupOrderReclaimable sorts the groups by the bytes freed by keeping only their best media, largest first.
+	GroupOrderReclaimable GroupOrder = "reclaimable"
+)
+
+// GroupOrders are the orders accepted in GroupOptions.Order.
+var GroupOrders = []GroupOrder{GroupOrderPath, GroupOrderSize, GroupOrderReclaimable}
+
+// SortGroups sorts the groups in the given order. Groups that are equal in that order are sorted by the name of their
+// best media, so the order is the same in every run with the same input.
+//
+// # Parameters:
+//   - groups: The groups to sort, in place; the media of each group must be sorted by quality, best first.
+//   - order: The order of the groups; an empty order means GroupOrderPath.
+func SortGroups(groups [][]Media, order GroupOrder) {
+	slices.SortStableFunc(groups, func(a, b []Media) int {
+		var result int
+
+		switch order {
+		case GroupOrderSize:
+			result = cmp.Compare(len(b), len(a))
+		case GroupOrderReclaimable:
+			result = cmp.Compare(ReclaimableBytes(b), ReclaimableBytes(a))
+		}
+
+		if result != 0 {
+			return result
+		}
+
+		return strings.Compare(a[0].Name, b[0].Name)
+	})
+}
+
+// GroupID returns a stable identifier of the group, derived from the fingerprints of its media. It doesn't depend on the
+// names of the media or on the order of the groups, so the same group has the same ID in different runs, even after its
+// files are renamed; it changes when media are added to or removed from the group. The fingerprints depend on how the
+// media were loaded, so the IDs are only comparable between runs with the same load options.
+//
+// # Parameters:
+//   - group: The media of the group.
+//
+// # Returns:
+//   - The ID of the group, with 16 hexadecimal digits.
+func GroupID(group []Media) string {
+	fingerprints := make([][sha256.Size]byte, len(group))
+	for i, m := range group {
+		fingerprints[i] = m.fingerprint()
+	}
+
+	slices.SortFunc(fingerprints, func(a, b [sha256.Size]byte) int {
+		return slices.Compare(a[:], b[:])
+	})
+
+	hash := sha256.New()
+	for _, fingerprint := range fingerprints {
+		hash.Write(fingerprint[:])
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)[:8])
+}
+
+// ReclaimableBytes returns the number of bytes freed by keeping only the best media of the group.
+//
+// # Parameters:
+//   - group: The media of the group, sorted by quality, best first.
+//
+// # Returns:
+//   - The sum of the sizes of every media but the first; 0 when the group has less than two media.
+func ReclaimableBytes(group []Media) int64 {
+	if len(group) < 2 {
+		return 0
+	}
+
+	var total int64
+	for _, m := range group[1:] {
+		total += m.Size
+	}
+
+	return total
+}
+
+// region - Private functions
+
+// fingerprint returns a hash of the type and of the icons of the media frames.
+func (m Media) fingerprint() [sha256.Size]byte {
+	hash := sha256.New()
+	hash.Write([]byte(m.Type))
+
+	for _, i := range m.icons {
+		_ = binary.Write(hash, binary.LittleEndian, i[:])
+	}
+
+	var result [sha256.Size]byte
+	hash.Sum(result[:0])
+	return result
+}
+
+// sortByQuality sorts the media of a group by quality, best first: longer, then with more pixels, then larger files.
+// Media of the same quality are sorted by name.
+func sortByQuality(group []Media) {
+	slices.SortFunc(group, func(a, b Media) int {
+		if a.Length != b.Length {
+			return b.Length - a.Length
+		}
+
+		mp1 := a.Width * a.Height
+		mp2 := b.Width * b.Height
+		if mp1 != mp2 {
+			return mp2 - mp1
+		}
+
+		if a.Size != b.Size {
+			return cmp.Compare(b.Size, a.Size)
+		}
+
+		return strings.Compare(a.Name, b.Name)
+	})
+}
+
+// endregion
@@ -0,0 +1,124 @@
+package mediasim
+
+import (
+	"image/color"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// groupNames returns the names of the media of each group.
+func groupNames(groups [][]Media) [][]string {
+	return lo.Map(groups, func(group []Media, _ int) []string {
+		return lo.Map(group, func(m Media, _ int) string { return m.Name })
+	})
+}
+
+func TestGroupMedia_Order(t *testing.T) {
+	white := iconFromImage(createSolidImage(color.White, 100, 100))
+	black := iconFromImage(createSolidImage(color.Black, 100, 100))
+	half := iconFromImage(createHalfImage(100, 100))
+	image := func(name string, i icon, size int64) Media {
+		return Media{Name: name, Type: "image", Width: 100, Height: 100, Size: size, frames: frames{icons: []icon{i}}}
+	}
+
+	media := []Media{
+		image("b/white1.jpg", white, 100),
+		image("b/white2.jpg", white, 100),
+		image("c/black1.jpg", black, 900),
+		image("c/black2.jpg", black, 800),
+		image("a/half1.jpg", half, 300),
+		image("a/half2.jpg", half, 200),
+		image("a/half3.jpg", half, 100),
+	}
+
+	t.Run("groups are in the same order whatever the order of the media", func(t *testing.T) {
+		expected := [][]string{
+			{"a/half1.jpg", "a/half2.jpg", "a/half3.jpg"},
+			{"b/white1.jpg", "b/white2.jpg"},
+			{"c/black1.jpg", "c/black2.jpg"},
+		}
+
+		random := rand.New(rand.NewPCG(1, 2))
+		for range 20 {
+			shuffled := slices.Clone(media)
+			random.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+
+			groups, _ := GroupMediaWithOptions(shuffled, GroupOptions{Threshold: 0.9})
+			require.Equal(t, expected, groupNames(groups))
+		}
+	})
+
+	t.Run("groups can be sorted by size", func(t *testing.T) {
+		groups, _ := GroupMediaWithOptions(media, GroupOptions{Threshold: 0.9, Order: GroupOrderSize})
+
+		// The groups of the same size are sorted by the name of the best media
+		assert.Equal(t, []string{"a/half1.jpg", "b/white1.jpg", "c/black1.jpg"},
+			lo.Map(groups, func(group []Media, _ int) string { return group[0].Name }))
+	})
+
+	t.Run("groups can be sorted by reclaimable bytes", func(t *testing.T) {
+		groups, _ := GroupMediaWithOptions(media, GroupOptions{Threshold: 0.9, Order: GroupOrderReclaimable})
+
+		assert.Equal(t, []int64{800, 300, 100}, lo.Map(groups, func(group []Media, _ int) int64 {
+			return ReclaimableBytes(group)
+		}))
+	})
+}
+
+func TestReclaimableBytes(t *testing.T) {
+	assert.Equal(t, int64(0), ReclaimableBytes(nil))
+	assert.Equal(t, int64(0), ReclaimableBytes([]Media{{Size: 100}}))
+	assert.Equal(t, int64(50), ReclaimableBytes([]Media{{Size: 100}, {Size: 30}, {Size: 20}}))
+}
+
+func TestGroupID(t *testing.T) {
+	white := iconFromImage(createSolidImage(color.White, 100, 100))
+	black := iconFromImage(createSolidImage(color.Black, 100, 100))
+	group := []Media{
+		{Name: "a.jpg", Type: "image", frames: frames{icons: []icon{white}}},
+		{Name: "b.jpg", Type: "image", frames: frames{icons: []icon{black}}},
+		{Name: "c.mp4", Type: "video", frames: frames{icons: []icon{white, black}}},
+	}
+
+	id := GroupID(group)
+	assert.Len(t, id, 16)
+
+	t.Run("the ID doesn't depend on the order or the names of the media", func(t *testing.T) {
+		renamed := slices.Clone(group)
+		slices.Reverse(renamed)
+		renamed[0].Name = "renamed.mp4"
+
+		assert.Equal(t, id, GroupID(renamed))
+	})
+
+	t.Run("the ID changes when the media of the group change", func(t *testing.T) {
+		assert.NotEqual(t, id, GroupID(group[:2]))
+		assert.NotEqual(t, GroupID(group[:2]), GroupID(group[1:]))
+	})
+
+	t.Run("the type is part of the fingerprint", func(t *testing.T) {
+		video := slices.Clone(group[:1])
+		video[0].Type = "video"
+
+		assert.NotEqual(t, GroupID(group[:1]), GroupID(video))
+	})
+}
+
+func TestSortByQuality(t *testing.T) {
+	group := []Media{
+		{Name: "c.jpg", Width: 10, Height: 10, Size: 100},
+		{Name: "a.jpg", Width: 10, Height: 10, Size: 100},
+		{Name: "b.jpg", Width: 10, Height: 10, Size: 200},
+		{Name: "d.mp4", Width: 10, Height: 10, Length: 5},
+	}
+
+	sortByQuality(group)
+	assert.Equal(t, []string{"d.mp4", "b.jpg", "a.jpg", "c.jpg"}, lo.Map(group, func(m Media, _ int) string {
+		return m.Name
+	}))
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package mediasim
+
+import "strings"
+
+// isHidden checks if the file is hidden; on Unix-like systems, these are the files whose names start with a dot.
+func isHidden(_ string, name string) bool {
+	return strings.HasPrefix(name, ".")
+}
@@ -0,0 +1,28 @@
+//go:build windows
+
+package mediasim
+
+import (
+	"strings"
+	"syscall"
+)
+
+// isHidden checks if the file is hidden; on Windows, these are the files with the hidden attribute, but names that
+// start with a dot are also considered hidden, for consistency with the other systems.
+func isHidden(path string, name string) bool {
+	if strings.HasPrefix(name, ".") {
+		return true
+	}
+
+	pointer, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return false
+	}
+
+	attributes, err := syscall.GetFileAttributes(pointer)
+	if err != nil {
+		return false
+	}
+
+	return attributes&syscall.FILE_ATTRIBUTE_HIDDEN != 0
+}
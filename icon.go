@@ -0,0 +1,104 @@
+package mediasim
+
+import (
+	"github.com/vitali-fedulov/images4"
+)
+
+// iconSize is the width and height of an icon, in pixels.
+const iconSize = images4.IconSize
+
+// iconLen is the number of values in an icon: 3 color channels for each pixel.
+const iconLen = iconSize * iconSize * 3
+
+// icon is a compact representation of images4.IconT.
+//
+// The pixels are stored in a fixed-size array, using the same layout and precision as images4, so icons can be packed
+// contiguously in a slice without an extra allocation (and pointer) per frame, and similarity scores are not affected.
+type icon [iconLen]uint16
+
+// newIcon creates an icon from the given images4.IconT.
+func newIcon(iconT images4.IconT) icon {
+	var i icon
+	copy(i[:], iconT.Pixels)
+	return i
+}
+
+// iconT returns the images4.IconT view of the icon, sharing the same pixels.
+func (i *icon) iconT() images4.IconT {
+	return images4.IconT{Pixels: i[:]}
+}
+
+// iconPixels is the number of pixels in an icon, in each color channel.
+const iconPixels = iconSize * iconSize
+
+// The same constants used by images4.EucMetric, so the distances of transformed icons are identical.
+const (
+	one255th  = 1 / float64(255)
+	one255th2 = one255th * one255th
+)
+
+// permutation maps each pixel of a transformed icon to the pixel of the original icon it's read from, so an icon can be
+// compared as if it were flipped or rotated, without copying it.
+type permutation [iconPixels]int
+
+// newPermutation creates the permutation where each pixel (x, y) is read from the position returned by fn.
+func newPermutation(fn func(x, y int) (int, int)) *permutation {
+	var p permutation
+
+	for y := 0; y < iconSize; y++ {
+		for x := 0; x < iconSize; x++ {
+			sx, sy := fn(x, y)
+			p[y*iconSize+x] = sy*iconSize + sx
+		}
+	}
+
+	return &p
+}
+
+// permutations are the pixel mappings of the transformations; TransformNone has none.
+var permutations = map[Transform]*permutation{
+	TransformFlipVertical:   newPermutation(func(x, y int) (int, int) { return x, iconSize - 1 - y }),
+	TransformFlipHorizontal: newPermutation(func(x, y int) (int, int) { return iconSize - 1 - x, y }),
+	TransformRotate90:       newPermutation(func(x, y int) (int, int) { return y, iconSize - 1 - x }),
+	TransformRotate180:      newPermutation(func(x, y int) (int, int) { return iconSize - 1 - x, iconSize - 1 - y }),
+	TransformRotate270:      newPermutation(func(x, y int) (int, int) { return iconSize - 1 - y, x }),
+}
+
+// transform returns a new icon with the transformation applied.
+func (i *icon) transform(t Transform) icon {
+	p := permutations[t]
+	if p == nil {
+		return *i
+	}
+
+	var out icon
+	for ch := 0; ch < 3; ch++ {
+		offset := ch * iconPixels
+
+		for idx, src := range p {
+			out[offset+idx] = i[offset+src]
+		}
+	}
+
+	return out
+}
+
+// eucMetric works like images4.EucMetric, but reads the second icon as if the transformation was applied to it.
+func eucMetric(iconA, iconB *icon, t Transform) (m1, m2, m3 float64) {
+	p := permutations[t]
+	if p == nil {
+		return images4.EucMetric(iconA.iconT(), iconB.iconT())
+	}
+
+	var cA, cB uint16
+	for i, j := range p {
+		cA, cB = iconA[i], iconB[j]
+		m1 += (float64(cA) - float64(cB)) * one255th2 * (float64(cA) - float64(cB))
+		cA, cB = iconA[i+iconPixels], iconB[j+iconPixels]
+		m2 += (float64(cA) - float64(cB)) * one255th2 * (float64(cA) - float64(cB))
+		cA, cB = iconA[i+2*iconPixels], iconB[j+2*iconPixels]
+		m3 += (float64(cA) - float64(cB)) * one255th2 * (float64(cA) - float64(cB))
+	}
+
+	return m1, m2, m3
+}
@@ -0,0 +1,120 @@
+package mediasim
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/disintegration/imaging"
+	"github.com/stretchr/testify/assert"
+	"github.com/vitali-fedulov/images4"
+)
+
+// createGradientImage creates an asymmetric image, so every flip and rotation produces a different icon.
+func createGradientImage(w, h int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 255 / w), G: uint8(y * 255 / h), B: uint8((x * y) % 256), A: 255})
+		}
+	}
+	return img
+}
+
+func TestIcon_Transforms(t *testing.T) {
+	img := createGradientImage(120, 80)
+	original := iconFromImage(img)
+
+	t.Run("flipping twice returns the original icon", func(t *testing.T) {
+		flipped := original.transform(TransformFlipHorizontal)
+		assert.NotEqual(t, original, flipped)
+		assert.Equal(t, original, flipped.transform(TransformFlipHorizontal))
+
+		flipped = original.transform(TransformFlipVertical)
+		assert.NotEqual(t, original, flipped)
+		assert.Equal(t, original, flipped.transform(TransformFlipVertical))
+	})
+
+	t.Run("rotations add up to a full turn", func(t *testing.T) {
+		r90 := original.transform(TransformRotate90)
+		r180 := original.transform(TransformRotate180)
+		r270 := original.transform(TransformRotate270)
+
+		assert.Equal(t, r180, r90.transform(TransformRotate90))
+		assert.Equal(t, r270, r180.transform(TransformRotate90))
+		assert.Equal(t, original, r270.transform(TransformRotate90))
+	})
+
+	t.Run("rotate90 matches images4", func(t *testing.T) {
+		// images4 rotates through float64 values, so there may be rounding differences of 1 in some pixels.
+		expected := newIcon(images4.Rotate90(original.iconT()))
+		rotated := original.transform(TransformRotate90)
+
+		for i := range expected {
+			assert.InDelta(t, expected[i], rotated[i], 1)
+		}
+	})
+
+	t.Run("transformed icons match the icons of transformed images", func(t *testing.T) {
+		cases := []struct {
+			name  string
+			icon  icon
+			image image.Image
+		}{
+			{"flipH", original.transform(TransformFlipHorizontal), imaging.FlipH(img)},
+			{"flipV", original.transform(TransformFlipVertical), imaging.FlipV(img)},
+			{"rotate180", original.transform(TransformRotate180), imaging.Rotate180(img)},
+		}
+
+		for _, c := range cases {
+			expected := iconFromImage(c.image)
+			assert.InDelta(t, 1.0, calculateImageSimilarity(&expected, &c.icon, TransformNone), 0.01, c.name)
+		}
+
+		// imaging rotates counter-clockwise, so its 90º rotation is one of our 90º or 270º rotations.
+		expected := iconFromImage(imaging.Rotate90(img))
+		r90, r270 := original.transform(TransformRotate90), original.transform(TransformRotate270)
+		score := max(calculateImageSimilarity(&expected, &r90, TransformNone), calculateImageSimilarity(&expected, &r270, TransformNone))
+		assert.InDelta(t, 1.0, score, 0.01)
+	})
+}
+
+func TestFrames_Transforms(t *testing.T) {
+	icons := []icon{iconFromImage(createGradientImage(50, 50))}
+
+	t.Run("only the original icons without frame options", func(t *testing.T) {
+		f := frames{icons: icons}
+		assert.Equal(t, []Transform{TransformNone}, f.transforms())
+	})
+
+	t.Run("flip and rotate add five variants", func(t *testing.T) {
+		f := frames{icons: icons, options: FrameOptions{FrameFlip: true, FrameRotate: true}}
+		assert.Len(t, f.transforms(), 6)
+	})
+}
+
+func TestEucMetric(t *testing.T) {
+	icon1 := iconFromImage(createGradientImage(120, 80))
+	icon2 := iconFromImage(imaging.FlipH(createGradientImage(100, 90)))
+
+	for _, transform := range []Transform{
+		TransformNone, TransformFlipVertical, TransformFlipHorizontal,
+		TransformRotate90, TransformRotate180, TransformRotate270,
+	} {
+		t.Run(string(transform)+" is the same as comparing the transformed icon", func(t *testing.T) {
+			transformed := icon2.transform(transform)
+			m1, m2, m3 := images4.EucMetric(icon1.iconT(), transformed.iconT())
+			p1, p2, p3 := eucMetric(&icon1, &icon2, transform)
+
+			assert.Equal(t, []float64{m1, m2, m3}, []float64{p1, p2, p3})
+		})
+	}
+
+	t.Run("comparing doesn't allocate", func(t *testing.T) {
+		allocs := testing.AllocsPerRun(10, func() {
+			calculateImageSimilarity(&icon1, &icon2, TransformRotate90)
+		})
+
+		assert.Zero(t, allocs)
+	})
+}
@@ -24,13 +24,13 @@ func (d *DSU) Find(x int) int {
 	return d.parent[x]
 }
 
-// Union by rank.
-func (d *DSU) Union(x, y int) {
+// Union by rank. Returns false if x and y were already in the same set.
+func (d *DSU) Union(x, y int) bool {
 	xRoot := d.Find(x)
 	yRoot := d.Find(y)
 
 	if xRoot == yRoot {
-		return
+		return false
 	}
 
 	if d.rank[xRoot] < d.rank[yRoot] {
@@ -41,4 +41,6 @@ func (d *DSU) Union(x, y int) {
 		d.parent[yRoot] = xRoot
 		d.rank[xRoot]++
 	}
+
+	return true
 }
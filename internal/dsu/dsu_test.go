@@ -50,6 +50,13 @@ func TestDSU_Union(t *testing.T) {
 		assert.Equal(t, root, dsu.Find(0))
 	})
 
+	t.Run("reports whether the sets were merged", func(t *testing.T) {
+		dsu := NewDSU(3)
+		assert.True(t, dsu.Union(0, 1))
+		assert.False(t, dsu.Union(1, 0))
+		assert.True(t, dsu.Union(2, 0))
+	})
+
 	t.Run("union by rank merges smaller into larger", func(t *testing.T) {
 		dsu := NewDSU(6)
 		// Build a larger tree on 0
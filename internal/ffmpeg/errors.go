@@ -0,0 +1,37 @@
+package ffmpeg
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrNotFound is returned when the FFmpeg executable can't be found or started.
+	ErrNotFound = errors.New("FFmpeg not found")
+	// ErrNoVideoStream is returned when FFmpeg didn't find any video stream to extract frames from.
+	ErrNoVideoStream = errors.New("no video stream")
+	// ErrTimeout is returned when FFmpeg was killed because it didn't finish in time.
+	ErrTimeout = errors.New("FFmpeg timed out")
+)
+
+// ExecError is returned when FFmpeg runs but fails.
+type ExecError struct {
+	// Stderr is the end of what FFmpeg wrote to stderr, where it explains what went wrong.
+	Stderr string
+	// Err is the error returned when running the command; usually an *exec.ExitError.
+	Err error
+}
+
+func (e *ExecError) Error() string {
+	lines := strings.Split(strings.TrimSpace(e.Stderr), "\n")
+	if last := strings.TrimSpace(lines[len(lines)-1]); last != "" {
+		return fmt.Sprintf("FFmpeg failed: %v: %s", e.Err, last)
+	}
+
+	return fmt.Sprintf("FFmpeg failed: %v", e.Err)
+}
+
+func (e *ExecError) Unwrap() error {
+	return e.Err
+}
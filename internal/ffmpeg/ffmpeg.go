@@ -1,11 +1,14 @@
 package ffmpeg
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"image"
 	_ "image/gif"
 	_ "image/jpeg"
 	_ "image/png"
+	"io"
 	"os"
 	"path/filepath"
 
@@ -48,7 +51,10 @@ func LoadFrames(directory string) ([]image.Image, error) {
 }
 
 // ExtractFrames extracts frames from a video file using FFmpeg and returns them as a slice of image.Image.
-func ExtractFrames(filePath string, ffmpegPath string) ([]image.Image, error) {
+//
+// The errors wrap ErrNotFound when FFmpeg can't be started, ErrNoVideoStream when the file has no video, or an
+// *ExecError with the output of FFmpeg when it fails for other reasons.
+func ExtractFrames(ctx context.Context, filePath string, config Config) ([]image.Image, error) {
 	images := make([]image.Image, 0)
 
 	tempDir, err := os.MkdirTemp("", "mediasim-*")
@@ -60,15 +66,10 @@ func ExtractFrames(filePath string, ffmpegPath string) ([]image.Image, error) {
 
 	// Export 1 frame per second
 	path := filepath.Join(tempDir, "frame_%04d.jpg")
-	command := ffmpeg.Input(filePath).
-		Filter("fps", ffmpeg.Args{"1"}).
-		Output(path).
-		Silent(true)
-
-	if ffmpegPath == "" {
-		_ = command.Run()
-	} else {
-		_ = command.SetFfmpegPath(ffmpegPath).Run()
+	command := config.output(config.input(filePath).Filter("fps", ffmpeg.Args{"1"}), path, nil)
+	err = config.run(ctx, command, nil)
+	if errors.Is(err, ErrNotFound) || ctx.Err() != nil {
+		return images, err
 	}
 
 	images, _ = LoadFrames(tempDir)
@@ -78,24 +79,98 @@ func ExtractFrames(filePath string, ffmpegPath string) ([]image.Image, error) {
 
 	// Failed to export multiple frames, so let's try to export a single frame
 	path = filepath.Join(tempDir, "frame.jpg")
-	command = ffmpeg.Input(filePath).
-		Output(path, ffmpeg.KwArgs{"vframes": 1}).
-		Silent(true)
-
-	if ffmpegPath == "" {
-		err = command.Run()
-	} else {
-		err = command.SetFfmpegPath(ffmpegPath).Run()
+	command = config.output(config.input(filePath), path, ffmpeg.KwArgs{"vframes": 1})
+	if err = config.run(ctx, command, nil); err != nil {
+		return images, err
 	}
 
+	return loadExtractedFrames(tempDir)
+}
+
+// ExtractFramesFromReader extracts frames from a video streamed through the given reader, which is sent to FFmpeg via
+// stdin, and returns them as a slice of image.Image. The errors are the same as the ones of ExtractFrames.
+//
+// Unlike ExtractFrames, there's no second attempt to export a single frame, since the reader can only be consumed once.
+func ExtractFramesFromReader(ctx context.Context, r io.Reader, config Config) ([]image.Image, error) {
+	images := make([]image.Image, 0)
+
+	tempDir, err := os.MkdirTemp("", "mediasim-*")
 	if err != nil {
-		return images, fmt.Errorf("error exporting video frames from '%s': %w", filePath, err)
+		return images, fmt.Errorf("error creating temp directory: %w", err)
 	}
 
-	images, err = LoadFrames(tempDir)
+	defer os.RemoveAll(tempDir)
+
+	// Export 1 frame per second
+	path := filepath.Join(tempDir, "frame_%04d.jpg")
+	command := config.output(config.input("pipe:0").Filter("fps", ffmpeg.Args{"1"}), path, nil)
+	if err = config.run(ctx, command, r); err != nil {
+		return images, err
+	}
+
+	return loadExtractedFrames(tempDir)
+}
+
+// ExtractFrame decodes a single frame of a media file using FFmpeg. It's used to decode images in formats that aren't
+// supported by the Go decoders.
+func ExtractFrame(ctx context.Context, filePath string, config Config) (image.Image, error) {
+	return extractFrame(ctx, filePath, nil, config)
+}
+
+// ExtractFrameFromReader decodes a single frame of a media streamed through the given reader, which is sent to FFmpeg
+// via stdin.
+func ExtractFrameFromReader(ctx context.Context, r io.Reader, config Config) (image.Image, error) {
+	return extractFrame(ctx, "pipe:0", r, config)
+}
+
+// region - Private functions
+
+func extractFrame(ctx context.Context, input string, stdin io.Reader, config Config) (image.Image, error) {
+	tempDir, err := os.MkdirTemp("", "mediasim-*")
+	if err != nil {
+		return nil, fmt.Errorf("error creating temp directory: %w", err)
+	}
+
+	defer os.RemoveAll(tempDir)
+
+	// PNG is lossless, so the frame is not degraded before the comparison
+	path := filepath.Join(tempDir, "frame.png")
+	command := config.output(config.imageInput(input), path, ffmpeg.KwArgs{"vframes": 1})
+	if err = config.run(ctx, command, stdin); err != nil {
+		return nil, err
+	}
+
+	// FFmpeg may succeed without exporting the frame, when the input has no video stream
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoVideoStream
+	} else if err != nil {
+		return nil, fmt.Errorf("error loading the frame extracted by FFmpeg: %w", err)
+	}
+
+	defer f.Close()
+
+	img, err := config.decodeFrame(f)
+	if err != nil {
+		return nil, fmt.Errorf("error loading the frame extracted by FFmpeg: %w", err)
+	}
+
+	return img, nil
+}
+
+// loadExtractedFrames loads the frames exported by FFmpeg to the directory. FFmpeg may succeed without exporting any
+// frame, when the input has no video stream.
+func loadExtractedFrames(directory string) ([]image.Image, error) {
+	images, err := LoadFrames(directory)
 	if err != nil {
-		return images, fmt.Errorf("error loading videos frames from '%s': %w", filePath, err)
+		return images, fmt.Errorf("error loading the frames extracted by FFmpeg: %w", err)
+	}
+
+	if len(images) == 0 {
+		return images, ErrNoVideoStream
 	}
 
 	return images, nil
 }
+
+// endregion
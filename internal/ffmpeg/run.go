@@ -0,0 +1,132 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	iofs "io/fs"
+	"math"
+	"os/exec"
+	"strings"
+
+	ffmpeg "github.com/u2takey/ffmpeg-go"
+)
+
+// stderrLimit is the maximum number of bytes of the FFmpeg output kept in an ExecError.
+const stderrLimit = 4096
+
+// RunFunc runs FFmpeg with the arguments, without the name of the executable. The input is read from stdin when it's
+// not nil, and the diagnostics of FFmpeg are written to stderr.
+type RunFunc func(ctx context.Context, args []string, stdin io.Reader, stderr io.Writer) error
+
+// Config configures how FFmpeg is run.
+type Config struct {
+	// Run runs the FFmpeg commands.
+	Run RunFunc
+	// Threads is the number of threads FFmpeg may use to decode and encode each file; 0 lets FFmpeg decide.
+	Threads int
+	// MaxPixels is the maximum number of pixels of the frame decoded by ExtractFrame; FFmpeg refuses to decode larger
+	// images. 0 means no limit.
+	MaxPixels int64
+	// DecodeFrame decodes the frame exported by ExtractFrame; nil means image.Decode.
+	DecodeFrame func(r io.Reader) (image.Image, error)
+}
+
+// region - Private functions
+
+// noStreamMessages are the messages FFmpeg writes when the input has no stream that can be turned into frames.
+var noStreamMessages = []string{
+	"does not contain any stream",
+	"matches no streams",
+	"Output file is empty, nothing was encoded",
+}
+
+// input creates the input of an FFmpeg command, with the thread cap of the config.
+func (c Config) input(filename string) *ffmpeg.Stream {
+	if c.Threads > 0 {
+		return ffmpeg.Input(filename, ffmpeg.KwArgs{"threads": c.Threads})
+	}
+
+	return ffmpeg.Input(filename)
+}
+
+// imageInput creates the input of an FFmpeg command that decodes an image, with the thread cap and the pixel limit of
+// the config.
+func (c Config) imageInput(filename string) *ffmpeg.Stream {
+	kwargs := ffmpeg.KwArgs{}
+	if c.Threads > 0 {
+		kwargs["threads"] = c.Threads
+	}
+
+	// The option of FFmpeg is a 32-bit integer
+	if c.MaxPixels > 0 {
+		kwargs["max_pixels"] = min(c.MaxPixels, math.MaxInt32)
+	}
+
+	return ffmpeg.Input(filename, kwargs)
+}
+
+// decodeFrame decodes a frame exported by FFmpeg with the decoder of the config.
+func (c Config) decodeFrame(r io.Reader) (image.Image, error) {
+	if c.DecodeFrame != nil {
+		return c.DecodeFrame(r)
+	}
+
+	img, _, err := image.Decode(r)
+	return img, err
+}
+
+// output adds the output of an FFmpeg command, with the thread cap of the config.
+func (c Config) output(stream *ffmpeg.Stream, path string, kwargs ffmpeg.KwArgs) *ffmpeg.Stream {
+	if kwargs == nil {
+		kwargs = ffmpeg.KwArgs{}
+	}
+
+	if c.Threads > 0 {
+		kwargs["threads"] = c.Threads
+	}
+
+	return stream.Output(path, kwargs)
+}
+
+// run runs the FFmpeg command, capturing its stderr to explain the failures. When the context is done before FFmpeg
+// finishes, the process is killed and the error wraps ErrTimeout or the error of the context.
+func (c Config) run(ctx context.Context, command *ffmpeg.Stream, stdin io.Reader) error {
+	var stderr bytes.Buffer
+
+	err := c.Run(ctx, command.GetArgs(), stdin, &stderr)
+	if err == nil {
+		return nil
+	}
+
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		if errors.Is(ctxErr, context.DeadlineExceeded) {
+			return fmt.Errorf("%w: %w", ErrTimeout, err)
+		}
+
+		return fmt.Errorf("%w: %w", ctxErr, err)
+	}
+
+	if errors.Is(err, exec.ErrNotFound) || errors.Is(err, iofs.ErrNotExist) || errors.Is(err, iofs.ErrPermission) {
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
+
+	output := stderr.String()
+	if len(output) > stderrLimit {
+		output = output[len(output)-stderrLimit:]
+	}
+
+	execErr := &ExecError{Stderr: output, Err: err}
+	for _, message := range noStreamMessages {
+		if strings.Contains(output, message) {
+			return fmt.Errorf("%w: %w", ErrNoVideoStream, execErr)
+		}
+	}
+
+	return execErr
+}
+
+// endregion
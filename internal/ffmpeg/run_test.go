@@ -0,0 +1,63 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Threads(t *testing.T) {
+	var args []string
+	config := Config{
+		Threads: 2,
+		Run: func(_ context.Context, a []string, _ io.Reader, _ io.Writer) error {
+			args = a
+			return errors.New("stop")
+		},
+	}
+
+	_, err := ExtractFrame(context.Background(), "photo.jxl", config)
+	require.Error(t, err)
+
+	// The cap is set before the input, for decoding, and before the output, for encoding
+	assert.Equal(t, []string{"-threads", "2", "-i", "photo.jxl"}, args[:4])
+	assert.Contains(t, strings.Join(args[4:], " "), "-threads 2")
+}
+
+func TestConfig_Run(t *testing.T) {
+	t.Run("deadlines are reported as timeouts, without retrying", func(t *testing.T) {
+		calls := 0
+		config := Config{Run: func(ctx context.Context, _ []string, _ io.Reader, _ io.Writer) error {
+			calls++
+			<-ctx.Done()
+			return errors.New("signal: killed")
+		}}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err := ExtractFrames(ctx, "clip.mp4", config)
+		assert.ErrorIs(t, err, ErrTimeout)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("FFmpeg output is kept when it fails", func(t *testing.T) {
+		config := Config{Run: func(_ context.Context, _ []string, _ io.Reader, stderr io.Writer) error {
+			_, _ = io.WriteString(stderr, "banner\nclip.mp4: Invalid data found when processing input\n")
+			return errors.New("exit status 1")
+		}}
+
+		_, err := ExtractFramesFromReader(context.Background(), strings.NewReader("video"), config)
+
+		var execErr *ExecError
+		require.ErrorAs(t, err, &execErr)
+		assert.Contains(t, execErr.Stderr, "banner")
+		assert.EqualError(t, err, "FFmpeg failed: exit status 1: clip.mp4: Invalid data found when processing input")
+	})
+}
@@ -0,0 +1,242 @@
+// Package variants creates labelled variants of images and videos, like the resized or recompressed copies found in
+// real collections, so the tests can measure how well each transform is matched.
+package variants
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math"
+
+	"github.com/disintegration/imaging"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// Transform is a change applied to an image to create a variant of it.
+//
+// # Fields:
+//   - Label: The name of the transform and its parameters, like "resize-50%", used as the label of the variant.
+//   - Apply: The function that creates the variant; it must not change the original image.
+type Transform struct {
+	Label string
+	Apply func(img image.Image) (image.Image, error)
+}
+
+// Variant is an image created by a transform, labelled with the transform.
+type Variant struct {
+	Label string
+	Image image.Image
+}
+
+// Generate creates a variant of the image for each transform.
+//
+// # Parameters:
+//   - img: The original image.
+//   - transforms: The transforms to apply; when empty, the transforms of Transforms are used.
+//
+// # Returns:
+//   - The variants, in the order of the transforms.
+//   - An error if any of the transforms failed.
+func Generate(img image.Image, transforms ...Transform) ([]Variant, error) {
+	if len(transforms) == 0 {
+		transforms = Transforms()
+	}
+
+	variants := make([]Variant, 0, len(transforms))
+	for _, transform := range transforms {
+		variant, err := transform.Apply(img)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the variant %s: %w", transform.Label, err)
+		}
+
+		variants = append(variants, Variant{Label: transform.Label, Image: variant})
+	}
+
+	return variants, nil
+}
+
+// Transforms returns the common transforms of near-duplicates: resized, recompressed at several JPEG qualities,
+// cropped, padded, flipped, rotated, colour-shifted, watermarked and converted to grayscale.
+func Transforms() []Transform {
+	return []Transform{
+		Resize(0.5),
+		Resize(2),
+		Recompress(90),
+		Recompress(60),
+		Recompress(30),
+		Recompress(10),
+		Crop(0.05),
+		Crop(0.15),
+		Pad(0.1, color.Black),
+		FlipH(),
+		FlipV(),
+		Rotate(90),
+		Rotate(180),
+		ColorShift(20),
+		Watermark("mediasim"),
+		Grayscale(),
+	}
+}
+
+// Resize scales the width and height of the image by the factor.
+func Resize(factor float64) Transform {
+	return Transform{
+		Label: fmt.Sprintf("resize-%g%%", factor*100),
+		Apply: func(img image.Image) (image.Image, error) {
+			bounds := img.Bounds()
+			width := max(1, int(math.Round(float64(bounds.Dx())*factor)))
+			height := max(1, int(math.Round(float64(bounds.Dy())*factor)))
+
+			return imaging.Resize(img, width, height, imaging.Lanczos), nil
+		},
+	}
+}
+
+// Recompress encodes the image as a JPEG with the quality, between 1 and 100, and decodes it back.
+func Recompress(quality int) Transform {
+	return Transform{
+		Label: fmt.Sprintf("jpeg-q%d", quality),
+		Apply: func(img image.Image) (image.Image, error) {
+			var buf bytes.Buffer
+			if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+				return nil, err
+			}
+
+			return jpeg.Decode(&buf)
+		},
+	}
+}
+
+// Crop removes the fraction of the width and height from each side of the image.
+func Crop(fraction float64) Transform {
+	return Transform{
+		Label: fmt.Sprintf("crop-%g%%", fraction*100),
+		Apply: func(img image.Image) (image.Image, error) {
+			bounds := img.Bounds()
+			dx := int(math.Round(float64(bounds.Dx()) * fraction))
+			dy := int(math.Round(float64(bounds.Dy()) * fraction))
+
+			return imaging.Crop(img, image.Rect(bounds.Min.X+dx, bounds.Min.Y+dy, bounds.Max.X-dx, bounds.Max.Y-dy)), nil
+		},
+	}
+}
+
+// Pad adds borders of the colour to each side of the image, with the fraction of its width and height, like the
+// letterboxing of a screenshot.
+func Pad(fraction float64, c color.Color) Transform {
+	return Transform{
+		Label: fmt.Sprintf("pad-%g%%", fraction*100),
+		Apply: func(img image.Image) (image.Image, error) {
+			bounds := img.Bounds()
+			dx := int(math.Round(float64(bounds.Dx()) * fraction))
+			dy := int(math.Round(float64(bounds.Dy()) * fraction))
+			background := imaging.New(bounds.Dx()+2*dx, bounds.Dy()+2*dy, c)
+
+			return imaging.Paste(background, img, image.Pt(dx, dy)), nil
+		},
+	}
+}
+
+// FlipH mirrors the image horizontally.
+func FlipH() Transform {
+	return Transform{
+		Label: "flip-h",
+		Apply: func(img image.Image) (image.Image, error) { return imaging.FlipH(img), nil },
+	}
+}
+
+// FlipV mirrors the image vertically.
+func FlipV() Transform {
+	return Transform{
+		Label: "flip-v",
+		Apply: func(img image.Image) (image.Image, error) { return imaging.FlipV(img), nil },
+	}
+}
+
+// Rotate rotates the image counter-clockwise by the angle, in degrees. Angles that aren't multiples of 90 leave black
+// corners around the image.
+func Rotate(degrees float64) Transform {
+	return Transform{
+		Label: fmt.Sprintf("rotate-%g", degrees),
+		Apply: func(img image.Image) (image.Image, error) {
+			switch math.Mod(math.Mod(degrees, 360)+360, 360) {
+			case 0:
+				return imaging.Clone(img), nil
+			case 90:
+				return imaging.Rotate90(img), nil
+			case 180:
+				return imaging.Rotate180(img), nil
+			case 270:
+				return imaging.Rotate270(img), nil
+			default:
+				return imaging.Rotate(img, degrees, color.Black), nil
+			}
+		},
+	}
+}
+
+// ColorShift makes the colours of the image warmer, adding the amount to the red channel and subtracting it from the
+// blue channel, like a different white balance. Negative amounts make the colours cooler.
+func ColorShift(amount int) Transform {
+	return Transform{
+		Label: fmt.Sprintf("color-shift-%d", amount),
+		Apply: func(img image.Image) (image.Image, error) {
+			return imaging.AdjustFunc(img, func(c color.NRGBA) color.NRGBA {
+				c.R = clamp(int(c.R) + amount)
+				c.B = clamp(int(c.B) - amount)
+				return c
+			}), nil
+		},
+	}
+}
+
+// Watermark writes the text, in white over a translucent box, in the bottom-right corner of the image. The text takes
+// about a third of the width of the image.
+func Watermark(text string) Transform {
+	return Transform{
+		Label: "watermark",
+		Apply: func(img image.Image) (image.Image, error) {
+			face := basicfont.Face7x13
+			width := font.MeasureString(face, text).Ceil() + 4
+			height := face.Metrics().Height.Ceil() + 4
+
+			mark := imaging.New(width, height, color.NRGBA{A: 160})
+			drawer := font.Drawer{
+				Dst:  mark,
+				Src:  image.White,
+				Face: face,
+				Dot:  fixed.P(2, 2+face.Metrics().Ascent.Ceil()),
+			}
+			drawer.DrawString(text)
+
+			bounds := img.Bounds()
+			scale := max(1, float64(bounds.Dx())/3/float64(width))
+			mark = imaging.Resize(mark, int(float64(width)*scale), int(float64(height)*scale), imaging.NearestNeighbor)
+			margin := bounds.Dx() / 40
+			position := image.Pt(bounds.Dx()-mark.Bounds().Dx()-margin, bounds.Dy()-mark.Bounds().Dy()-margin)
+
+			return imaging.Overlay(img, mark, position, 0.8), nil
+		},
+	}
+}
+
+// Grayscale removes the colours of the image.
+func Grayscale() Transform {
+	return Transform{
+		Label: "grayscale",
+		Apply: func(img image.Image) (image.Image, error) { return imaging.Grayscale(img), nil },
+	}
+}
+
+// region - Private functions
+
+// clamp limits the value of a colour channel to 0–255.
+func clamp(value int) uint8 {
+	return uint8(min(255, max(0, value)))
+}
+
+// endregion
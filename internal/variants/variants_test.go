@@ -0,0 +1,91 @@
+package variants
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/disintegration/imaging"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createTestImage creates an image with a red left half and a blue right half.
+func createTestImage(w, h int) *image.NRGBA {
+	img := imaging.New(w, h, color.NRGBA{R: 200, G: 30, B: 30, A: 255})
+	return imaging.Paste(img, imaging.New(w/2, h, color.NRGBA{R: 30, G: 30, B: 200, A: 255}), image.Pt(w/2, 0))
+}
+
+func TestGenerate(t *testing.T) {
+	img := createTestImage(200, 100)
+	original := imaging.Clone(img)
+
+	generated, err := Generate(img)
+	require.NoError(t, err)
+
+	labels := lo.Map(generated, func(v Variant, _ int) string { return v.Label })
+	assert.Equal(t, lo.Map(Transforms(), func(t Transform, _ int) string { return t.Label }), labels)
+	assert.Len(t, lo.Uniq(labels), len(labels), "the labels are unique")
+	assert.Equal(t, original, img, "the original isn't changed")
+
+	sizes := lo.SliceToMap(generated, func(v Variant) (string, image.Point) { return v.Label, v.Image.Bounds().Size() })
+	assert.Equal(t, image.Pt(100, 50), sizes["resize-50%"])
+	assert.Equal(t, image.Pt(400, 200), sizes["resize-200%"])
+	assert.Equal(t, image.Pt(200, 100), sizes["jpeg-q30"])
+	assert.Equal(t, image.Pt(180, 90), sizes["crop-5%"])
+	assert.Equal(t, image.Pt(240, 120), sizes["pad-10%"])
+	assert.Equal(t, image.Pt(100, 200), sizes["rotate-90"])
+	assert.Equal(t, image.Pt(200, 100), sizes["watermark"])
+}
+
+func TestTransforms(t *testing.T) {
+	img := createTestImage(200, 100)
+	apply := func(transform Transform) image.Image {
+		variant, err := transform.Apply(img)
+		require.NoError(t, err)
+		return variant
+	}
+
+	t.Run("flips and rotations move the halves", func(t *testing.T) {
+		assert.Equal(t, color.NRGBA{R: 30, G: 30, B: 200, A: 255}, apply(FlipH()).At(0, 0))
+		assert.Equal(t, color.NRGBA{R: 200, G: 30, B: 30, A: 255}, apply(FlipV()).At(0, 0))
+		assert.Equal(t, color.NRGBA{R: 30, G: 30, B: 200, A: 255}, apply(Rotate(180)).At(0, 0))
+		assert.Equal(t, image.Pt(100, 200), apply(Rotate(-90)).Bounds().Size())
+	})
+
+	t.Run("padding surrounds the image with the colour", func(t *testing.T) {
+		padded := apply(Pad(0.1, color.White))
+		assert.Equal(t, color.NRGBA{R: 255, G: 255, B: 255, A: 255}, padded.At(0, 0))
+		assert.Equal(t, color.NRGBA{R: 200, G: 30, B: 30, A: 255}, padded.At(20, 10))
+	})
+
+	t.Run("colour shifts are clamped", func(t *testing.T) {
+		warmer := apply(ColorShift(100))
+		assert.Equal(t, color.NRGBA{R: 255, G: 30, B: 0, A: 255}, warmer.At(0, 0))
+		assert.Equal(t, color.NRGBA{R: 130, G: 30, B: 100, A: 255}, warmer.At(199, 0))
+	})
+
+	t.Run("grayscale images have no colour", func(t *testing.T) {
+		r, g, b, _ := apply(Grayscale()).At(0, 0).RGBA()
+		assert.Equal(t, r, g)
+		assert.Equal(t, g, b)
+	})
+
+	t.Run("watermarks are in the bottom-right corner", func(t *testing.T) {
+		marked := apply(Watermark("mediasim"))
+		assert.Equal(t, img.At(0, 0), marked.At(0, 0))
+		assert.Equal(t, img.At(0, 99), marked.At(0, 99))
+
+		changed := 0
+		for x := 100; x < 200; x++ {
+			for y := 50; y < 100; y++ {
+				if marked.At(x, y) != img.At(x, y) {
+					changed++
+				}
+			}
+		}
+
+		assert.Greater(t, changed, 500)
+	})
+}
@@ -0,0 +1,157 @@
+package variants
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	ffmpeg "github.com/u2takey/ffmpeg-go"
+	iffmpeg "github.com/vegidio/mediasim/internal/ffmpeg"
+)
+
+// VideoTransform is a change applied to a video by FFmpeg to create a variant of it.
+//
+// # Fields:
+//   - Label: The name of the transform and its parameters, like "fps-15", used as the label and name of the variant.
+//   - Input: The FFmpeg options of the input, like the start of a trim.
+//   - Output: The FFmpeg options of the output, like the codec and the filters.
+type VideoTransform struct {
+	Label  string
+	Input  ffmpeg.KwArgs
+	Output ffmpeg.KwArgs
+}
+
+// VideoVariant is a video file created by a transform, labelled with the transform.
+type VideoVariant struct {
+	Label string
+	Path  string
+}
+
+// GenerateVideos creates a variant of the video for each transform, in the directory, with FFmpeg. Every variant is
+// re-encoded as H.264 in an MP4 file, without audio, unless the transform sets another codec.
+//
+// # Parameters:
+//   - ctx: The context of the FFmpeg commands.
+//   - run: The function that runs FFmpeg.
+//   - input: The path to the original video.
+//   - directory: The directory where the variants are created, named after their labels.
+//   - transforms: The transforms to apply; when empty, the transforms of VideoTransforms are used.
+//
+// # Returns:
+//   - The variants, in the order of the transforms.
+//   - An error if FFmpeg failed to create any of the variants.
+func GenerateVideos(
+	ctx context.Context,
+	run iffmpeg.RunFunc,
+	input string,
+	directory string,
+	transforms ...VideoTransform,
+) ([]VideoVariant, error) {
+	if len(transforms) == 0 {
+		transforms = VideoTransforms()
+	}
+
+	variants := make([]VideoVariant, 0, len(transforms))
+	for _, transform := range transforms {
+		output := ffmpeg.KwArgs{"c:v": "libx264", "pix_fmt": "yuv420p", "an": ""}
+		for key, value := range transform.Output {
+			output[key] = value
+		}
+
+		path := filepath.Join(directory, transform.Label+".mp4")
+		args := ffmpeg.Input(input, transform.Input).Output(path, output).OverWriteOutput().GetArgs()
+
+		var stderr bytes.Buffer
+		if err := run(ctx, args, nil, &stderr); err != nil {
+			return nil, fmt.Errorf("failed to create the variant %s: %w: %s", transform.Label, err, lastLine(stderr))
+		}
+
+		variants = append(variants, VideoVariant{Label: transform.Label, Path: path})
+	}
+
+	return variants, nil
+}
+
+// VideoTransforms returns the common transforms of videos: re-encoded at several qualities, trimmed, and with a lower
+// frame rate.
+func VideoTransforms() []VideoTransform {
+	return []VideoTransform{
+		Reencode(23),
+		Reencode(40),
+		Trim(0, 4*time.Second),
+		Trim(time.Second, 0),
+		FPS(10),
+	}
+}
+
+// Reencode re-encodes the video as H.264 with the constant rate factor, between 0 (lossless) and 51 (the worst).
+func Reencode(crf int) VideoTransform {
+	return VideoTransform{
+		Label:  fmt.Sprintf("reencode-crf%d", crf),
+		Output: ffmpeg.KwArgs{"crf": crf},
+	}
+}
+
+// Trim keeps the part of the video that starts at the offset and lasts the duration; a duration of 0 keeps the rest
+// of the video.
+func Trim(start, duration time.Duration) VideoTransform {
+	transform := VideoTransform{
+		Label:  fmt.Sprintf("trim-%s-end", start),
+		Input:  ffmpeg.KwArgs{"ss": start.Seconds()},
+		Output: ffmpeg.KwArgs{},
+	}
+
+	if duration > 0 {
+		transform.Label = fmt.Sprintf("trim-%s-%s", start, start+duration)
+		transform.Output["t"] = duration.Seconds()
+	}
+
+	return transform
+}
+
+// FPS changes the frame rate of the video, dropping or duplicating frames.
+func FPS(fps int) VideoTransform {
+	return VideoTransform{
+		Label:  fmt.Sprintf("fps-%d", fps),
+		Output: ffmpeg.KwArgs{"vf": fmt.Sprintf("fps=%d", fps)},
+	}
+}
+
+// SyntheticVideo creates a synthetic video with FFmpeg, with moving patterns and a timer, to be used as the original of the
+// variants.
+//
+// # Parameters:
+//   - ctx: The context of the FFmpeg command.
+//   - run: The function that runs FFmpeg.
+//   - path: The path of the video to create; the format is chosen by its extension.
+//   - duration: The length of the video.
+//
+// # Returns:
+//   - An error if FFmpeg failed to create the video.
+func SyntheticVideo(ctx context.Context, run iffmpeg.RunFunc, path string, duration time.Duration) error {
+	source := fmt.Sprintf("testsrc2=size=320x240:rate=30:duration=%g", duration.Seconds())
+	args := ffmpeg.Input(source, ffmpeg.KwArgs{"f": "lavfi"}).
+		Output(path, ffmpeg.KwArgs{"c:v": "libx264", "pix_fmt": "yuv420p"}).
+		OverWriteOutput().
+		GetArgs()
+
+	var stderr bytes.Buffer
+	if err := run(ctx, args, nil, &stderr); err != nil {
+		return fmt.Errorf("failed to create the test video: %w: %s", err, lastLine(stderr))
+	}
+
+	return nil
+}
+
+// region - Private functions
+
+// lastLine returns the last line of the FFmpeg output, which usually explains why it failed.
+func lastLine(stderr bytes.Buffer) string {
+	lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+	return lines[len(lines)-1]
+}
+
+// endregion
@@ -0,0 +1,59 @@
+package variants
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateVideos(t *testing.T) {
+	t.Run("each transform is an FFmpeg command", func(t *testing.T) {
+		calls := make([]string, 0)
+		run := func(_ context.Context, args []string, _ io.Reader, _ io.Writer) error {
+			calls = append(calls, strings.Join(args, " "))
+			return nil
+		}
+
+		dir := filepath.FromSlash("/variants")
+		generated, err := GenerateVideos(context.Background(), run, "clip.mov", dir,
+			Reencode(40), Trim(time.Second, 2*time.Second), Trim(3*time.Second, 0), FPS(10))
+		require.NoError(t, err)
+
+		assert.Equal(t, []VideoVariant{
+			{Label: "reencode-crf40", Path: filepath.Join(dir, "reencode-crf40.mp4")},
+			{Label: "trim-1s-3s", Path: filepath.Join(dir, "trim-1s-3s.mp4")},
+			{Label: "trim-3s-end", Path: filepath.Join(dir, "trim-3s-end.mp4")},
+			{Label: "fps-10", Path: filepath.Join(dir, "fps-10.mp4")},
+		}, generated)
+
+		require.Len(t, calls, 4)
+		assert.Contains(t, calls[0], "-crf 40")
+		assert.True(t, strings.HasPrefix(calls[1], "-ss 1 -i clip.mov"))
+		assert.Contains(t, calls[1], "-t 2")
+		assert.NotContains(t, calls[2], "-t ")
+		assert.Contains(t, calls[3], "-vf fps=10")
+
+		// Every variant is re-encoded without audio
+		for _, call := range calls {
+			assert.Contains(t, call, "-an")
+			assert.Contains(t, call, "-c:v libx264")
+		}
+	})
+
+	t.Run("FFmpeg failures name the variant", func(t *testing.T) {
+		run := func(_ context.Context, _ []string, _ io.Reader, stderr io.Writer) error {
+			_, _ = io.WriteString(stderr, "banner\nUnknown encoder 'libx264'\n")
+			return errors.New("exit status 1")
+		}
+
+		_, err := GenerateVideos(context.Background(), run, "clip.mov", t.TempDir(), FPS(10))
+		assert.EqualError(t, err, "failed to create the variant fps-10: exit status 1: Unknown encoder 'libx264'")
+	})
+}
@@ -0,0 +1,95 @@
+package mediasim
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"io"
+)
+
+// DefaultMaxPixels is the maximum number of pixels of an image when LoadOptions.MaxPixels is 0. It's the same limit
+// used by other image libraries to detect decompression bombs: about 180 megapixels, or 720 MB once decoded.
+const DefaultMaxPixels = 178_956_970
+
+// region - Private functions
+
+// bytesPerPixel is the estimated memory used by each pixel of a decoded image.
+const bytesPerPixel = 4
+
+// decodeLimited decodes the image within the limits of the options. The header of the image is decoded first, so images
+// with too many pixels are rejected before they are decoded, JPEGs with a suitable EXIF thumbnail are decoded from it
+// (when FastDecode is set), and the others wait for their share of the memory budget.
+func (o LoadOptions) decodeLimited(r io.Reader, name string) (image.Image, error) {
+	// The header consumed by DecodeConfig is kept, so it can be read again by Decode
+	var header bytes.Buffer
+	config, format, err := image.DecodeConfig(io.TeeReader(r, &header))
+	if err != nil {
+		return nil, err
+	}
+
+	pixels := int64(config.Width) * int64(config.Height)
+	if o.MaxPixels > 0 && pixels > o.MaxPixels {
+		return nil, newMediaError(name, ErrTooManyPixels, fmt.Errorf("%dx%d pixels exceeds the limit of %d pixels",
+			config.Width, config.Height, o.MaxPixels))
+	}
+
+	// The EXIF segment comes before the image data, so it was already read with the header
+	if format == "jpeg" && o.FastDecode {
+		if thumbnail := decodeThumbnail(header.Bytes(), config); thumbnail != nil {
+			return thumbnail, nil
+		}
+	}
+
+	if o.memory != nil {
+		// Images larger than the whole budget take all of it, so they are decoded alone instead of waiting forever
+		cost := min(pixels*bytesPerPixel, o.MemoryBudget)
+		if err = o.memory.Acquire(context.Background(), cost); err != nil {
+			return nil, err
+		}
+
+		defer o.memory.Release(cost)
+	}
+
+	img, _, err := image.Decode(io.MultiReader(&header, r))
+	return img, err
+}
+
+// checkFileSize returns an error when the size of the image file exceeds the limit of the options.
+func (o LoadOptions) checkFileSize(name string, size int64) error {
+	if o.MaxFileSize > 0 && size > o.MaxFileSize {
+		return newMediaError(name, ErrFileTooLarge, fmt.Errorf("%d bytes exceeds the limit of %d bytes", size,
+			o.MaxFileSize))
+	}
+
+	return nil
+}
+
+// sizeLimitReader wraps a reader, failing once more than limit bytes are read from it.
+type sizeLimitReader struct {
+	reader io.Reader
+	limit  int64
+	count  int64
+}
+
+func (l *sizeLimitReader) Read(p []byte) (int, error) {
+	if l.exceeded() {
+		return 0, ErrFileTooLarge
+	}
+
+	// Reading one byte past the limit is enough to know it was exceeded
+	if remaining := l.limit + 1 - l.count; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+
+	n, err := l.reader.Read(p)
+	l.count += int64(n)
+	return n, err
+}
+
+// exceeded reports whether more than limit bytes were read.
+func (l *sizeLimitReader) exceeded() bool {
+	return l.count > l.limit
+}
+
+// endregion
@@ -0,0 +1,134 @@
+package mediasim
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pngBomb returns the beginning of a PNG whose header claims the given dimensions; the pixels are never written, so
+// decoding it fully would only fail after allocating the memory of the whole image.
+func pngBomb(width, height uint32) []byte {
+	var ihdr bytes.Buffer
+	ihdr.WriteString("IHDR")
+	_ = binary.Write(&ihdr, binary.BigEndian, width)
+	_ = binary.Write(&ihdr, binary.BigEndian, height)
+	// 8 bits per sample, RGBA, default compression, filter and interlacing
+	ihdr.Write([]byte{8, 6, 0, 0, 0})
+
+	var content bytes.Buffer
+	content.WriteString("\x89PNG\r\n\x1a\n")
+	_ = binary.Write(&content, binary.BigEndian, uint32(ihdr.Len()-4))
+	content.Write(ihdr.Bytes())
+	_ = binary.Write(&content, binary.BigEndian, crc32.ChecksumIEEE(ihdr.Bytes()))
+
+	return content.Bytes()
+}
+
+func TestLoadOptions_Limits(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, content []byte) string {
+		filePath := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(filePath, content, 0o644))
+		return filePath
+	}
+
+	image := encodePng(t, createSolidImage(color.White, 100, 100))
+	imagePath := write("image.png", image)
+
+	t.Run("decompression bombs are rejected by default", func(t *testing.T) {
+		filePath := write("bomb.png", pngBomb(30000, 30000))
+		_, err := LoadMediaFromFileWithOptions(filePath, LoadOptions{})
+
+		requireMediaError(t, err, ErrTooManyPixels, filePath)
+		assert.Contains(t, err.Error(), "30000x30000 pixels")
+		assert.Equal(t, "too_many_pixels", ErrorCategory(err))
+	})
+
+	t.Run("images with more pixels than the limit are rejected", func(t *testing.T) {
+		_, err := LoadMediaFromFileWithOptions(imagePath, LoadOptions{MaxPixels: 5000})
+		requireMediaError(t, err, ErrTooManyPixels, imagePath)
+
+		_, err = LoadMediaFromReaderWithOptions("image.png", bytes.NewReader(image), LoadOptions{MaxPixels: 5000})
+		requireMediaError(t, err, ErrTooManyPixels, "image.png")
+	})
+
+	t.Run("rejected images aren't decoded with FFmpeg", func(t *testing.T) {
+		fake := &fakeFFmpeg{frame: image}
+		_, err := LoadMediaFromFileWithOptions(imagePath, LoadOptions{MaxPixels: 5000, FFmpeg: fake, FFmpegFallback: []string{FallbackAll}})
+
+		requireMediaError(t, err, ErrTooManyPixels, imagePath)
+		assert.Empty(t, fake.calls)
+	})
+
+	t.Run("a negative limit disables the pixel check", func(t *testing.T) {
+		media, err := LoadMediaFromFileWithOptions(imagePath, LoadOptions{MaxPixels: -1})
+		require.NoError(t, err)
+		assert.Equal(t, 100, media.Width)
+	})
+
+	t.Run("image files larger than the limit are rejected", func(t *testing.T) {
+		limit := int64(len(image) - 1)
+
+		_, err := LoadMediaFromFileWithOptions(imagePath, LoadOptions{MaxFileSize: limit})
+		requireMediaError(t, err, ErrFileTooLarge, imagePath)
+		assert.Equal(t, "file_too_large", ErrorCategory(err))
+
+		_, err = LoadMediaFromReaderWithOptions("image.png", bytes.NewReader(image), LoadOptions{MaxFileSize: limit})
+		requireMediaError(t, err, ErrFileTooLarge, "image.png")
+	})
+
+	t.Run("image files within the limit are loaded", func(t *testing.T) {
+		limit := int64(len(image))
+
+		_, err := LoadMediaFromFileWithOptions(imagePath, LoadOptions{MaxFileSize: limit})
+		require.NoError(t, err)
+
+		media, err := LoadMediaFromReaderWithOptions("image.png", bytes.NewReader(image), LoadOptions{MaxFileSize: limit})
+		require.NoError(t, err)
+		assert.Equal(t, limit, media.Size)
+	})
+
+	t.Run("videos aren't limited by the file size", func(t *testing.T) {
+		filePath := write("clip.mp4", bytes.Repeat([]byte("video"), 100))
+		fake := &fakeFFmpeg{frame: image}
+
+		_, err := LoadMediaFromFileWithOptions(filePath, LoadOptions{MaxFileSize: 10, FFmpeg: fake})
+		assert.NoError(t, err)
+	})
+
+	t.Run("images larger than the memory budget are decoded one at a time", func(t *testing.T) {
+		paths := make([]string, 8)
+		for i := range paths {
+			paths[i] = write(fmt.Sprintf("budget%d.png", i), image)
+		}
+
+		// Each image needs 40 KB, so the budget can't hold any of them
+		channel := LoadMediaFromFiles(paths, FilesOptions{Parallel: 4, LoadOptions: LoadOptions{MemoryBudget: 1024}})
+
+		loaded := 0
+		for result := range channel {
+			require.NoError(t, result.Err)
+			loaded++
+		}
+
+		assert.Equal(t, len(paths), loaded)
+	})
+
+	t.Run("the memory budget is shared by the copies of the options", func(t *testing.T) {
+		options := FilesOptions{LoadOptions: LoadOptions{MemoryBudget: 1024}}
+		options.SetDefaults()
+
+		loadOptions := options.LoadOptions
+		loadOptions.SetDefaults()
+		assert.Same(t, options.memory, loadOptions.memory)
+	})
+}
@@ -0,0 +1,322 @@
+package mediasim
+
+import (
+	"fmt"
+	"io"
+	iofs "io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// AllowsFile reports whether a file of the directory would be listed by LoadMediaFromDirectory with these options: it
+// is in a directory that is searched, it passes the filters, and it's one of the included types of media. Archives
+// aren't allowed, since their media are loaded separately.
+//
+// It's used to check single files, like the ones created after the directory was loaded, without listing the whole
+// directory again.
+//
+// # Parameters:
+//   - directory: The directory the options apply to.
+//   - filePath: The path of the file, inside the directory.
+//
+// # Returns:
+//   - True if the file is allowed; false if it isn't, or it's not a regular file inside the directory.
+func (o DirectoryOptions) AllowsFile(directory string, filePath string) bool {
+	o.SetDefaults()
+	filter := fileFilter{options: o, extensions: o.mediaTypes()}
+
+	relPath, err := filepath.Rel(directory, filePath)
+	if err != nil || relPath == "." || strings.HasPrefix(relPath, "..") {
+		return false
+	}
+
+	relPath = filepath.ToSlash(relPath)
+	segments := strings.Split(relPath, "/")
+
+	dir := directory
+	for depth, name := range segments[:len(segments)-1] {
+		dir = filepath.Join(dir, name)
+		if !filter.allowDir(path.Join(segments[:depth+1]...), name, isHidden(dir, name), depth+1) {
+			return false
+		}
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+
+	name := segments[len(segments)-1]
+	if !filter.allowFile(relPath, name, isHidden(filePath, name), info) {
+		return false
+	}
+
+	return filter.allowType(name, func() (io.ReadCloser, error) { return os.Open(filePath) })
+}
+
+// region - Private functions
+
+// listingInterval is the number of files found between two listing events.
+const listingInterval = 100
+
+// fileFilter decides which files and directories are listed, based on the options of the directory. With archives, the
+// supported archives are also listed.
+type fileFilter struct {
+	options    DirectoryOptions
+	extensions []string
+	archives   bool
+}
+
+// allowDir reports whether the directory, at the given depth (1 for the subdirectories of the root), should be
+// searched.
+func (f fileFilter) allowDir(relPath string, name string, hidden bool, depth int) bool {
+	if !f.options.IsRecursive {
+		return false
+	}
+
+	if f.options.MaxDepth > 0 && depth > f.options.MaxDepth {
+		return false
+	}
+
+	if hidden && f.options.ExcludeHidden {
+		return false
+	}
+
+	return !matchAny(f.options.Exclude, relPath, name)
+}
+
+// allowFile reports whether the file passes the filters based on its path and metadata. Its type is checked separately
+// by allowType, which may need to read the file.
+func (f fileFilter) allowFile(relPath string, name string, hidden bool, info iofs.FileInfo) bool {
+	if hidden && f.options.ExcludeHidden {
+		return false
+	}
+
+	// Archives are only skipped by their path; the filters are applied to their entries by allowEntry
+	if f.archives && isArchive(name) {
+		return !matchAny(f.options.Exclude, relPath, name)
+	}
+
+	if len(f.options.Include) > 0 && !matchAny(f.options.Include, relPath, name) {
+		return false
+	}
+
+	if matchAny(f.options.Exclude, relPath, name) {
+		return false
+	}
+
+	if f.options.MinSize > 0 && info.Size() < f.options.MinSize {
+		return false
+	}
+
+	if f.options.MaxSize > 0 && info.Size() > f.options.MaxSize {
+		return false
+	}
+
+	if !f.options.ModifiedSince.IsZero() && info.ModTime().Before(f.options.ModifiedSince) {
+		return false
+	}
+
+	if !f.options.ModifiedBefore.IsZero() && !info.ModTime().Before(f.options.ModifiedBefore) {
+		return false
+	}
+
+	return true
+}
+
+// allowType reports whether the file has one of the extensions or, when content detection is enabled, whether its
+// content, read with open, is one of the included types of media.
+func (f fileFilter) allowType(name string, open func() (io.ReadCloser, error)) bool {
+	if f.archives && isArchive(name) {
+		return true
+	}
+
+	if slices.Contains(f.extensions, strings.ToLower(path.Ext(name))) {
+		return true
+	}
+
+	if !f.options.DetectContent {
+		return false
+	}
+
+	switch sniffType(open) {
+	case "image":
+		return f.options.IncludeImages
+	case "video":
+		return f.options.IncludeVideos
+	}
+
+	return false
+}
+
+// allowEntry reports whether the entry of an archive passes the filters and is one of the included types of media, as a
+// file of the directory would. Its path is the path of the archive, relative to the directory, followed by the path of
+// the entry; the directories inside the archive are checked like the ones of the directory, except for their depth.
+func (f fileFilter) allowEntry(
+	archiveRelPath string,
+	entryName string,
+	info iofs.FileInfo,
+	open func() (io.ReadCloser, error),
+) bool {
+	segments := strings.Split(strings.TrimPrefix(path.Clean("/"+entryName), "/"), "/")
+	relPath := archiveRelPath
+
+	for _, name := range segments[:len(segments)-1] {
+		relPath = path.Join(relPath, name)
+		if strings.HasPrefix(name, ".") && f.options.ExcludeHidden || matchAny(f.options.Exclude, relPath, name) {
+			return false
+		}
+	}
+
+	name := segments[len(segments)-1]
+	relPath = path.Join(relPath, name)
+
+	return f.allowFile(relPath, name, strings.HasPrefix(name, "."), info) && f.allowType(name, open)
+}
+
+// reportListing sends a listing event to the observer every listingInterval files found, and when the listing is done.
+func (f fileFilter) reportListing(directory string, found int, done bool) {
+	if done || found%listingInterval == 0 {
+		notify(f.options.Observer, ListingEvent{Directory: directory, Found: found, Done: done})
+	}
+}
+
+// listFiles lists the files in the directory that have one of the extensions and pass the filters in the options; with
+// IncludeArchives, the supported archives are also listed.
+func listFiles(directory string, options DirectoryOptions, extensions []string) ([]string, error) {
+	filter := fileFilter{options: options, extensions: extensions, archives: options.IncludeArchives}
+	files := make([]string, 0)
+
+	root, err := os.Stat(directory)
+	if err != nil {
+		return files, err
+	}
+
+	if !root.IsDir() {
+		return files, fmt.Errorf("'%s' is not a directory", directory)
+	}
+
+	// The real paths of the visited directories are tracked to avoid loops when following symlinks
+	visited := make(map[string]bool)
+
+	var walk func(dir string, relDir string, depth int)
+	walk = func(dir string, relDir string, depth int) {
+		if realPath, realErr := filepath.EvalSymlinks(dir); realErr == nil {
+			if visited[realPath] {
+				return
+			}
+
+			visited[realPath] = true
+		}
+
+		entries, readErr := os.ReadDir(dir)
+		if readErr != nil {
+			return // skip on error for subdirectories
+		}
+
+		for _, entry := range entries {
+			fullPath := filepath.Join(dir, entry.Name())
+			relPath := path.Join(relDir, entry.Name())
+			hidden := isHidden(fullPath, entry.Name())
+
+			info, infoErr := entry.Info()
+			if infoErr != nil {
+				continue
+			}
+
+			if entry.Type()&iofs.ModeSymlink != 0 {
+				target, statErr := os.Stat(fullPath)
+				if statErr != nil {
+					continue // broken link
+				}
+
+				if target.IsDir() {
+					if options.FollowSymlinks && filter.allowDir(relPath, entry.Name(), hidden, depth+1) {
+						walk(fullPath, relPath, depth+1)
+					}
+
+					continue
+				}
+
+				info = target
+			}
+
+			if info.IsDir() {
+				if filter.allowDir(relPath, entry.Name(), hidden, depth+1) {
+					walk(fullPath, relPath, depth+1)
+				}
+
+				continue
+			}
+
+			if !info.Mode().IsRegular() || !filter.allowFile(relPath, entry.Name(), hidden, info) {
+				continue
+			}
+
+			if filter.allowType(entry.Name(), func() (io.ReadCloser, error) { return os.Open(fullPath) }) {
+				files = append(files, fullPath)
+				filter.reportListing(directory, len(files), false)
+			}
+		}
+	}
+
+	walk(directory, "", 0)
+	filter.reportListing(directory, len(files), true)
+
+	return files, nil
+}
+
+// matchAny reports whether any of the glob patterns matches the path. Patterns without a "/" are matched against the
+// name only, while the others are matched against the whole path relative to the searched directory, where "**"
+// matches any number of directories.
+func matchAny(patterns []string, relPath string, name string) bool {
+	for _, pattern := range patterns {
+		pattern = filepath.ToSlash(pattern)
+
+		if !strings.Contains(pattern, "/") {
+			if ok, _ := path.Match(pattern, name); ok {
+				return true
+			}
+
+			continue
+		}
+
+		if matchSegments(strings.Split(strings.TrimPrefix(pattern, "/"), "/"), strings.Split(relPath, "/")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchSegments matches the segments of a path against the segments of a glob pattern, supporting "**".
+func matchSegments(pattern []string, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if len(segments) == 0 {
+		return false
+	}
+
+	if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+		return false
+	}
+
+	return matchSegments(pattern[1:], segments[1:])
+}
+
+// endregion
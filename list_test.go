@@ -0,0 +1,171 @@
+package mediasim
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeFiles creates the files, relative to the directory, with the given sizes in bytes.
+func writeFiles(t *testing.T, dir string, files map[string]int) {
+	t.Helper()
+
+	for name, size := range files {
+		fullPath := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(fullPath), 0o755))
+		require.NoError(t, os.WriteFile(fullPath, make([]byte, size), 0o644))
+	}
+}
+
+// relPaths lists the files and returns their paths relative to the directory, sorted.
+func relPaths(t *testing.T, dir string, options DirectoryOptions) []string {
+	t.Helper()
+
+	files, err := listFiles(dir, options, []string{".jpg", ".png"})
+	require.NoError(t, err)
+
+	paths := make([]string, 0, len(files))
+	for _, file := range files {
+		rel, relErr := filepath.Rel(dir, file)
+		require.NoError(t, relErr)
+		paths = append(paths, filepath.ToSlash(rel))
+	}
+
+	sort.Strings(paths)
+	return paths
+}
+
+func TestListFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]int{
+		"a.jpg":                  100,
+		"b.png":                  5000,
+		"notes.txt":              100,
+		".hidden.jpg":            100,
+		"sub/c.jpg":              100,
+		"sub/deep/d.jpg":         100,
+		"cache/e.jpg":            100,
+		".thumbnails/thumb.jpg":  100,
+		"sub/deep/icons/ico.png": 10,
+	})
+
+	t.Run("non-recursive lists only the top level", func(t *testing.T) {
+		assert.Equal(t, []string{"a.jpg", "b.png"}, relPaths(t, dir, DirectoryOptions{ExcludeHidden: true}))
+	})
+
+	t.Run("hidden files and directories are included unless excluded", func(t *testing.T) {
+		paths := relPaths(t, dir, DirectoryOptions{IsRecursive: true})
+		assert.Contains(t, paths, ".hidden.jpg")
+		assert.Contains(t, paths, ".thumbnails/thumb.jpg")
+
+		paths = relPaths(t, dir, DirectoryOptions{IsRecursive: true, ExcludeHidden: true})
+		assert.NotContains(t, paths, ".hidden.jpg")
+		assert.NotContains(t, paths, ".thumbnails/thumb.jpg")
+	})
+
+	t.Run("include patterns match names and paths", func(t *testing.T) {
+		paths := relPaths(t, dir, DirectoryOptions{IsRecursive: true, Include: []string{"*.png"}})
+		assert.Equal(t, []string{"b.png", "sub/deep/icons/ico.png"}, paths)
+
+		paths = relPaths(t, dir, DirectoryOptions{IsRecursive: true, Include: []string{"sub/**/*.jpg"}})
+		assert.Equal(t, []string{"sub/c.jpg", "sub/deep/d.jpg"}, paths)
+	})
+
+	t.Run("exclude patterns skip files and directories", func(t *testing.T) {
+		paths := relPaths(t, dir, DirectoryOptions{IsRecursive: true, Exclude: []string{"cache", "**/icons"}, ExcludeHidden: true})
+		assert.Equal(t, []string{"a.jpg", "b.png", "sub/c.jpg", "sub/deep/d.jpg"}, paths)
+	})
+
+	t.Run("size limits", func(t *testing.T) {
+		paths := relPaths(t, dir, DirectoryOptions{IsRecursive: true, MinSize: 50, MaxSize: 1000, ExcludeHidden: true})
+		assert.Equal(t, []string{"a.jpg", "cache/e.jpg", "sub/c.jpg", "sub/deep/d.jpg"}, paths)
+	})
+
+	t.Run("max depth limits the subdirectory levels", func(t *testing.T) {
+		paths := relPaths(t, dir, DirectoryOptions{IsRecursive: true, MaxDepth: 1, ExcludeHidden: true})
+		assert.Equal(t, []string{"a.jpg", "b.png", "cache/e.jpg", "sub/c.jpg"}, paths)
+	})
+
+	t.Run("modification time limits", func(t *testing.T) {
+		old := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		require.NoError(t, os.Chtimes(filepath.Join(dir, "a.jpg"), old, old))
+
+		since := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+		assert.Equal(t, []string{"b.png"}, relPaths(t, dir, DirectoryOptions{ModifiedSince: since, ExcludeHidden: true}))
+		assert.Equal(t, []string{"a.jpg"}, relPaths(t, dir, DirectoryOptions{ModifiedBefore: since, ExcludeHidden: true}))
+	})
+
+	t.Run("missing directory returns an error", func(t *testing.T) {
+		_, err := listFiles(filepath.Join(dir, "missing"), DirectoryOptions{}, []string{".jpg"})
+		assert.Error(t, err)
+	})
+}
+
+func TestDirectoryOptions_AllowsFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]int{
+		"a.jpg":          100,
+		"notes.txt":      100,
+		".hidden.jpg":    100,
+		"sub/b.jpg":      100,
+		"sub/deep/c.jpg": 100,
+		"cache/d.jpg":    100,
+	})
+
+	allowed := func(options DirectoryOptions, name string) bool {
+		return options.AllowsFile(dir, filepath.Join(dir, filepath.FromSlash(name)))
+	}
+
+	recursive := DirectoryOptions{IsRecursive: true, MaxDepth: 1, Exclude: []string{"cache"}, ExcludeHidden: true}
+	assert.True(t, allowed(recursive, "a.jpg"))
+	assert.True(t, allowed(recursive, "sub/b.jpg"))
+	assert.False(t, allowed(recursive, "notes.txt"), "not a media")
+	assert.False(t, allowed(recursive, ".hidden.jpg"), "hidden")
+	assert.False(t, allowed(recursive, "sub/deep/c.jpg"), "deeper than the max depth")
+	assert.False(t, allowed(recursive, "cache/d.jpg"), "in an excluded directory")
+	assert.False(t, allowed(recursive, "missing.jpg"), "doesn't exist")
+	assert.False(t, allowed(recursive, "sub"), "not a file")
+
+	assert.False(t, allowed(DirectoryOptions{}, "sub/b.jpg"), "not recursive")
+	assert.False(t, allowed(DirectoryOptions{IncludeVideos: true}, "a.jpg"), "only videos")
+	assert.False(t, DirectoryOptions{}.AllowsFile(filepath.Join(dir, "sub"), filepath.Join(dir, "a.jpg")), "outside")
+}
+
+func TestListFiles_Symlinks(t *testing.T) {
+	dir := t.TempDir()
+	other := t.TempDir()
+	writeFiles(t, dir, map[string]int{"a.jpg": 10, "sub/b.jpg": 10})
+	writeFiles(t, other, map[string]int{"c.jpg": 10})
+
+	if err := os.Symlink(other, filepath.Join(dir, "linked")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	// A link back to the root creates a loop
+	require.NoError(t, os.Symlink(dir, filepath.Join(dir, "sub", "loop")))
+	require.NoError(t, os.Symlink(filepath.Join(dir, "a.jpg"), filepath.Join(dir, "alias.jpg")))
+
+	t.Run("symlinked directories are not followed by default", func(t *testing.T) {
+		paths := relPaths(t, dir, DirectoryOptions{IsRecursive: true})
+		assert.Equal(t, []string{"a.jpg", "alias.jpg", "sub/b.jpg"}, paths)
+	})
+
+	t.Run("symlinked directories are followed without loops", func(t *testing.T) {
+		paths := relPaths(t, dir, DirectoryOptions{IsRecursive: true, FollowSymlinks: true})
+		assert.Equal(t, []string{"a.jpg", "alias.jpg", "linked/c.jpg", "sub/b.jpg"}, paths)
+	})
+}
+
+func TestMatchAny(t *testing.T) {
+	assert.True(t, matchAny([]string{"*.jpg"}, "a/b/c.jpg", "c.jpg"))
+	assert.True(t, matchAny([]string{"a/**/c.jpg"}, "a/b/c.jpg", "c.jpg"))
+	assert.True(t, matchAny([]string{"a/**/c.jpg"}, "a/c.jpg", "c.jpg"))
+	assert.True(t, matchAny([]string{"**/b"}, "a/b", "b"))
+	assert.False(t, matchAny([]string{"a/*.jpg"}, "a/b/c.jpg", "c.jpg"))
+	assert.False(t, matchAny(nil, "a.jpg", "a.jpg"))
+}
@@ -1,32 +1,28 @@
 package mediasim
 
 import (
+	"bufio"
+	"context"
 	"fmt"
 	"image"
 	_ "image/gif"
 	_ "image/jpeg"
 	_ "image/png"
+	"io"
+	iofs "io/fs"
 	"os"
-	"path/filepath"
-	"slices"
 	"strings"
 
-	"github.com/disintegration/imaging"
 	"github.com/samber/lo"
 	"github.com/vegidio/go-sak/async"
-	"github.com/vegidio/go-sak/fs"
 	. "github.com/vegidio/go-sak/types"
 	iffmpeg "github.com/vegidio/mediasim/internal/ffmpeg"
 	"github.com/vitali-fedulov/images4"
 	_ "golang.org/x/image/bmp"
 	_ "golang.org/x/image/tiff"
 	_ "golang.org/x/image/webp"
-	"shared"
 )
 
-// Holds the file path to the FFmpeg binary. Defaults to the system-installed path if not explicitly set.
-var ffmpegPath = shared.GetFFmpegPath("mediasim")
-
 // LoadMediaFromImages creates a Media object from the given image or video.
 //
 // # Parameters:
@@ -46,45 +42,25 @@ func LoadMediaFromImages(name string, images []image.Image, options FrameOptions
 		seconds = size
 	}
 
+	width, height := imageSize(images[0])
 	media := Media{
 		Name:   name,
 		Type:   mediaType,
-		Width:  images[0].Bounds().Dx(),
-		Height: images[0].Bounds().Dy(),
+		Width:  width,
+		Height: height,
 		Length: seconds,
 	}
 
-	media.framesOriginal = lo.Map(images, func(img image.Image, _ int) images4.IconT {
-		return images4.Icon(img)
+	media.options = options
+	media.icons = lo.Map(images, func(img image.Image, _ int) icon {
+		return newIcon(images4.Icon(img))
 	})
 
-	if options.FrameFlip {
-		flipped := lo.Map(images, func(img image.Image, _ int) lo.Tuple2[images4.IconT, images4.IconT] {
-			return lo.T2(
-				images4.Icon(imaging.FlipH(img)),
-				images4.Icon(imaging.FlipV(img)),
-			)
-		})
-
-		media.framesFlippedH, media.framesFlippedV = lo.Unzip2(flipped)
-	}
-
-	if options.FrameRotate {
-		rotated := lo.Map(images, func(img image.Image, _ int) lo.Tuple3[images4.IconT, images4.IconT, images4.IconT] {
-			return lo.T3(
-				images4.Icon(imaging.Rotate90(img)),
-				images4.Icon(imaging.Rotate180(img)),
-				images4.Icon(imaging.Rotate270(img)),
-			)
-		})
-
-		media.framesRotated90, media.framesRotated180, media.framesRotated270 = lo.Unzip3(rotated)
-	}
-
 	return media
 }
 
-// LoadMediaFromFile loads a Media object from the given file path.
+// LoadMediaFromFile loads a Media object from the given file path, using the default type registry and the default
+// limits. Use LoadMediaFromFileWithOptions for the other options.
 //
 // # Parameters:
 //   - filePath: The path to the image or video file.
@@ -94,45 +70,64 @@ func LoadMediaFromImages(name string, images []image.Image, options FrameOptions
 //   - A pointer to a Media object containing the name and the converted image.
 //   - An error if there is an issue opening or decoding the file.
 func LoadMediaFromFile(filePath string, options FrameOptions) (*Media, error) {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("error opening file '%s': %w", filePath, err)
-	}
-
-	defer file.Close()
-
-	ext := strings.ToLower(filepath.Ext(file.Name()))
-	images := make([]image.Image, 0)
-
-	if slices.Contains(shared.ValidImageTypes, ext) {
-		img, _, imgErr := image.Decode(file)
-		if imgErr != nil {
-			return nil, imgErr
-		}
-
-		images = append(images, img)
-
-	} else if slices.Contains(shared.ValidVideoTypes, ext) {
-		videos, vidErr := iffmpeg.ExtractFrames(file.Name(), ffmpegPath)
-		if vidErr != nil {
-			return nil, vidErr
-		}
+	return LoadMediaFromFileWithOptions(filePath, LoadOptions{FrameOptions: options})
+}
 
-		images = append(images, videos...)
-	}
+// LoadMediaFromFileWithOptions works like LoadMediaFromFile, but accepts the full set of options for loading the media.
+//
+// # Parameters:
+//   - filePath: The path to the image or video file.
+//   - options: The configuration options for loading the media.
+//
+// # Returns:
+//   - A pointer to a Media object containing the name and the converted image.
+//   - An error if there is an issue opening or decoding the file.
+func LoadMediaFromFileWithOptions(filePath string, options LoadOptions) (*Media, error) {
+	options.SetDefaults()
 
-	if len(images) == 0 {
-		return nil, fmt.Errorf("no valid images found in file '%s'", filePath)
-	}
+	return observeLoad(options.Observer, filePath, func() (*Media, error) {
+		return loadMediaFromFile(filePath, options)
+	})
+}
 
-	media := LoadMediaFromImages(filePath, images, options)
+// LoadMediaFromReader loads a Media object from the content of the given reader, using the default type registry and
+// the default limits. Use LoadMediaFromReaderWithOptions for the other options.
+//
+// The type of the media is determined by the extension of the name. Videos are streamed to FFmpeg via stdin, so
+// formats that require seeking (e.g., MP4 files with the index at the end) may fail to load; prefer LoadMediaFromFile
+// when the media is available in the file system.
+//
+// # Parameters:
+//   - name: The name of the media; e.g., the original file name.
+//   - r: The reader with the content of the image or video.
+//   - options: The configuration options for loading frames.
+//
+// # Returns:
+//   - A pointer to a Media object containing the name and the converted image.
+//   - An error if there is an issue reading or decoding the content.
+func LoadMediaFromReader(name string, r io.Reader, options FrameOptions) (*Media, error) {
+	return LoadMediaFromReaderWithOptions(name, r, LoadOptions{FrameOptions: options})
+}
 
-	// Add the file size to the media
-	if info, infoErr := file.Stat(); infoErr == nil {
-		media.Size = info.Size()
-	}
+// LoadMediaFromReaderWithOptions works like LoadMediaFromReader, but accepts the full set of options for loading the
+// media.
+//
+// The type of the media is determined by the extension of the name or, with DetectContent, by the content.
+//
+// # Parameters:
+//   - name: The name of the media; e.g., the original file name.
+//   - r: The reader with the content of the image or video.
+//   - options: The configuration options for loading the media.
+//
+// # Returns:
+//   - A pointer to a Media object containing the name and the converted image.
+//   - An error if there is an issue reading or decoding the content.
+func LoadMediaFromReaderWithOptions(name string, r io.Reader, options LoadOptions) (*Media, error) {
+	options.SetDefaults()
 
-	return &media, nil
+	return observeLoad(options.Observer, name, func() (*Media, error) {
+		return loadMediaFromReader(name, r, options)
+	})
 }
 
 // LoadMediaFromFiles loads Media objects from an array of file paths.
@@ -148,7 +143,7 @@ func LoadMediaFromFiles(filePaths []string, options FilesOptions) <-chan Result[
 	options.SetDefaults()
 
 	return async.SliceToChannel(filePaths, options.Parallel, func(filePath string) Result[Media] {
-		media, err := LoadMediaFromFile(filePath, options.FrameOptions)
+		media, err := LoadMediaFromFileWithOptions(filePath, options.loadOptions())
 
 		if err == nil {
 			return Result[Media]{Data: *media}
@@ -160,6 +155,9 @@ func LoadMediaFromFiles(filePaths []string, options FilesOptions) <-chan Result[
 
 // LoadMediaFromDirectory loads Media objects from a specified directory based on the provided options.
 //
+// When IncludeArchives is set, the media inside the archives are also loaded, and their names have the form
+// "archive.zip!/path/in/archive.jpg".
+//
 // # Parameters:
 //   - directory: The path to the directory containing media files.
 //   - options: A DirectoryOptions struct specifying the configuration for loading media.
@@ -170,20 +168,88 @@ func LoadMediaFromFiles(filePaths []string, options FilesOptions) <-chan Result[
 func LoadMediaFromDirectory(directory string, options DirectoryOptions) (<-chan Result[Media], int) {
 	options.SetDefaults()
 
-	mediaTypes := make([]string, 0)
-	if options.IncludeImages {
-		mediaTypes = append(mediaTypes, shared.ValidImageTypes...)
-	}
-	if options.IncludeVideos {
-		mediaTypes = append(mediaTypes, shared.ValidVideoTypes...)
+	mediaTypes := options.mediaTypes()
+	paths, err := listFiles(directory, options, mediaTypes)
+
+	if err != nil {
+		result := make(chan Result[Media], 1)
+		defer close(result)
+
+		result <- Result[Media]{Err: err}
+		return result, 0
 	}
 
-	flags := fs.LpFile
-	if options.IsRecursive {
-		flags |= fs.LpRecursive
+	if options.IncludeArchives {
+		filePaths := lo.Filter(paths, func(p string, _ int) bool { return !isArchive(p) })
+		archivePaths := lo.Filter(paths, func(p string, _ int) bool { return isArchive(p) })
+		return loadMediaFromSources(directory, filePaths, archivePaths, options)
 	}
 
-	filePaths, err := fs.ListPath(directory, flags, mediaTypes)
+	return LoadMediaFromFiles(paths, FilesOptions{
+		Parallel:     options.Parallel,
+		FrameOptions: options.FrameOptions,
+		LoadOptions:  options.LoadOptions,
+	}), len(paths)
+}
+
+// LoadMediaFromFS loads Media objects from a directory in a file system, like an embed.FS or a virtual file system.
+//
+// # Parameters:
+//   - fsys: The file system containing the media files.
+//   - directory: The path to the directory in the file system; use "." for its root.
+//   - options: A DirectoryOptions struct specifying the configuration for loading media.
+//
+// # Returns:
+//   - A channel that will receive Result[Media] objects for each valid file processed. The name of each Media is its
+//     path in the file system.
+//   - An integer representing the total number of files that will be processed.
+func LoadMediaFromFS(fsys iofs.FS, directory string, options DirectoryOptions) (<-chan Result[Media], int) {
+	options.SetDefaults()
+
+	mediaTypes := options.mediaTypes()
+
+	filter := fileFilter{options: options, extensions: mediaTypes}
+	filePaths := make([]string, 0)
+
+	err := iofs.WalkDir(fsys, directory, func(p string, d iofs.DirEntry, err error) error {
+		if err != nil {
+			// If this is the root directory, and it can't be read, return the error
+			if p == directory {
+				return err
+			}
+			return nil
+		}
+
+		if p == directory {
+			return nil
+		}
+
+		relPath := strings.TrimPrefix(p, directory+"/")
+		if directory == "." {
+			relPath = p
+		}
+
+		hidden := strings.HasPrefix(d.Name(), ".")
+
+		if d.IsDir() {
+			if !filter.allowDir(relPath, d.Name(), hidden, strings.Count(relPath, "/")+1) {
+				return iofs.SkipDir
+			}
+			return nil
+		}
+
+		info, infoErr := d.Info()
+		if infoErr != nil || !filter.allowFile(relPath, d.Name(), hidden, info) {
+			return nil
+		}
+
+		if filter.allowType(d.Name(), func() (io.ReadCloser, error) { return fsys.Open(p) }) {
+			filePaths = append(filePaths, p)
+			filter.reportListing(directory, len(filePaths), false)
+		}
+
+		return nil
+	})
 
 	if err != nil {
 		result := make(chan Result[Media], 1)
@@ -193,8 +259,174 @@ func LoadMediaFromDirectory(directory string, options DirectoryOptions) (<-chan
 		return result, 0
 	}
 
-	return LoadMediaFromFiles(filePaths, FilesOptions{
-		Parallel:     options.Parallel,
-		FrameOptions: options.FrameOptions,
+	filter.reportListing(directory, len(filePaths), true)
+
+	return async.SliceToChannel(filePaths, options.Parallel, func(filePath string) Result[Media] {
+		file, openErr := fsys.Open(filePath)
+		if openErr != nil {
+			return Result[Media]{Err: newMediaError(filePath, ErrUnreadable, openErr)}
+		}
+
+		defer file.Close()
+
+		media, loadErr := LoadMediaFromReaderWithOptions(filePath, file, options.loadOptions())
+		if loadErr != nil {
+			return Result[Media]{Err: loadErr}
+		}
+
+		return Result[Media]{Data: *media}
 	}), len(filePaths)
 }
+
+// region - Private functions
+
+// loadMediaFromFile loads the media of LoadMediaFromFile, with the options already set to their defaults.
+func loadMediaFromFile(filePath string, options LoadOptions) (*Media, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, newMediaError(filePath, ErrUnreadable, err)
+	}
+
+	defer file.Close()
+
+	var header []byte
+	if options.DetectContent {
+		if header, err = readHeader(file); err != nil {
+			return nil, newMediaError(filePath, ErrUnreadable, err)
+		}
+
+		if _, err = file.Seek(0, io.SeekStart); err != nil {
+			return nil, newMediaError(filePath, ErrUnreadable, err)
+		}
+	}
+
+	mediaType, contentExt := options.resolveType(filePath, header)
+	images := make([]image.Image, 0)
+
+	if mediaType == "image" {
+		if info, infoErr := file.Stat(); infoErr == nil {
+			if sizeErr := options.checkFileSize(filePath, info.Size()); sizeErr != nil {
+				return nil, sizeErr
+			}
+		}
+
+		img, imgErr := options.decodeImageFile(file, filePath)
+		if imgErr != nil {
+			return nil, imgErr
+		}
+
+		images = append(images, img)
+
+	} else if mediaType == "video" {
+		videos, vidErr := runFFmpeg(options, func(ctx context.Context, config iffmpeg.Config) ([]image.Image, error) {
+			return iffmpeg.ExtractFrames(ctx, file.Name(), config)
+		})
+		if vidErr != nil {
+			return nil, ffmpegError(filePath, vidErr)
+		}
+
+		images = append(images, videos...)
+	}
+
+	if len(images) == 0 {
+		return nil, newMediaError(filePath, ErrUnsupportedFormat, nil)
+	}
+
+	media := LoadMediaFromImages(filePath, images, options.FrameOptions)
+	media.ContentExt = contentExt
+
+	// Add the file size to the media
+	if info, infoErr := file.Stat(); infoErr == nil {
+		media.Size = info.Size()
+	}
+
+	return &media, nil
+}
+
+// loadMediaFromReader loads the media of LoadMediaFromReader, with the options already set to their defaults.
+func loadMediaFromReader(name string, r io.Reader, options LoadOptions) (*Media, error) {
+	counter := &countingReader{reader: r}
+	var reader io.Reader = counter
+	var header []byte
+
+	if options.DetectContent {
+		// The header is peeked, so it's still available to the decoders
+		buffered := bufio.NewReader(counter)
+		header, _ = buffered.Peek(sniffLen)
+		reader = buffered
+	}
+
+	mediaType, contentExt := options.resolveType(name, header)
+	images := make([]image.Image, 0)
+
+	if mediaType == "image" {
+		var limited *sizeLimitReader
+		if options.MaxFileSize > 0 {
+			limited = &sizeLimitReader{reader: reader, limit: options.MaxFileSize}
+			reader = limited
+		}
+
+		img, imgErr := options.decodeImageStream(reader, name)
+
+		if limited != nil {
+			// The rest of the content is read to know whether it exceeds the limit
+			_, _ = io.Copy(io.Discard, limited)
+			if limited.exceeded() {
+				return nil, newMediaError(name, ErrFileTooLarge, fmt.Errorf("more than %d bytes", options.MaxFileSize))
+			}
+		}
+
+		if imgErr != nil {
+			return nil, imgErr
+		}
+
+		images = append(images, img)
+
+	} else if mediaType == "video" {
+		videos, vidErr := runFFmpeg(options, func(ctx context.Context, config iffmpeg.Config) ([]image.Image, error) {
+			return iffmpeg.ExtractFramesFromReader(ctx, reader, config)
+		})
+		if vidErr != nil {
+			return nil, ffmpegError(name, vidErr)
+		}
+
+		images = append(images, videos...)
+	}
+
+	if len(images) == 0 {
+		return nil, newMediaError(name, ErrUnsupportedFormat, nil)
+	}
+
+	// Decoders may stop reading before the end of the content, so we drain the rest to know its size
+	_, _ = io.Copy(io.Discard, reader)
+
+	media := LoadMediaFromImages(name, images, options.FrameOptions)
+	media.Size = counter.count
+	media.ContentExt = contentExt
+
+	return &media, nil
+}
+
+// mediaTypes returns the extensions of the media included in the directory.
+func (o DirectoryOptions) mediaTypes() []string {
+	mediaTypes := o.Types.mediaTypes(o.IncludeImages, o.IncludeVideos)
+	if o.IncludeImages {
+		mediaTypes = append(mediaTypes, o.fallbackTypes()...)
+	}
+
+	return mediaTypes
+}
+
+// countingReader wraps a reader, counting the number of bytes read from it.
+type countingReader struct {
+	reader io.Reader
+	count  int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.count += int64(n)
+	return n, err
+}
+
+// endregion
@@ -1,6 +1,8 @@
 package mediasim
 
 import (
+	"time"
+
 	. "github.com/vegidio/go-sak/types"
 	"github.com/vegidio/mediasim/internal/dsu"
 )
@@ -17,6 +19,8 @@ type LoadAndGroupResult struct {
 	Done bool
 	// Groups contains the final grouped result, only populated when Done is true.
 	Groups [][]Media
+	// Stats contains the statistics of the run, only populated when Done is true.
+	Stats GroupStats
 }
 
 // LoadAndGroupMedia performs media loading and similarity grouping in a single pass.
@@ -37,18 +41,42 @@ func LoadAndGroupMedia(
 	total int,
 	threshold float64,
 	ignoreErrors bool,
+) <-chan LoadAndGroupResult {
+	return LoadAndGroupMediaWithOptions(channel, total, GroupOptions{
+		Threshold:    threshold,
+		IgnoreErrors: ignoreErrors,
+	})
+}
+
+// LoadAndGroupMediaWithOptions works like LoadAndGroupMedia, but accepts the full set of grouping options.
+//
+// # Parameters:
+//   - channel: A channel of Result[Media] from LoadMediaFromFiles or LoadMediaFromDirectory.
+//   - total: The expected total number of items (used for DSU pre-allocation).
+//   - options: The configuration options for grouping.
+//
+// # Returns:
+//   - A channel of LoadAndGroupResult messages reporting progress and the final result.
+func LoadAndGroupMediaWithOptions(
+	channel <-chan Result[Media],
+	total int,
+	options GroupOptions,
 ) <-chan LoadAndGroupResult {
 	out := make(chan LoadAndGroupResult)
 
 	go func() {
 		defer close(out)
 
+		start := time.Now()
 		media := make([]Media, 0, total)
 		d := dsu.NewDSU(total)
+		stats := GroupStats{}
+		failed := 0
 
 		for r := range channel {
 			if r.Err != nil {
-				if ignoreErrors {
+				if options.IgnoreErrors {
+					failed++
 					out <- LoadAndGroupResult{Err: r.Err}
 					continue
 				}
@@ -62,22 +90,34 @@ func LoadAndGroupMedia(
 			media = append(media, m)
 
 			// Compare against all previously loaded items.
+			previous := stats
 			for j := range i {
-				if CalculateSimilarity(media[j], m) >= threshold {
-					d.Union(i, j)
+				if stats.compare(media[j], m, options) {
+					stats.union(d, i, j)
 				}
 			}
 
+			notify(options.Observer, stats.comparisonEvent(m.Name, previous))
+
 			out <- LoadAndGroupResult{
 				Media:  &media[i],
 				Loaded: len(media),
 			}
 		}
 
-		groups := extractGroups(media, d)
+		groups := extractGroups(media, d, options.Order)
+		notify(options.Observer, FinishedEvent{
+			Loaded:   len(media),
+			Failed:   failed,
+			Groups:   len(groups),
+			Stats:    stats,
+			Duration: time.Since(start),
+		})
+
 		out <- LoadAndGroupResult{
 			Done:   true,
 			Groups: groups,
+			Stats:  stats,
 		}
 	}()
 
@@ -8,7 +8,6 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	. "github.com/vegidio/go-sak/types"
-	"github.com/vitali-fedulov/images4"
 )
 
 // feedChannel sends media items through a channel, simulating LoadMediaFromFiles.
@@ -50,9 +49,9 @@ func TestLoadAndGroupMedia(t *testing.T) {
 
 	t.Run("produces same groups as GroupMedia", func(t *testing.T) {
 		media := []Media{
-			{Name: "a.jpg", Type: "image", Width: 100, Height: 100, Size: 1000, frames: frames{framesOriginal: []images4.IconT{whiteIcon}}},
-			{Name: "b.jpg", Type: "image", Width: 100, Height: 100, Size: 500, frames: frames{framesOriginal: []images4.IconT{whiteIcon}}},
-			{Name: "c.jpg", Type: "image", Width: 100, Height: 100, Size: 800, frames: frames{framesOriginal: []images4.IconT{blackIcon}}},
+			{Name: "a.jpg", Type: "image", Width: 100, Height: 100, Size: 1000, frames: frames{icons: []icon{whiteIcon}}},
+			{Name: "b.jpg", Type: "image", Width: 100, Height: 100, Size: 500, frames: frames{icons: []icon{whiteIcon}}},
+			{Name: "c.jpg", Type: "image", Width: 100, Height: 100, Size: 800, frames: frames{icons: []icon{blackIcon}}},
 		}
 
 		// Two-pass result
@@ -80,7 +79,7 @@ func TestLoadAndGroupMedia(t *testing.T) {
 
 	t.Run("single item returns no groups", func(t *testing.T) {
 		media := []Media{
-			{Name: "alone.jpg", Type: "image", frames: frames{framesOriginal: []images4.IconT{whiteIcon}}},
+			{Name: "alone.jpg", Type: "image", frames: frames{icons: []icon{whiteIcon}}},
 		}
 
 		ch := feedChannel(media)
@@ -95,9 +94,9 @@ func TestLoadAndGroupMedia(t *testing.T) {
 		inputCh := make(chan Result[Media], 3)
 		go func() {
 			defer close(inputCh)
-			inputCh <- Result[Media]{Data: Media{Name: "a.jpg", Type: "image", frames: frames{framesOriginal: []images4.IconT{whiteIcon}}}}
+			inputCh <- Result[Media]{Data: Media{Name: "a.jpg", Type: "image", frames: frames{icons: []icon{whiteIcon}}}}
 			inputCh <- Result[Media]{Err: fmt.Errorf("bad file")}
-			inputCh <- Result[Media]{Data: Media{Name: "b.jpg", Type: "image", frames: frames{framesOriginal: []images4.IconT{whiteIcon}}}}
+			inputCh <- Result[Media]{Data: Media{Name: "b.jpg", Type: "image", frames: frames{icons: []icon{whiteIcon}}}}
 		}()
 
 		updateCh := LoadAndGroupMedia(inputCh, 3, 0.9, false)
@@ -111,9 +110,9 @@ func TestLoadAndGroupMedia(t *testing.T) {
 		inputCh := make(chan Result[Media], 3)
 		go func() {
 			defer close(inputCh)
-			inputCh <- Result[Media]{Data: Media{Name: "a.jpg", Type: "image", Width: 100, Height: 100, Size: 1000, frames: frames{framesOriginal: []images4.IconT{whiteIcon}}}}
+			inputCh <- Result[Media]{Data: Media{Name: "a.jpg", Type: "image", Width: 100, Height: 100, Size: 1000, frames: frames{icons: []icon{whiteIcon}}}}
 			inputCh <- Result[Media]{Err: fmt.Errorf("bad file")}
-			inputCh <- Result[Media]{Data: Media{Name: "b.jpg", Type: "image", Width: 100, Height: 100, Size: 500, frames: frames{framesOriginal: []images4.IconT{whiteIcon}}}}
+			inputCh <- Result[Media]{Data: Media{Name: "b.jpg", Type: "image", Width: 100, Height: 100, Size: 500, frames: frames{icons: []icon{whiteIcon}}}}
 		}()
 
 		updateCh := LoadAndGroupMedia(inputCh, 3, 0.9, true)
@@ -126,8 +125,8 @@ func TestLoadAndGroupMedia(t *testing.T) {
 
 	t.Run("threshold 0 groups everything together", func(t *testing.T) {
 		media := []Media{
-			{Name: "white.jpg", Type: "image", frames: frames{framesOriginal: []images4.IconT{whiteIcon}}},
-			{Name: "black.jpg", Type: "image", frames: frames{framesOriginal: []images4.IconT{blackIcon}}},
+			{Name: "white.jpg", Type: "image", frames: frames{icons: []icon{whiteIcon}}},
+			{Name: "black.jpg", Type: "image", frames: frames{icons: []icon{blackIcon}}},
 		}
 
 		ch := feedChannel(media)
@@ -141,9 +140,9 @@ func TestLoadAndGroupMedia(t *testing.T) {
 
 	t.Run("progress updates are sent for each loaded item", func(t *testing.T) {
 		media := []Media{
-			{Name: "a.jpg", Type: "image", frames: frames{framesOriginal: []images4.IconT{whiteIcon}}},
-			{Name: "b.jpg", Type: "image", frames: frames{framesOriginal: []images4.IconT{whiteIcon}}},
-			{Name: "c.jpg", Type: "image", frames: frames{framesOriginal: []images4.IconT{blackIcon}}},
+			{Name: "a.jpg", Type: "image", frames: frames{icons: []icon{whiteIcon}}},
+			{Name: "b.jpg", Type: "image", frames: frames{icons: []icon{whiteIcon}}},
+			{Name: "c.jpg", Type: "image", frames: frames{icons: []icon{blackIcon}}},
 		}
 
 		ch := feedChannel(media)
@@ -158,4 +157,28 @@ func TestLoadAndGroupMedia(t *testing.T) {
 
 		assert.Equal(t, []int{1, 2, 3}, loadedCounts)
 	})
+
+	t.Run("final message reports pruned pairs", func(t *testing.T) {
+		media := []Media{
+			{Name: "a.mp4", Type: "video", Length: 10, frames: frames{icons: []icon{whiteIcon, whiteIcon}}},
+			{Name: "b.mp4", Type: "video", Length: 12, frames: frames{icons: []icon{whiteIcon, whiteIcon}}},
+			{Name: "c.mp4", Type: "video", Length: 600, frames: frames{icons: []icon{whiteIcon, whiteIcon}}},
+		}
+
+		ch := feedChannel(media)
+		updateCh := LoadAndGroupMediaWithOptions(ch, len(media), GroupOptions{
+			Threshold: 0.9,
+			Prefilter: PrefilterOptions{MaxDurationRatio: 2},
+		})
+
+		var last LoadAndGroupResult
+		for update := range updateCh {
+			last = update
+		}
+
+		assert.True(t, last.Done)
+		assert.Len(t, last.Groups, 1)
+		assert.Equal(t, 1, last.Stats.Compared)
+		assert.Equal(t, 2, last.Stats.Pruned)
+	})
 }
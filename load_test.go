@@ -0,0 +1,337 @@
+package mediasim
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"testing/fstest"
+
+	"github.com/disintegration/imaging"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vitali-fedulov/images4"
+)
+
+func TestLoadMediaFromImages(t *testing.T) {
+	img := createGradientImage(160, 90)
+
+	t.Run("single image is loaded as image", func(t *testing.T) {
+		media := LoadMediaFromImages("a.jpg", []image.Image{img}, FrameOptions{})
+
+		assert.Equal(t, "image", media.Type)
+		assert.Equal(t, 160, media.Width)
+		assert.Equal(t, 90, media.Height)
+		assert.Equal(t, 0, media.Length)
+		assert.Len(t, media.icons, 1)
+	})
+
+	t.Run("multiple images are loaded as video", func(t *testing.T) {
+		media := LoadMediaFromImages("a.mp4", []image.Image{img, img, img}, FrameOptions{FrameFlip: true})
+
+		assert.Equal(t, "video", media.Type)
+		assert.Equal(t, 3, media.Length)
+		assert.Len(t, media.icons, 3)
+		assert.True(t, media.options.FrameFlip)
+	})
+
+	t.Run("variants are not stored in the media", func(t *testing.T) {
+		media := LoadMediaFromImages("a.mp4", []image.Image{img, img}, FrameOptions{FrameFlip: true, FrameRotate: true})
+
+		assert.Len(t, media.icons, 2)
+		assert.Len(t, media.transforms(), 6)
+	})
+}
+
+// encodePng encodes an image as PNG for testing.
+func encodePng(t testing.TB, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// encodeJpeg encodes an image as JPEG for testing.
+func encodeJpeg(t testing.TB, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// stubFFmpeg replaces the FFmpeg binary with a shell script that consumes stdin, saves it to a file, and writes a
+// single JPEG frame to the output path. It returns the path of the file where stdin is saved.
+func stubFFmpeg(t *testing.T, frame []byte) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the FFmpeg stub is a shell script")
+	}
+
+	dir := t.TempDir()
+	framePath := filepath.Join(dir, "frame.jpg")
+	stdinPath := filepath.Join(dir, "stdin.bin")
+	scriptPath := filepath.Join(dir, "ffmpeg")
+
+	if err := os.WriteFile(framePath, frame, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	script := fmt.Sprintf(`#!/bin/sh
+for last; do :; done
+cat > '%s'
+cp '%s' "$(printf "$last" 1)"
+`, stdinPath, framePath)
+
+	if err := os.WriteFile(scriptPath, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	SetFFmpegPath(scriptPath)
+	t.Cleanup(func() { SetFFmpegPath("") })
+
+	return stdinPath
+}
+
+func TestLoadMediaFromReader(t *testing.T) {
+	img := createGradientImage(64, 48)
+
+	t.Run("loads an image and measures its size", func(t *testing.T) {
+		content := encodePng(t, img)
+		media, err := LoadMediaFromReader("upload.png", bytes.NewReader(content), FrameOptions{})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "upload.png", media.Name)
+		assert.Equal(t, "image", media.Type)
+		assert.Equal(t, 64, media.Width)
+		assert.Equal(t, 48, media.Height)
+		assert.Equal(t, int64(len(content)), media.Size)
+	})
+
+	t.Run("extension is case insensitive", func(t *testing.T) {
+		media, err := LoadMediaFromReader("UPLOAD.JPG", bytes.NewReader(encodeJpeg(t, img)), FrameOptions{})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "image", media.Type)
+	})
+
+	t.Run("same content as a file produces the same score", func(t *testing.T) {
+		content := encodeJpeg(t, img)
+		path := filepath.Join(t.TempDir(), "file.jpg")
+		assert.NoError(t, os.WriteFile(path, content, 0o644))
+
+		fromFile, err := LoadMediaFromFile(path, FrameOptions{})
+		assert.NoError(t, err)
+		fromReader, err := LoadMediaFromReader("file.jpg", bytes.NewReader(content), FrameOptions{})
+		assert.NoError(t, err)
+
+		assert.Equal(t, 1.0, CalculateSimilarity(*fromFile, *fromReader))
+		assert.Equal(t, fromFile.Size, fromReader.Size)
+	})
+
+	t.Run("invalid content returns an error", func(t *testing.T) {
+		_, err := LoadMediaFromReader("broken.png", bytes.NewReader([]byte("not an image")), FrameOptions{})
+		assert.Error(t, err)
+	})
+
+	t.Run("unknown extension returns an error", func(t *testing.T) {
+		_, err := LoadMediaFromReader("notes.txt", bytes.NewReader(encodePng(t, img)), FrameOptions{})
+		assert.Error(t, err)
+	})
+
+	t.Run("videos are streamed to FFmpeg via stdin", func(t *testing.T) {
+		stdinPath := stubFFmpeg(t, encodeJpeg(t, img))
+		content := []byte("fake video content")
+
+		media, err := LoadMediaFromReader("clip.mp4", bytes.NewReader(content), FrameOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(len(content)), media.Size)
+		assert.Equal(t, 64, media.Width)
+
+		received, err := os.ReadFile(stdinPath)
+		assert.NoError(t, err)
+		assert.Equal(t, content, received)
+	})
+}
+
+func TestLoadMediaFromDirectory_FrameOptions(t *testing.T) {
+	content := encodePng(t, createGradientImage(64, 48))
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.png"), content, 0o644))
+	writeZip(t, filepath.Join(dir, "backup.zip"), map[string][]byte{"b.png": content})
+
+	flip := FrameOptions{FrameFlip: true}
+	ignored := LoadOptions{FrameOptions: FrameOptions{FrameRotate: true}}
+
+	t.Run("files are loaded with the frame options of the directory", func(t *testing.T) {
+		for _, archives := range []bool{false, true} {
+			ch, _ := LoadMediaFromDirectory(dir, DirectoryOptions{
+				IncludeArchives: archives,
+				FrameOptions:    flip,
+				LoadOptions:     ignored,
+			})
+
+			for r := range ch {
+				require.NoError(t, r.Err)
+				assert.Equal(t, flip, r.Data.frames.options, r.Data.Name)
+			}
+		}
+	})
+
+	t.Run("files are loaded with the frame options of the files", func(t *testing.T) {
+		ch := LoadMediaFromFiles([]string{filepath.Join(dir, "a.png")}, FilesOptions{
+			FrameOptions: flip,
+			LoadOptions:  ignored,
+		})
+
+		for r := range ch {
+			require.NoError(t, r.Err)
+			assert.Equal(t, flip, r.Data.frames.options)
+		}
+	})
+}
+
+func TestLoadMediaFromFS(t *testing.T) {
+	img := createGradientImage(64, 48)
+	fsys := fstest.MapFS{
+		"a.png":          {Data: encodePng(t, img)},
+		"b.jpg":          {Data: encodeJpeg(t, img)},
+		"notes.txt":      {Data: []byte("not media")},
+		"sub/c.png":      {Data: encodePng(t, img)},
+		"sub/deep/d.png": {Data: encodePng(t, img)},
+		"broken.png":     {Data: []byte("not an image")},
+	}
+
+	t.Run("loads media from the root of the file system", func(t *testing.T) {
+		ch, total := LoadMediaFromFS(fsys, ".", DirectoryOptions{})
+		names, errors := collectNames(ch)
+
+		assert.Equal(t, 3, total)
+		assert.ElementsMatch(t, []string{"a.png", "b.jpg"}, names)
+		assert.Equal(t, 1, errors)
+	})
+
+	t.Run("loads media recursively", func(t *testing.T) {
+		ch, total := LoadMediaFromFS(fsys, ".", DirectoryOptions{IsRecursive: true})
+		names, _ := collectNames(ch)
+
+		assert.Equal(t, 5, total)
+		assert.ElementsMatch(t, []string{"a.png", "b.jpg", "sub/c.png", "sub/deep/d.png"}, names)
+	})
+
+	t.Run("loads media from a subdirectory", func(t *testing.T) {
+		ch, total := LoadMediaFromFS(fsys, "sub", DirectoryOptions{})
+		names, _ := collectNames(ch)
+
+		assert.Equal(t, 1, total)
+		assert.Equal(t, []string{"sub/c.png"}, names)
+	})
+
+	t.Run("respects the media types", func(t *testing.T) {
+		ch, total := LoadMediaFromFS(fsys, ".", DirectoryOptions{IncludeVideos: true})
+		names, _ := collectNames(ch)
+
+		assert.Equal(t, 0, total)
+		assert.Empty(t, names)
+	})
+
+	t.Run("missing directory returns an error", func(t *testing.T) {
+		ch, total := LoadMediaFromFS(fsys, "missing", DirectoryOptions{})
+		_, errors := collectNames(ch)
+
+		assert.Equal(t, 0, total)
+		assert.Equal(t, 1, errors)
+	})
+
+	t.Run("loaded media can be grouped", func(t *testing.T) {
+		ch, total := LoadMediaFromFS(fsys, ".", DirectoryOptions{IsRecursive: true})
+		groups, err := collectGroups(t, LoadAndGroupMedia(ch, total, 0.9, true))
+
+		assert.NoError(t, err)
+		assert.Len(t, groups, 1)
+		assert.Len(t, groups[0], 4)
+	})
+}
+
+// eagerFrames replicates the previous representation, where all the variants were computed from the full images and
+// stored in every media, to compare the memory usage.
+func eagerFrames(images []image.Image) [][]images4.IconT {
+	return [][]images4.IconT{
+		lo.Map(images, func(img image.Image, _ int) images4.IconT { return images4.Icon(img) }),
+		lo.Map(images, func(img image.Image, _ int) images4.IconT { return images4.Icon(imaging.FlipH(img)) }),
+		lo.Map(images, func(img image.Image, _ int) images4.IconT { return images4.Icon(imaging.FlipV(img)) }),
+		lo.Map(images, func(img image.Image, _ int) images4.IconT { return images4.Icon(imaging.Rotate90(img)) }),
+		lo.Map(images, func(img image.Image, _ int) images4.IconT { return images4.Icon(imaging.Rotate180(img)) }),
+		lo.Map(images, func(img image.Image, _ int) images4.IconT { return images4.Icon(imaging.Rotate270(img)) }),
+	}
+}
+
+// retainedBytes measures the heap still in use by the result of fn, after the garbage collector frees the rest.
+func retainedBytes(fn func() any) float64 {
+	var before, after runtime.MemStats
+
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	result := fn()
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	runtime.KeepAlive(result)
+
+	return float64(int64(after.HeapAlloc) - int64(before.HeapAlloc))
+}
+
+// BenchmarkLoadMediaFromImages measures a 60-second video loaded with flip and rotate enabled. The "retained-B/op"
+// metric is the heap kept alive by each Media after loading, measured with runtime.ReadMemStats, which is what
+// LoadAndGroupMedia holds for every file.
+func BenchmarkLoadMediaFromImages(b *testing.B) {
+	images := make([]image.Image, 60)
+	for i := range images {
+		images[i] = createGradientImage(320, 180)
+	}
+
+	options := FrameOptions{FrameFlip: true, FrameRotate: true}
+
+	b.Run("eager", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			eagerFrames(images)
+		}
+
+		b.ReportMetric(retainedBytes(func() any { return eagerFrames(images) }), "retained-B/op")
+	})
+
+	b.Run("lazy", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			LoadMediaFromImages("video.mp4", images, options)
+		}
+
+		b.ReportMetric(retainedBytes(func() any { return LoadMediaFromImages("video.mp4", images, options) }),
+			"retained-B/op")
+	})
+}
+
+// BenchmarkCalculateSimilarity measures the cost of comparing the variants through the permutations of the icons.
+func BenchmarkCalculateSimilarity(b *testing.B) {
+	images := make([]image.Image, 30)
+	for i := range images {
+		images[i] = createGradientImage(320, 180)
+	}
+
+	media1 := LoadMediaFromImages("video1.mp4", images, FrameOptions{})
+	media2 := LoadMediaFromImages("video2.mp4", images, FrameOptions{FrameFlip: true, FrameRotate: true})
+
+	b.ReportAllocs()
+	for b.Loop() {
+		CalculateSimilarity(media1, media2)
+	}
+}
@@ -0,0 +1,45 @@
+package mediasim
+
+import (
+	"github.com/vegidio/go-sak/async"
+)
+
+// CalculateSimilarityMatrix computes the similarity score of every pair of media, in parallel.
+//
+// Each pair is compared once and the matrix is symmetric: the score of media i and media j is in matrix[i][j] and in
+// matrix[j][i]. The diagonal, where each media is compared with itself, is always 1.
+//
+// # Parameters:
+//   - media: The media to be compared.
+//   - options: The configuration options for the calculation.
+//
+// # Returns:
+//   - A square matrix with the similarity scores, in the same order as the media.
+func CalculateSimilarityMatrix(media []Media, options MatrixOptions) [][]float64 {
+	options.SetDefaults()
+
+	matrix := make([][]float64, len(media))
+	rows := make([]int, len(media))
+	for i := range media {
+		matrix[i] = make([]float64, len(media))
+		matrix[i][i] = 1
+		rows[i] = i
+	}
+
+	// Each row only has the pairs with the following media, so every pair is calculated once
+	done := async.SliceToChannel(rows, options.Parallel, func(i int) int {
+		for j := i + 1; j < len(media); j++ {
+			matrix[i][j] = CalculateSimilarity(media[i], media[j])
+		}
+
+		return i
+	})
+
+	for i := range done {
+		for j := i + 1; j < len(media); j++ {
+			matrix[j][i] = matrix[i][j]
+		}
+	}
+
+	return matrix
+}
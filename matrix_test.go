@@ -0,0 +1,41 @@
+package mediasim
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculateSimilarityMatrix(t *testing.T) {
+	whiteIcon := iconFromImage(createSolidImage(color.White, 100, 100))
+	blackIcon := iconFromImage(createSolidImage(color.Black, 100, 100))
+
+	media := []Media{
+		{Name: "white.jpg", Type: "image", frames: frames{icons: []icon{whiteIcon}}},
+		{Name: "black.jpg", Type: "image", frames: frames{icons: []icon{blackIcon}}},
+		{Name: "white-copy.jpg", Type: "image", frames: frames{icons: []icon{whiteIcon}}},
+		{Name: "clip.mp4", Type: "video", frames: frames{icons: []icon{whiteIcon}}},
+	}
+
+	matrix := CalculateSimilarityMatrix(media, MatrixOptions{Parallel: 2})
+
+	assert.Len(t, matrix, 4)
+	for i := range media {
+		assert.Len(t, matrix[i], 4)
+		assert.Equal(t, 1.0, matrix[i][i], "the diagonal is 1")
+
+		for j := range media {
+			assert.Equal(t, matrix[i][j], matrix[j][i], "the matrix is symmetric")
+			if i < j {
+				assert.Equal(t, CalculateSimilarity(media[i], media[j]), matrix[i][j])
+			}
+		}
+	}
+
+	assert.Equal(t, 1.0, matrix[0][2])
+	assert.Less(t, matrix[0][1], 0.5)
+	assert.Zero(t, matrix[0][3], "images and videos aren't similar")
+
+	assert.Empty(t, CalculateSimilarityMatrix(nil, MatrixOptions{}))
+}
@@ -1,18 +1,32 @@
 package mediasim
 
 import (
+	"bytes"
+	"encoding/gob"
 	"fmt"
-
-	"github.com/vitali-fedulov/images4"
 )
 
+// frames holds the icons of the original frames of a media. The flipped and rotated variants are not stored; they are
+// compared by reading the original icons in another order, according to the frame options used to load the media.
 type frames struct {
-	framesOriginal   []images4.IconT
-	framesFlippedV   []images4.IconT
-	framesFlippedH   []images4.IconT
-	framesRotated90  []images4.IconT
-	framesRotated180 []images4.IconT
-	framesRotated270 []images4.IconT
+	icons   []icon
+	options FrameOptions
+}
+
+// transforms returns the transformations of the variants enabled in the frame options, starting with TransformNone for
+// the original icons. The variants are compared through the permutations of the icons, without copying them.
+func (f frames) transforms() []Transform {
+	result := []Transform{TransformNone}
+
+	if f.options.FrameFlip {
+		result = append(result, TransformFlipVertical, TransformFlipHorizontal)
+	}
+
+	if f.options.FrameRotate {
+		result = append(result, TransformRotate90, TransformRotate180, TransformRotate270)
+	}
+
+	return result
 }
 
 // Media represents a media object.
@@ -31,6 +45,9 @@ type Media struct {
 	Size int64 `json:"size"`
 	// Length represents the duration of the media in seconds (for images this is always 0)
 	Length int `json:"length"`
+	// ContentExt is the extension of the format detected from the content, when content detection is enabled and it
+	// doesn't match the extension of the name (e.g., a JPEG file named "photo.png"); empty otherwise.
+	ContentExt string `json:"contentExt,omitempty"`
 }
 
 func (m Media) String() string {
@@ -38,6 +55,17 @@ func (m Media) String() string {
 		m.Name, m.Type, m.Width, m.Height, m.Size, m.Length)
 }
 
+// HasExtMismatch reports whether the extension of the media doesn't match its content. It's only detected when the media
+// is loaded with content detection enabled.
+func (m Media) HasExtMismatch() bool {
+	return m.ContentExt != ""
+}
+
+// isRotatable reports whether the media was loaded with rotated frames.
+func (m Media) isRotatable() bool {
+	return m.options.FrameRotate
+}
+
 func (m Media) Equal(other Media) bool {
 	return m.Name == other.Name &&
 		m.Type == other.Type &&
@@ -46,3 +74,64 @@ func (m Media) Equal(other Media) bool {
 		m.Size == other.Size &&
 		m.Length == other.Length
 }
+
+// MarshalBinary encodes the media with its fingerprint, so it can be stored (e.g., in an index of a directory) and
+// compared later without loading the file again.
+func (m Media) MarshalBinary() ([]byte, error) {
+	var buffer bytes.Buffer
+	record := mediaRecord{
+		Name:         m.Name,
+		Type:         m.Type,
+		Width:        m.Width,
+		Height:       m.Height,
+		Size:         m.Size,
+		Length:       m.Length,
+		ContentExt:   m.ContentExt,
+		Icons:        m.icons,
+		FrameOptions: m.options,
+	}
+
+	if err := gob.NewEncoder(&buffer).Encode(record); err != nil {
+		return nil, fmt.Errorf("failed to encode the media %s: %w", m.Name, err)
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a media encoded by MarshalBinary.
+func (m *Media) UnmarshalBinary(data []byte) error {
+	var record mediaRecord
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&record); err != nil {
+		return fmt.Errorf("failed to decode the media: %w", err)
+	}
+
+	*m = Media{
+		frames:     frames{icons: record.Icons, options: record.FrameOptions},
+		Name:       record.Name,
+		Type:       record.Type,
+		Width:      record.Width,
+		Height:     record.Height,
+		Size:       record.Size,
+		Length:     record.Length,
+		ContentExt: record.ContentExt,
+	}
+
+	return nil
+}
+
+// region - Private functions
+
+// mediaRecord is how a media is encoded by MarshalBinary.
+type mediaRecord struct {
+	Name         string
+	Type         string
+	Width        int
+	Height       int
+	Size         int64
+	Length       int
+	ContentExt   string
+	Icons        []icon
+	FrameOptions FrameOptions
+}
+
+// endregion
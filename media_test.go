@@ -1,9 +1,11 @@
 package mediasim
 
 import (
+	"image"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestMedia_String(t *testing.T) {
@@ -75,3 +77,20 @@ func TestMedia_Equal(t *testing.T) {
 		assert.False(t, base.Equal(other))
 	})
 }
+
+func TestMedia_MarshalBinary(t *testing.T) {
+	media := LoadMediaFromImages("a.jpg", []image.Image{createGradientImage(64, 48)}, FrameOptions{FrameFlip: true})
+	media.Size = 1234
+	media.ContentExt = ".png"
+
+	data, err := media.MarshalBinary()
+	require.NoError(t, err)
+
+	var decoded Media
+	require.NoError(t, decoded.UnmarshalBinary(data))
+
+	assert.Equal(t, media, decoded)
+	assert.Equal(t, 1.0, CalculateSimilarity(media, decoded))
+
+	assert.Error(t, decoded.UnmarshalBinary([]byte("invalid")))
+}
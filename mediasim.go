@@ -2,11 +2,10 @@ package mediasim
 
 import (
 	"math"
-	"slices"
+	"time"
 
 	"github.com/vegidio/mediasim/internal/dsu"
 	idtw "github.com/vegidio/mediasim/internal/dtw"
-	"github.com/vitali-fedulov/images4"
 )
 
 // maxDifference is the maximum numeric difference when comparing two images:
@@ -16,37 +15,41 @@ const maxDifference = 2804
 // CalculateSimilarity computes a similarity score between two Media objects.
 // Returns a value between 0 and 1, where higher values indicate greater similarity.
 func CalculateSimilarity(media1, media2 Media) float64 {
-	frameGroup := slices.DeleteFunc([][]images4.IconT{
-		media2.framesOriginal,
-		media2.framesFlippedV,
-		media2.framesFlippedH,
-		media2.framesRotated90,
-		media2.framesRotated180,
-		media2.framesRotated270,
-	}, func(f []images4.IconT) bool {
-		return len(f) == 0
-	})
+	if len(media1.icons) == 0 || len(media2.icons) == 0 {
+		return 0
+	}
 
 	similarity := 0.0
 
 	if media1.Type == "image" && media2.Type == "image" {
-		for _, frames := range frameGroup {
-			similarity = max(similarity, calculateImageSimilarity(media1.framesOriginal[0], frames[0]))
+		for _, t := range media2.transforms() {
+			similarity = max(similarity, calculateImageSimilarity(&media1.icons[0], &media2.icons[0], t))
 		}
 	} else if media1.Type == "video" && media2.Type == "video" {
-		for _, frames := range frameGroup {
-			similarity = max(similarity, calculateVideoSimilarity(media1.framesOriginal, frames))
+		for _, t := range media2.transforms() {
+			similarity = max(similarity, calculateVideoSimilarity(media1.icons, media2.icons, t))
 		}
 	}
 
 	return similarity
 }
 
+// GroupStats holds statistics about a grouping run.
+type GroupStats struct {
+	// Compared is the number of pairs whose similarity was calculated.
+	Compared int `json:"compared"`
+	// Pruned is the number of pairs skipped by the prefilters, without calculating their similarity.
+	Pruned int `json:"pruned"`
+	// Unions is the number of times two groups were merged because a pair was similar.
+	Unions int `json:"unions"`
+}
+
 // GroupMedia organizes a list of media objects into groups based on a similarity threshold.
 //
 // It uses a Disjoint Set Union (DSU) to cluster media items whose pairwise similarity score meets or exceeds the given
 // threshold. Within each group (of at least two items), media are sorted by quality, prioritizing length, then
-// resolution, then file size.
+// resolution, then file size, then name. The groups are sorted by the name of their best media (see SortGroups), so
+// the result is the same in every run with the same input.
 //
 // # Parameters:
 //   - media: []Media Slice of Media objects to be grouped.
@@ -56,23 +59,52 @@ func CalculateSimilarity(media1, media2 Media) float64 {
 //   - [][]Media A two-dimensional slice where each inner slice represents a group of media items (minimum length of 2),
 //     sorted by quality descending.
 func GroupMedia(media []Media, threshold float64) [][]Media {
+	groups, _ := GroupMediaWithOptions(media, GroupOptions{Threshold: threshold})
+	return groups
+}
+
+// GroupMediaWithOptions works like GroupMedia, but accepts the full set of grouping options and also returns the
+// statistics of the run.
+//
+// # Parameters:
+//   - media: []Media Slice of Media objects to be grouped.
+//   - options: GroupOptions The configuration options for grouping.
+//
+// # Returns:
+//   - [][]Media A two-dimensional slice where each inner slice represents a group of media items (minimum length of 2),
+//     sorted by quality descending.
+//   - GroupStats The number of pairs compared and pruned.
+func GroupMediaWithOptions(media []Media, options GroupOptions) ([][]Media, GroupStats) {
+	start := time.Now()
 	size := len(media)
 	d := dsu.NewDSU(size)
+	stats := GroupStats{}
 
 	for i := 0; i < size; i++ {
+		previous := stats
+
 		for j := i + 1; j < size; j++ {
-			similarity := CalculateSimilarity(media[i], media[j])
-			if similarity >= threshold {
-				d.Union(i, j)
+			if stats.compare(media[i], media[j], options) {
+				stats.union(d, i, j)
 			}
 		}
+
+		notify(options.Observer, stats.comparisonEvent(media[i].Name, previous))
 	}
 
-	return extractGroups(media, d)
+	groups := extractGroups(media, d, options.Order)
+	notify(options.Observer, FinishedEvent{
+		Loaded:   size,
+		Groups:   len(groups),
+		Stats:    stats,
+		Duration: time.Since(start),
+	})
+
+	return groups, stats
 }
 
-// extractGroups builds groups from a DSU, keeping only groups with 2+ items, sorted by quality.
-func extractGroups(media []Media, d *dsu.DSU) [][]Media {
+// extractGroups builds groups from a DSU, keeping only groups with 2+ items, sorted by quality, in the given order.
+func extractGroups(media []Media, d *dsu.DSU, order GroupOrder) [][]Media {
 	groups := make([][]Media, 0)
 	groupsMap := make(map[int][]Media)
 
@@ -83,31 +115,45 @@ func extractGroups(media []Media, d *dsu.DSU) [][]Media {
 
 	for _, m := range groupsMap {
 		if len(m) >= 2 {
-			slices.SortFunc(m, func(a, b Media) int {
-				if a.Length != b.Length {
-					return b.Length - a.Length
-				}
-
-				mp1 := a.Width * a.Height
-				mp2 := b.Width * b.Height
-				if mp1 != mp2 {
-					return mp2 - mp1
-				}
-
-				return int(b.Size - a.Size)
-			})
-
+			sortByQuality(m)
 			groups = append(groups, m)
 		}
 	}
 
+	SortGroups(groups, order)
 	return groups
 }
 
 // region - Private functions
 
-func calculateImageSimilarity(frame1 images4.IconT, frame2 images4.IconT) float64 {
-	m1, m2, m3 := images4.EucMetric(frame1, frame2)
+// compare reports whether two media are similar enough to be grouped, updating the statistics along the way.
+func (s *GroupStats) compare(media1, media2 Media, options GroupOptions) bool {
+	score, ok := s.score(media1, media2, options.Prefilter)
+	return ok && score >= options.Threshold
+}
+
+// union merges the groups of i and j, counting it if they were different groups.
+func (s *GroupStats) union(d *dsu.DSU, i, j int) {
+	if d.Union(i, j) {
+		s.Unions++
+	}
+}
+
+// score calculates the similarity between two media, unless the pair is pruned by the prefilters, in which case the
+// last value is false. The statistics are updated along the way.
+func (s *GroupStats) score(media1, media2 Media, prefilter PrefilterOptions) (float64, bool) {
+	if !prefilter.Accept(media1, media2) {
+		s.Pruned++
+		return 0, false
+	}
+
+	s.Compared++
+	return CalculateSimilarity(media1, media2), true
+}
+
+// calculateImageSimilarity compares the frames, with the transformation applied to the second frame.
+func calculateImageSimilarity(frame1, frame2 *icon, transform Transform) float64 {
+	m1, m2, m3 := eucMetric(frame1, frame2, transform)
 
 	// m1 is the lumen, in other words, what makes easy to identify the form and shape in the image, so this value
 	// is the most important doing the similarity comparison. The other values, m2 and m3, are the colors, which are
@@ -117,20 +163,21 @@ func calculateImageSimilarity(frame1 images4.IconT, frame2 images4.IconT) float6
 	return 1 - difference
 }
 
-func calculateVideoSimilarity(frames1, frames2 []images4.IconT) float64 {
+// calculateVideoSimilarity compares the frames of the videos, with the transformation applied to the second video.
+func calculateVideoSimilarity(frames1, frames2 []icon, transform Transform) float64 {
 	matrix := make([][]float64, len(frames1))
 
 	// Dynamic Time Warping (DTW) is used to measure the similarity of videos. It does that by creating a matrix
 	// measuring the image similarity of every frame with the other frames of the opposing video and calculating the
 	// shortest path to traverse the matrix.
-	for i, f1 := range frames1 {
+	for i := range frames1 {
 		// Pre-allocate each row to avoid repeated append reallocations.
 		matrix[i] = make([]float64, len(frames2))
 
-		for j, f2 := range frames2 {
+		for j := range frames2 {
 			// We are using the inverted similarity here (in other words, the difference) because DTW uses the shortest
 			// path to traverse the matrix.
-			matrix[i][j] = 1 - calculateImageSimilarity(f1, f2)
+			matrix[i][j] = 1 - calculateImageSimilarity(&frames1[i], &frames2[j], transform)
 		}
 	}
 
@@ -5,6 +5,7 @@ import (
 	"image/color"
 	"testing"
 
+	"github.com/disintegration/imaging"
 	"github.com/stretchr/testify/assert"
 	"github.com/vitali-fedulov/images4"
 )
@@ -20,9 +21,24 @@ func createSolidImage(c color.Color, w, h int) image.Image {
 	return img
 }
 
-// iconFromImage converts an image.Image to an images4.IconT for testing.
-func iconFromImage(img image.Image) images4.IconT {
-	return images4.Icon(img)
+// iconFromImage converts an image.Image to an icon for testing.
+func iconFromImage(img image.Image) icon {
+	return newIcon(images4.Icon(img))
+}
+
+// createHalfImage creates an image whose left half is white and right half is black.
+func createHalfImage(w, h int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if x < w/2 {
+				img.Set(x, y, color.White)
+			} else {
+				img.Set(x, y, color.Black)
+			}
+		}
+	}
+	return img
 }
 
 func TestCalculateSimilarity(t *testing.T) {
@@ -32,24 +48,24 @@ func TestCalculateSimilarity(t *testing.T) {
 	blackIcon := iconFromImage(blackImg)
 
 	t.Run("identical images have similarity of 1", func(t *testing.T) {
-		m1 := Media{Type: "image", frames: frames{framesOriginal: []images4.IconT{whiteIcon}}}
-		m2 := Media{Type: "image", frames: frames{framesOriginal: []images4.IconT{whiteIcon}}}
+		m1 := Media{Type: "image", frames: frames{icons: []icon{whiteIcon}}}
+		m2 := Media{Type: "image", frames: frames{icons: []icon{whiteIcon}}}
 
 		score := CalculateSimilarity(m1, m2)
 		assert.Equal(t, 1.0, score)
 	})
 
 	t.Run("very different images have low similarity", func(t *testing.T) {
-		m1 := Media{Type: "image", frames: frames{framesOriginal: []images4.IconT{whiteIcon}}}
-		m2 := Media{Type: "image", frames: frames{framesOriginal: []images4.IconT{blackIcon}}}
+		m1 := Media{Type: "image", frames: frames{icons: []icon{whiteIcon}}}
+		m2 := Media{Type: "image", frames: frames{icons: []icon{blackIcon}}}
 
 		score := CalculateSimilarity(m1, m2)
 		assert.Less(t, score, 0.5)
 	})
 
 	t.Run("similarity is between 0 and 1", func(t *testing.T) {
-		m1 := Media{Type: "image", frames: frames{framesOriginal: []images4.IconT{whiteIcon}}}
-		m2 := Media{Type: "image", frames: frames{framesOriginal: []images4.IconT{blackIcon}}}
+		m1 := Media{Type: "image", frames: frames{icons: []icon{whiteIcon}}}
+		m2 := Media{Type: "image", frames: frames{icons: []icon{blackIcon}}}
 
 		score := CalculateSimilarity(m1, m2)
 		assert.GreaterOrEqual(t, score, 0.0)
@@ -57,39 +73,53 @@ func TestCalculateSimilarity(t *testing.T) {
 	})
 
 	t.Run("mixed types return zero similarity", func(t *testing.T) {
-		m1 := Media{Type: "image", frames: frames{framesOriginal: []images4.IconT{whiteIcon}}}
-		m2 := Media{Type: "video", frames: frames{framesOriginal: []images4.IconT{whiteIcon}}}
+		m1 := Media{Type: "image", frames: frames{icons: []icon{whiteIcon}}}
+		m2 := Media{Type: "video", frames: frames{icons: []icon{whiteIcon}}}
 
 		score := CalculateSimilarity(m1, m2)
 		assert.Equal(t, 0.0, score)
 	})
 
 	t.Run("identical video frames have high similarity", func(t *testing.T) {
-		icons := []images4.IconT{whiteIcon, whiteIcon, whiteIcon}
-		m1 := Media{Type: "video", frames: frames{framesOriginal: icons}}
-		m2 := Media{Type: "video", frames: frames{framesOriginal: icons}}
+		icons := []icon{whiteIcon, whiteIcon, whiteIcon}
+		m1 := Media{Type: "video", frames: frames{icons: icons}}
+		m2 := Media{Type: "video", frames: frames{icons: icons}}
 
 		score := CalculateSimilarity(m1, m2)
 		assert.Equal(t, 1.0, score)
 	})
 
 	t.Run("uses flipped frames for higher similarity", func(t *testing.T) {
-		grayImg := createSolidImage(color.Gray{Y: 128}, 100, 100)
-		grayIcon := iconFromImage(grayImg)
-
-		m1 := Media{Type: "image", frames: frames{framesOriginal: []images4.IconT{whiteIcon}}}
-		m2 := Media{Type: "image", frames: frames{
-			framesOriginal: []images4.IconT{blackIcon},
-			framesFlippedH: []images4.IconT{grayIcon},
-		}}
+		halfImg := createHalfImage(100, 100)
+		halfIcon := iconFromImage(halfImg)
+		flippedIcon := iconFromImage(imaging.FlipH(halfImg))
 
+		m1 := Media{Type: "image", frames: frames{icons: []icon{halfIcon}}}
+		m2 := Media{Type: "image", frames: frames{icons: []icon{flippedIcon}, options: FrameOptions{FrameFlip: true}}}
 		scoreWithFlip := CalculateSimilarity(m1, m2)
 
-		m3 := Media{Type: "image", frames: frames{framesOriginal: []images4.IconT{blackIcon}}}
+		m3 := Media{Type: "image", frames: frames{icons: []icon{flippedIcon}}}
+		scoreWithout := CalculateSimilarity(m1, m3)
+
+		// The flipped variant of the second image is the first image itself
+		assert.Greater(t, scoreWithFlip, scoreWithout)
+		assert.InDelta(t, 1.0, scoreWithFlip, 0.01)
+	})
+
+	t.Run("uses rotated frames for higher similarity", func(t *testing.T) {
+		halfImg := createHalfImage(100, 100)
+		halfIcon := iconFromImage(halfImg)
+		rotatedIcon := iconFromImage(imaging.Rotate90(halfImg))
+
+		m1 := Media{Type: "image", frames: frames{icons: []icon{halfIcon}}}
+		m2 := Media{Type: "image", frames: frames{icons: []icon{rotatedIcon}, options: FrameOptions{FrameRotate: true}}}
+		scoreWithRotation := CalculateSimilarity(m1, m2)
+
+		m3 := Media{Type: "image", frames: frames{icons: []icon{rotatedIcon}}}
 		scoreWithout := CalculateSimilarity(m1, m3)
 
-		// The flipped version (gray) should be closer to white than black
-		assert.GreaterOrEqual(t, scoreWithFlip, scoreWithout)
+		assert.Greater(t, scoreWithRotation, scoreWithout)
+		assert.InDelta(t, 1.0, scoreWithRotation, 0.01)
 	})
 }
 
@@ -101,8 +131,8 @@ func TestGroupMedia(t *testing.T) {
 
 	t.Run("identical media are grouped together", func(t *testing.T) {
 		media := []Media{
-			{Name: "a.jpg", Type: "image", Width: 100, Height: 100, Size: 1000, frames: frames{framesOriginal: []images4.IconT{whiteIcon}}},
-			{Name: "b.jpg", Type: "image", Width: 100, Height: 100, Size: 500, frames: frames{framesOriginal: []images4.IconT{whiteIcon}}},
+			{Name: "a.jpg", Type: "image", Width: 100, Height: 100, Size: 1000, frames: frames{icons: []icon{whiteIcon}}},
+			{Name: "b.jpg", Type: "image", Width: 100, Height: 100, Size: 500, frames: frames{icons: []icon{whiteIcon}}},
 		}
 
 		groups := GroupMedia(media, 0.9)
@@ -112,8 +142,8 @@ func TestGroupMedia(t *testing.T) {
 
 	t.Run("dissimilar media are not grouped", func(t *testing.T) {
 		media := []Media{
-			{Name: "white.jpg", Type: "image", frames: frames{framesOriginal: []images4.IconT{whiteIcon}}},
-			{Name: "black.jpg", Type: "image", frames: frames{framesOriginal: []images4.IconT{blackIcon}}},
+			{Name: "white.jpg", Type: "image", frames: frames{icons: []icon{whiteIcon}}},
+			{Name: "black.jpg", Type: "image", frames: frames{icons: []icon{blackIcon}}},
 		}
 
 		groups := GroupMedia(media, 0.9)
@@ -122,8 +152,8 @@ func TestGroupMedia(t *testing.T) {
 
 	t.Run("groups are sorted by quality - resolution", func(t *testing.T) {
 		media := []Media{
-			{Name: "small.jpg", Type: "image", Width: 100, Height: 100, frames: frames{framesOriginal: []images4.IconT{whiteIcon}}},
-			{Name: "large.jpg", Type: "image", Width: 1920, Height: 1080, frames: frames{framesOriginal: []images4.IconT{whiteIcon}}},
+			{Name: "small.jpg", Type: "image", Width: 100, Height: 100, frames: frames{icons: []icon{whiteIcon}}},
+			{Name: "large.jpg", Type: "image", Width: 1920, Height: 1080, frames: frames{icons: []icon{whiteIcon}}},
 		}
 
 		groups := GroupMedia(media, 0.9)
@@ -133,8 +163,8 @@ func TestGroupMedia(t *testing.T) {
 
 	t.Run("groups are sorted by quality - file size as tiebreaker", func(t *testing.T) {
 		media := []Media{
-			{Name: "small.jpg", Type: "image", Width: 100, Height: 100, Size: 500, frames: frames{framesOriginal: []images4.IconT{whiteIcon}}},
-			{Name: "big.jpg", Type: "image", Width: 100, Height: 100, Size: 5000, frames: frames{framesOriginal: []images4.IconT{whiteIcon}}},
+			{Name: "small.jpg", Type: "image", Width: 100, Height: 100, Size: 500, frames: frames{icons: []icon{whiteIcon}}},
+			{Name: "big.jpg", Type: "image", Width: 100, Height: 100, Size: 5000, frames: frames{icons: []icon{whiteIcon}}},
 		}
 
 		groups := GroupMedia(media, 0.9)
@@ -144,7 +174,7 @@ func TestGroupMedia(t *testing.T) {
 
 	t.Run("single item is not returned as a group", func(t *testing.T) {
 		media := []Media{
-			{Name: "alone.jpg", Type: "image", frames: frames{framesOriginal: []images4.IconT{whiteIcon}}},
+			{Name: "alone.jpg", Type: "image", frames: frames{icons: []icon{whiteIcon}}},
 		}
 
 		groups := GroupMedia(media, 0.5)
@@ -158,8 +188,8 @@ func TestGroupMedia(t *testing.T) {
 
 	t.Run("threshold of 0 groups everything together", func(t *testing.T) {
 		media := []Media{
-			{Name: "white.jpg", Type: "image", frames: frames{framesOriginal: []images4.IconT{whiteIcon}}},
-			{Name: "black.jpg", Type: "image", frames: frames{framesOriginal: []images4.IconT{blackIcon}}},
+			{Name: "white.jpg", Type: "image", frames: frames{icons: []icon{whiteIcon}}},
+			{Name: "black.jpg", Type: "image", frames: frames{icons: []icon{blackIcon}}},
 		}
 
 		groups := GroupMedia(media, 0.0)
@@ -0,0 +1,193 @@
+package mediasim
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Event is a progress event sent to an Observer. It's one of ListingEvent, FileStartedEvent, FileFinishedEvent,
+// ComparisonEvent or FinishedEvent.
+type Event interface {
+	isEvent()
+}
+
+// ListingEvent reports the progress of listing the files of a directory.
+type ListingEvent struct {
+	// Directory is the directory being listed.
+	Directory string
+	// Found is the number of media files found so far.
+	Found int
+	// Done is true when the listing has finished; Found is then the total number of files.
+	Done bool
+}
+
+// FileStartedEvent reports that a media started loading.
+type FileStartedEvent struct {
+	// Name is the name of the media; e.g., its file path.
+	Name string
+}
+
+// FileFinishedEvent reports that a media finished loading, successfully or not.
+type FileFinishedEvent struct {
+	// Name is the name of the media; e.g., its file path.
+	Name string
+	// Type is the type of the media ("image" or "video"); empty when it failed.
+	Type string
+	// Bytes is the size of the media; 0 when it failed.
+	Bytes int64
+	// Duration is the time it took to load (read and decode) the media.
+	Duration time.Duration
+	// Err is non-nil if the media failed to load.
+	Err error
+}
+
+// ComparisonEvent reports the comparisons made when a media was added to the grouping.
+type ComparisonEvent struct {
+	// Name is the name of the media that was compared with the previous ones.
+	Name string
+	// Compared is the number of pairs whose similarity was calculated in this step.
+	Compared int
+	// Pruned is the number of pairs skipped by the prefilters in this step.
+	Pruned int
+	// Unions is the number of groups merged in this step.
+	Unions int
+	// Stats contains the totals so far.
+	Stats GroupStats
+}
+
+// FinishedEvent reports the end of a grouping run.
+type FinishedEvent struct {
+	// Loaded is the number of media grouped.
+	Loaded int
+	// Failed is the number of media that failed to load and were skipped.
+	Failed int
+	// Groups is the number of groups found.
+	Groups int
+	// Stats contains the number of pairs compared and pruned, and the number of unions made.
+	Stats GroupStats
+	// Duration is the time the whole run took.
+	Duration time.Duration
+}
+
+func (ListingEvent) isEvent()      {}
+func (FileStartedEvent) isEvent()  {}
+func (FileFinishedEvent) isEvent() {}
+func (ComparisonEvent) isEvent()   {}
+func (FinishedEvent) isEvent()     {}
+
+// Observer receives the progress events of loading and grouping media.
+//
+// Files are loaded in parallel, so OnEvent may be called concurrently from different goroutines and must be safe for
+// concurrent use. It's called synchronously, so it should return quickly.
+type Observer interface {
+	OnEvent(event Event)
+}
+
+// ObserverFunc is an adapter to allow the use of ordinary functions as observers.
+type ObserverFunc func(event Event)
+
+// OnEvent calls f(event).
+func (f ObserverFunc) OnEvent(event Event) {
+	f(event)
+}
+
+// MultiObserver creates an observer that sends every event to all the given observers, in order. Nil observers are
+// ignored.
+//
+// # Parameters:
+//   - observers: The observers that will receive the events.
+//
+// # Returns:
+//   - An Observer that forwards the events to all the observers.
+func MultiObserver(observers ...Observer) Observer {
+	return ObserverFunc(func(event Event) {
+		for _, observer := range observers {
+			notify(observer, event)
+		}
+	})
+}
+
+// NewSlogObserver creates an observer that logs the events with the given logger. The end of a run is logged at info
+// level, failed files at warn level, and the other events at debug level.
+//
+// # Parameters:
+//   - logger: The logger to write the events to; nil means slog.Default().
+//
+// # Returns:
+//   - An Observer that logs every event.
+func NewSlogObserver(logger *slog.Logger) Observer {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	ctx := context.Background()
+
+	return ObserverFunc(func(event Event) {
+		switch e := event.(type) {
+		case ListingEvent:
+			logger.DebugContext(ctx, "listing files", "directory", e.Directory, "found", e.Found, "done", e.Done)
+		case FileStartedEvent:
+			logger.DebugContext(ctx, "loading file", "name", e.Name)
+		case FileFinishedEvent:
+			if e.Err != nil {
+				logger.WarnContext(ctx, "file failed", "name", e.Name, "duration", e.Duration, "error", e.Err)
+				return
+			}
+
+			logger.DebugContext(ctx, "file loaded",
+				"name", e.Name, "type", e.Type, "bytes", e.Bytes, "duration", e.Duration)
+		case ComparisonEvent:
+			logger.DebugContext(ctx, "media compared",
+				"name", e.Name, "compared", e.Compared, "pruned", e.Pruned, "unions", e.Unions)
+		case FinishedEvent:
+			logger.InfoContext(ctx, "grouping finished",
+				"loaded", e.Loaded, "failed", e.Failed, "groups", e.Groups,
+				"compared", e.Stats.Compared, "pruned", e.Stats.Pruned, "unions", e.Stats.Unions,
+				"duration", e.Duration)
+		}
+	})
+}
+
+// region - Private functions
+
+// notify sends the event to the observer, if there's one.
+func notify(observer Observer, event Event) {
+	if observer != nil {
+		observer.OnEvent(event)
+	}
+}
+
+// observeLoad loads a media with the load function, sending the started and finished events to the observer.
+func observeLoad(observer Observer, name string, load func() (*Media, error)) (*Media, error) {
+	if observer == nil {
+		return load()
+	}
+
+	observer.OnEvent(FileStartedEvent{Name: name})
+	start := time.Now()
+
+	media, err := load()
+
+	finished := FileFinishedEvent{Name: name, Duration: time.Since(start), Err: err}
+	if err == nil {
+		finished.Type = media.Type
+		finished.Bytes = media.Size
+	}
+
+	observer.OnEvent(finished)
+	return media, err
+}
+
+// comparisonEvent returns the event reporting the comparisons made since the previous statistics.
+func (s GroupStats) comparisonEvent(name string, previous GroupStats) ComparisonEvent {
+	return ComparisonEvent{
+		Name:     name,
+		Compared: s.Compared - previous.Compared,
+		Pruned:   s.Pruned - previous.Pruned,
+		Unions:   s.Unions - previous.Unions,
+		Stats:    s,
+	}
+}
+
+// endregion
@@ -0,0 +1,174 @@
+package mediasim
+
+import (
+	"bytes"
+	"image/color"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder is an observer that keeps all the events it receives.
+type recorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *recorder) OnEvent(event Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+// ofType returns the recorded events of type T.
+func ofType[T Event](r *recorder) []T {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	events := make([]T, 0)
+	for _, event := range r.events {
+		if e, ok := event.(T); ok {
+			events = append(events, e)
+		}
+	}
+
+	return events
+}
+
+func TestObserver_Grouping(t *testing.T) {
+	whiteIcon := iconFromImage(createSolidImage(color.White, 100, 100))
+	blackIcon := iconFromImage(createSolidImage(color.Black, 100, 100))
+
+	media := []Media{
+		{Name: "a.jpg", Type: "image", Width: 100, Height: 100, frames: frames{icons: []icon{whiteIcon}}},
+		{Name: "b.jpg", Type: "image", Width: 100, Height: 100, frames: frames{icons: []icon{whiteIcon}}},
+		{Name: "c.jpg", Type: "image", Width: 100, Height: 100, frames: frames{icons: []icon{whiteIcon}}},
+		{Name: "d.jpg", Type: "image", Width: 400, Height: 100, frames: frames{icons: []icon{blackIcon}}},
+		{Name: "e.mp4", Type: "video", Width: 100, Height: 100, frames: frames{icons: []icon{blackIcon}}},
+	}
+
+	t.Run("GroupMediaWithOptions reports comparisons and the end of the run", func(t *testing.T) {
+		r := &recorder{}
+		groups, stats := GroupMediaWithOptions(media, GroupOptions{
+			Threshold: 0.9,
+			Prefilter: PrefilterOptions{MaxAspectDistance: 0.5},
+			Observer:  r,
+		})
+
+		comparisons := ofType[ComparisonEvent](r)
+		require.Len(t, comparisons, len(media))
+		assert.Equal(t, "a.jpg", comparisons[0].Name)
+		assert.Equal(t, stats, comparisons[len(comparisons)-1].Stats)
+
+		compared, pruned, unions := 0, 0, 0
+		for _, e := range comparisons {
+			compared += e.Compared
+			pruned += e.Pruned
+			unions += e.Unions
+		}
+
+		assert.Equal(t, stats.Compared, compared)
+		assert.Equal(t, stats.Pruned, pruned)
+		assert.Equal(t, stats.Unions, unions)
+
+		// a, b and c are merged with 2 unions, even though 3 pairs are similar; d has a different aspect ratio
+		assert.Equal(t, 2, stats.Unions)
+		assert.Equal(t, 4, stats.Pruned)
+		assert.Equal(t, 6, stats.Compared)
+
+		finished := ofType[FinishedEvent](r)
+		require.Len(t, finished, 1)
+		assert.Equal(t, len(media), finished[0].Loaded)
+		assert.Equal(t, len(groups), finished[0].Groups)
+		assert.Equal(t, stats, finished[0].Stats)
+	})
+
+	t.Run("LoadAndGroupMediaWithOptions reports the comparisons of the loaded media", func(t *testing.T) {
+		r := &recorder{}
+		ch := feedChannel(media)
+		out := LoadAndGroupMediaWithOptions(ch, len(media), GroupOptions{Threshold: 0.9, Observer: r})
+		groups, err := collectGroups(t, out)
+		require.NoError(t, err)
+
+		assert.Len(t, ofType[ComparisonEvent](r), len(media))
+
+		finished := ofType[FinishedEvent](r)
+		require.Len(t, finished, 1)
+		assert.Equal(t, len(media), finished[0].Loaded)
+		assert.Equal(t, 0, finished[0].Failed)
+		assert.Equal(t, len(groups), finished[0].Groups)
+	})
+}
+
+func TestObserver_Loading(t *testing.T) {
+	dir := t.TempDir()
+	white := encodePng(t, createSolidImage(color.White, 10, 10))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.png"), white, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.png"), white, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.png"), []byte("not an image"), 0o644))
+
+	r := &recorder{}
+	channel, total := LoadMediaFromDirectory(dir, DirectoryOptions{
+		IncludeImages: true,
+		LoadOptions:   LoadOptions{Observer: r},
+	})
+
+	for range channel {
+	}
+
+	listing := ofType[ListingEvent](r)
+	require.NotEmpty(t, listing)
+	assert.Equal(t, ListingEvent{Directory: dir, Found: total, Done: true}, listing[len(listing)-1])
+
+	assert.Len(t, ofType[FileStartedEvent](r), 3)
+
+	finished := ofType[FileFinishedEvent](r)
+	require.Len(t, finished, 3)
+
+	for _, e := range finished {
+		if strings.HasSuffix(e.Name, "broken.png") {
+			assert.Error(t, e.Err)
+			assert.Empty(t, e.Type)
+			continue
+		}
+
+		assert.NoError(t, e.Err)
+		assert.Equal(t, "image", e.Type)
+		assert.Equal(t, int64(len(white)), e.Bytes)
+	}
+}
+
+func TestNewSlogObserver(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	observer := NewSlogObserver(logger)
+
+	observer.OnEvent(FileStartedEvent{Name: "a.jpg"})
+	assert.Empty(t, buf.String(), "debug events are filtered by the level")
+
+	observer.OnEvent(FileFinishedEvent{Name: "a.jpg", Err: assert.AnError})
+	assert.Contains(t, buf.String(), "level=WARN")
+	assert.Contains(t, buf.String(), "name=a.jpg")
+
+	buf.Reset()
+	observer.OnEvent(FinishedEvent{Loaded: 2, Groups: 1, Stats: GroupStats{Compared: 1, Unions: 1}})
+	assert.Contains(t, buf.String(), "level=INFO")
+	assert.Contains(t, buf.String(), "groups=1")
+	assert.Contains(t, buf.String(), "unions=1")
+}
+
+func TestMultiObserver(t *testing.T) {
+	r1 := &recorder{}
+	r2 := &recorder{}
+	observer := MultiObserver(r1, nil, r2)
+
+	observer.OnEvent(FileStartedEvent{Name: "a.jpg"})
+	assert.Equal(t, []Event{FileStartedEvent{Name: "a.jpg"}}, r1.events)
+	assert.Equal(t, []Event{FileStartedEvent{Name: "a.jpg"}}, r2.events)
+}
@@ -1,6 +1,11 @@
 package mediasim
 
-import "runtime"
+import (
+	"runtime"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+)
 
 // FrameOptions represents the configuration options for loading media frames.
 //
@@ -12,20 +17,109 @@ type FrameOptions struct {
 	FrameRotate bool
 }
 
+// LoadOptions represents the configuration options for loading a single media.
+//
+// # Fields:
+//   - Types: The registry of the extensions recognised as images and videos; nil means the default registry.
+//   - DetectContent: If true, the type of the media is detected from the first bytes of its content (magic bytes),
+//     so files with wrong or missing extensions are also loaded. When the extension doesn't match the content, the
+//     content wins and the mismatch is reported in Media.ContentExt.
+//   - FFmpegFallback: Extensions of the images (e.g., ".jxl", ".cr2") that are decoded with FFmpeg when the Go decoders
+//     can't decode them; they are handled as images even when they are not in the registry. Use FallbackAll to enable
+//     the fallback for every image type. Disabled by default.
+//   - FFmpegPath: The path to the FFmpeg executable; when empty, it's resolved as described in ExecFFmpeg.
+//   - FFmpeg: The runner of FFmpeg; when nil, the executable at FFmpegPath is run.
+//   - FFmpegTimeout: The maximum time FFmpeg may take to decode each file; when it's exceeded, FFmpeg is killed and
+//     the media fails with ErrFFmpegTimeout. 0 means no timeout.
+//   - FFmpegConcurrency: The maximum number of FFmpeg processes running at the same time, shared by all the media
+//     loaded in the same call (e.g., to LoadMediaFromDirectory); it's independent of the number of files loaded in
+//     parallel, so images can keep being decoded while videos wait. 0 means no limit other than Parallel.
+//   - FFmpegThreads: The number of threads each FFmpeg process may use. 0 lets FFmpeg decide.
+//   - MaxPixels: The maximum number of pixels (width × height) of an image. It's checked against the header of the
+//     image, before it's decoded, so decompression bombs are rejected with ErrTooManyPixels without using their memory.
+//     Images decoded by the FFmpeg fallback are limited too: FFmpeg refuses to decode them, and the frame it exports is
+//     checked and waits for the MemoryBudget like the others. 0 means DefaultMaxPixels; a negative value means no
+//     limit.
+//   - MaxFileSize: The maximum size in bytes of an image file; larger images fail with ErrFileTooLarge. Videos aren't
+//     limited, since they are never loaded into memory. 0 means no limit.
+//   - MemoryBudget: The maximum number of bytes of decoded images held in memory at the same time, shared by all the
+//     media loaded in the same call; images wait for their share of the budget before being decoded, and an image
+//     larger than the whole budget waits to be decoded alone. The memory of an image is estimated as 4 bytes per
+//     pixel. 0 means no budget other than Parallel.
+//   - FastDecode: If true, JPEGs with an EXIF thumbnail that is large enough, and has the same aspect ratio of the
+//     image, are fingerprinted from the thumbnail, which is many times faster. Its similarity scores are slightly
+//     different, and images edited by programs that don't update the thumbnail are fingerprinted from their original
+//     content, so an edited copy can be a false duplicate of the original. By default, images are decoded at their
+//     full resolution.
+//   - Observer: Receives the events of each file started and finished and, in directories, of the listing progress.
+//   - FrameOptions: Frame transformation options (flip, rotate).
+type LoadOptions struct {
+	Types             *TypeRegistry
+	DetectContent     bool
+	FFmpegFallback    []string
+	FFmpegPath        string
+	FFmpeg            FFmpegRunner
+	FFmpegTimeout     time.Duration
+	FFmpegConcurrency int
+	FFmpegThreads     int
+	MaxPixels         int64
+	MaxFileSize       int64
+	MemoryBudget      int64
+	FastDecode        bool
+	Observer          Observer
+	FrameOptions
+
+	// ffmpegSlots limits the number of FFmpeg processes; it's shared by the copies of the options.
+	ffmpegSlots chan struct{}
+	// memory limits the memory used by the images being decoded; it's shared by the copies of the options.
+	memory *semaphore.Weighted
+}
+
+func (o *LoadOptions) SetDefaults() {
+	if o.Types == nil {
+		o.Types = DefaultTypeRegistry()
+	}
+
+	if o.FFmpegConcurrency > 0 && o.ffmpegSlots == nil {
+		o.ffmpegSlots = make(chan struct{}, o.FFmpegConcurrency)
+	}
+
+	if o.MaxPixels == 0 {
+		o.MaxPixels = DefaultMaxPixels
+	}
+
+	if o.MemoryBudget > 0 && o.memory == nil {
+		o.memory = semaphore.NewWeighted(o.MemoryBudget)
+	}
+}
+
 // FilesOptions represents the configuration options for processing multiple files.
 //
 // # Fields:
 //   - Parallel: The number of files to process in parallel.
 //   - FrameOptions: Frame transformation options (flip, rotate).
+//   - LoadOptions: The other options for loading each file (type registry, content detection, limits, FFmpeg and
+//     observer); its FrameOptions are replaced by the ones above.
 type FilesOptions struct {
 	Parallel int
 	FrameOptions
+	LoadOptions
 }
 
 func (o *FilesOptions) SetDefaults() {
 	if o.Parallel == 0 {
 		o.Parallel = runtime.NumCPU()
 	}
+
+	o.LoadOptions.SetDefaults()
+}
+
+// loadOptions returns the options for loading each file, with the frame options of the files.
+func (o FilesOptions) loadOptions() LoadOptions {
+	options := o.LoadOptions
+	options.FrameOptions = o.FrameOptions
+
+	return options
 }
 
 // DirectoryOptions represents the configuration options for loading media from a directory.
@@ -34,14 +128,45 @@ func (o *FilesOptions) SetDefaults() {
 //   - IncludeImages: A flag indicating whether to include image files.
 //   - IncludeVideos: A flag indicating whether to include video files.
 //   - IsRecursive: A flag indicating whether to search subdirectories recursively.
+//   - IncludeArchives: A flag indicating whether to load the media inside ZIP and TAR (.tar, .tar.gz, .tgz) archives.
+//     The entries pass the same filters as the files, where their path is the path of the archive followed by their
+//     path inside it, like "backup.zip/photos/beach.jpg"; the archives themselves are only skipped by Exclude and
+//     ExcludeHidden.
+//   - Include: Glob patterns of the files to include; when set, a file must match at least one of them. Patterns
+//     without a "/" are matched against the file name, the others against the path relative to the directory, where
+//     "**" matches any number of subdirectories.
+//   - Exclude: Glob patterns of the files and subdirectories to skip, in the same format as Include.
+//   - MinSize: The minimum file size in bytes; 0 means no limit.
+//   - MaxSize: The maximum file size in bytes; 0 means no limit.
+//   - ModifiedSince: Only include files modified at or after this time; the zero value means no limit.
+//   - ModifiedBefore: Only include files modified before this time; the zero value means no limit.
+//   - ExcludeHidden: A flag indicating whether to skip hidden files and subdirectories (e.g., ".thumbnails"); they are
+//     included by default.
+//   - FollowSymlinks: A flag indicating whether to search symlinked subdirectories; loops are detected and skipped.
+//     Symlinked files are always included.
+//   - MaxDepth: The maximum number of subdirectory levels to search when IsRecursive is set; 0 means no limit.
 //   - Parallel: The number of files to process in parallel.
 //   - FrameOptions: Frame transformation options (flip, rotate).
+//   - LoadOptions: The other options for loading each file (type registry, content detection, limits, FFmpeg and
+//     observer); its FrameOptions are replaced by the ones above. With DetectContent, files whose extensions are not in
+//     the registry are also listed when their content is an image or a video.
 type DirectoryOptions struct {
-	IncludeImages bool
-	IncludeVideos bool
-	IsRecursive   bool
-	Parallel      int
+	IncludeImages   bool
+	IncludeVideos   bool
+	IsRecursive     bool
+	IncludeArchives bool
+	Include         []string
+	Exclude         []string
+	MinSize         int64
+	MaxSize         int64
+	ModifiedSince   time.Time
+	ModifiedBefore  time.Time
+	ExcludeHidden   bool
+	FollowSymlinks  bool
+	MaxDepth        int
+	Parallel        int
 	FrameOptions
+	LoadOptions
 }
 
 func (o *DirectoryOptions) SetDefaults() {
@@ -55,4 +180,121 @@ func (o *DirectoryOptions) SetDefaults() {
 	if o.Parallel == 0 {
 		o.Parallel = runtime.NumCPU()
 	}
+
+	o.LoadOptions.SetDefaults()
+}
+
+// loadOptions returns the options for loading each file, with the frame options of the directory.
+func (o DirectoryOptions) loadOptions() LoadOptions {
+	options := o.LoadOptions
+	options.FrameOptions = o.FrameOptions
+
+	return options
+}
+
+// GroupOptions represents the configuration options for grouping media based on similarity.
+//
+// # Fields:
+//   - Threshold: Similarity threshold (0.0–1.0) for merging two media items.
+//   - IgnoreErrors: If true, loading errors are skipped; if false, the first error terminates processing.
+//   - Prefilter: Metadata checks used to prune pairs before their similarity is calculated.
+//   - Order: The order of the groups; GroupOrderPath when empty. Groups are always returned in the same order for
+//     the same input, regardless of the order in which the media were loaded.
+//   - Observer: Receives the events of the comparisons made and of the end of the run.
+type GroupOptions struct {
+	Threshold    float64
+	IgnoreErrors bool
+	Prefilter    PrefilterOptions
+	Order        GroupOrder
+	Observer     Observer
+}
+
+// CrossOptions represents the configuration options for comparing candidate media against reference media.
+//
+// # Fields:
+//   - GroupOptions: The threshold and prefilters used for every compared pair.
+//   - CompareCandidates: If true, candidates are also compared with each other, so similar candidates are grouped even
+//     when they don't match any reference. References are never compared with each other.
+type CrossOptions struct {
+	GroupOptions
+	CompareCandidates bool
+}
+
+// MatrixOptions represents the configuration options for calculating the similarity of every pair of media.
+//
+// # Fields:
+//   - Parallel: The number of rows of the matrix calculated in parallel.
+type MatrixOptions struct {
+	Parallel int
+}
+
+func (o *MatrixOptions) SetDefaults() {
+	if o.Parallel == 0 {
+		o.Parallel = runtime.NumCPU()
+	}
+}
+
+// DiffOptions represents the configuration options for finding the differences between two images.
+//
+// # Fields:
+//   - Size: The longest side, in pixels, of the images when they are compared, and of the heatmap. 0 means
+//     DefaultDiffSize.
+//   - Threshold: The difference, between 0 and 1, above which an area of the images is changed. 0 means
+//     DefaultDiffThreshold.
+//   - LoadOptions: The options for decoding the images, used by DiffFiles. The flipped and rotated variants of its
+//     FrameOptions are tried to align the second image with the first, like in the similarity score.
+type DiffOptions struct {
+	Size      int
+	Threshold float64
+	LoadOptions
+}
+
+// DefaultDiffSize is the longest side of the images compared by DiffImages when DiffOptions.Size is 0.
+const DefaultDiffSize = 512
+
+// DefaultDiffThreshold is the difference above which an area is changed when DiffOptions.Threshold is 0.
+const DefaultDiffThreshold = 0.1
+
+func (o *DiffOptions) SetDefaults() {
+	if o.Size == 0 {
+		o.Size = DefaultDiffSize
+	}
+
+	if o.Threshold == 0 {
+		o.Threshold = DefaultDiffThreshold
+	}
+
+	o.LoadOptions.SetDefaults()
+}
+
+// TuneOptions represents the configuration options for suggesting a similarity threshold.
+//
+// # Fields:
+//   - MaxComparisons: The maximum number of pairs whose similarity is calculated. When the media have more pairs, a
+//     random sample of the media is compared with all the others, so the near-duplicates of the sampled media are
+//     still found. 0 means DefaultTuneComparisons.
+//   - Bins: The number of bins of the histogram of the scores, between 0 and 1. 0 means 50.
+//   - Seed: The seed of the random sample, so the results can be reproduced.
+//   - Prefilter: Metadata checks used to skip pairs, as in the grouping; skipped pairs are not in the histogram.
+//   - Pairs: Pairs labelled as duplicates or not. When set, only these pairs are scored, and the threshold is chosen by
+//     their precision and recall instead of the distribution of the scores.
+type TuneOptions struct {
+	MaxComparisons int
+	Bins           int
+	Seed           uint64
+	Prefilter      PrefilterOptions
+	Pairs          []LabeledPair
+}
+
+// DefaultTuneComparisons is the maximum number of pairs compared by AdviseThreshold when TuneOptions.MaxComparisons is 0.
+const DefaultTuneComparisons = 200_000
+
+func (o *TuneOptions) SetDefaults() {
+	if o.MaxComparisons == 0 {
+		o.MaxComparisons = DefaultTuneComparisons
+	}
+
+	if o.Bins == 0 {
+		o.Bins = 50
+	}
 }
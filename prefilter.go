@@ -0,0 +1,85 @@
+package mediasim
+
+import "math"
+
+// PrefilterOptions represents cheap metadata checks that are applied to a pair of media before their similarity score is
+// calculated. Pairs that fail any of the checks are pruned and never scored.
+//
+// A zero value disables the respective check.
+//
+// # Fields:
+//   - MaxDurationRatio: The maximum ratio between the longer and the shorter video duration (e.g., 2 means a video can
+//     be at most twice as long as the other). Only applies to videos.
+//   - MaxAspectDistance: The maximum distance between the aspect ratios of the two media, measured as the absolute
+//     difference of their natural logarithms (e.g., 0.1 allows roughly 10% of difference).
+//   - MaxResolutionRatio: The maximum ratio between the larger and the smaller resolution, in number of pixels.
+type PrefilterOptions struct {
+	MaxDurationRatio   float64
+	MaxAspectDistance  float64
+	MaxResolutionRatio float64
+}
+
+// IsEnabled reports whether at least one of the prefilters is enabled.
+func (p PrefilterOptions) IsEnabled() bool {
+	return p.MaxDurationRatio > 0 || p.MaxAspectDistance > 0 || p.MaxResolutionRatio > 0
+}
+
+// Accept reports whether the pair of media passes all the enabled prefilters and should be scored.
+//
+// The checks are aware of rotation: when any of the media was loaded with FrameRotate, the aspect ratio is also compared
+// against the 90º rotated version of the other media. Media with unknown metadata (e.g., zero width or length) are
+// never pruned by the check that depends on that metadata.
+func (p PrefilterOptions) Accept(media1, media2 Media) bool {
+	if p.MaxDurationRatio > 0 && media1.Type == "video" && media2.Type == "video" {
+		if ratio(float64(media1.Length), float64(media2.Length)) > p.MaxDurationRatio {
+			return false
+		}
+	}
+
+	if p.MaxAspectDistance > 0 && aspectDistance(media1, media2) > p.MaxAspectDistance {
+		return false
+	}
+
+	if p.MaxResolutionRatio > 0 {
+		pixels1 := float64(media1.Width) * float64(media1.Height)
+		pixels2 := float64(media2.Width) * float64(media2.Height)
+
+		if ratio(pixels1, pixels2) > p.MaxResolutionRatio {
+			return false
+		}
+	}
+
+	return true
+}
+
+// region - Private functions
+
+// ratio returns the ratio between the larger and the smaller value, or 1 when any of them is unknown.
+func ratio(a, b float64) float64 {
+	if a <= 0 || b <= 0 {
+		return 1
+	}
+
+	return max(a, b) / min(a, b)
+}
+
+// aspectDistance returns the log distance between the aspect ratios of two media, considering the rotated version of
+// the media when rotation is enabled.
+func aspectDistance(media1, media2 Media) float64 {
+	if media1.Width <= 0 || media1.Height <= 0 || media2.Width <= 0 || media2.Height <= 0 {
+		return 0
+	}
+
+	aspect1 := math.Log(float64(media1.Width) / float64(media1.Height))
+	aspect2 := math.Log(float64(media2.Width) / float64(media2.Height))
+	distance := math.Abs(aspect1 - aspect2)
+
+	// Rotating a media by 90º or 270º inverts its aspect ratio, which is the same as negating its logarithm.
+	if media1.isRotatable() || media2.isRotatable() {
+		distance = min(distance, math.Abs(aspect1+aspect2))
+	}
+
+	return distance
+}
+
+// endregion
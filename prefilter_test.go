@@ -0,0 +1,98 @@
+package mediasim
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrefilterOptions_Accept(t *testing.T) {
+	t.Run("zero value accepts everything", func(t *testing.T) {
+		p := PrefilterOptions{}
+		m1 := Media{Type: "video", Width: 1920, Height: 1080, Length: 3}
+		m2 := Media{Type: "video", Width: 100, Height: 1000, Length: 2400}
+
+		assert.False(t, p.IsEnabled())
+		assert.True(t, p.Accept(m1, m2))
+	})
+
+	t.Run("prunes videos with very different durations", func(t *testing.T) {
+		p := PrefilterOptions{MaxDurationRatio: 2}
+
+		assert.True(t, p.Accept(Media{Type: "video", Length: 10}, Media{Type: "video", Length: 20}))
+		assert.False(t, p.Accept(Media{Type: "video", Length: 3}, Media{Type: "video", Length: 2400}))
+	})
+
+	t.Run("duration is ignored for images", func(t *testing.T) {
+		p := PrefilterOptions{MaxDurationRatio: 2}
+		assert.True(t, p.Accept(Media{Type: "image"}, Media{Type: "image"}))
+	})
+
+	t.Run("prunes media with different aspect ratios", func(t *testing.T) {
+		p := PrefilterOptions{MaxAspectDistance: 0.1}
+		portrait := Media{Type: "image", Width: 900, Height: 1600}
+		panorama := Media{Type: "image", Width: 2100, Height: 900}
+
+		assert.False(t, p.Accept(portrait, panorama))
+		assert.True(t, p.Accept(portrait, Media{Type: "image", Width: 450, Height: 800}))
+	})
+
+	t.Run("aspect ratio considers rotation", func(t *testing.T) {
+		p := PrefilterOptions{MaxAspectDistance: 0.1}
+		portrait := Media{Type: "image", Width: 1080, Height: 1920}
+		landscape := Media{Type: "image", Width: 1920, Height: 1080}
+
+		assert.False(t, p.Accept(portrait, landscape))
+
+		landscape.options.FrameRotate = true
+		assert.True(t, p.Accept(portrait, landscape))
+	})
+
+	t.Run("prunes media with very different resolutions", func(t *testing.T) {
+		p := PrefilterOptions{MaxResolutionRatio: 4}
+		small := Media{Type: "image", Width: 100, Height: 100}
+
+		assert.True(t, p.Accept(small, Media{Type: "image", Width: 200, Height: 200}))
+		assert.False(t, p.Accept(small, Media{Type: "image", Width: 1000, Height: 1000}))
+	})
+
+	t.Run("unknown metadata is never pruned", func(t *testing.T) {
+		p := PrefilterOptions{MaxDurationRatio: 1.1, MaxAspectDistance: 0.01, MaxResolutionRatio: 1.1}
+		assert.True(t, p.Accept(Media{Type: "video"}, Media{Type: "video", Width: 1920, Height: 1080, Length: 60}))
+	})
+}
+
+func TestGroupMediaWithOptions(t *testing.T) {
+	whiteIcon := iconFromImage(createSolidImage(color.White, 100, 100))
+
+	t.Run("pruned pairs are not grouped and are counted", func(t *testing.T) {
+		media := []Media{
+			{Name: "a.jpg", Type: "image", Width: 100, Height: 100, frames: frames{icons: []icon{whiteIcon}}},
+			{Name: "b.jpg", Type: "image", Width: 100, Height: 100, frames: frames{icons: []icon{whiteIcon}}},
+			{Name: "c.jpg", Type: "image", Width: 3000, Height: 1000, frames: frames{icons: []icon{whiteIcon}}},
+		}
+
+		groups, stats := GroupMediaWithOptions(media, GroupOptions{
+			Threshold: 0.9,
+			Prefilter: PrefilterOptions{MaxAspectDistance: 0.2},
+		})
+
+		assert.Len(t, groups, 1)
+		assert.Len(t, groups[0], 2)
+		assert.Equal(t, 1, stats.Compared)
+		assert.Equal(t, 2, stats.Pruned)
+	})
+
+	t.Run("without prefilters every pair is compared", func(t *testing.T) {
+		media := []Media{
+			{Name: "a.jpg", Type: "image", frames: frames{icons: []icon{whiteIcon}}},
+			{Name: "b.jpg", Type: "image", frames: frames{icons: []icon{whiteIcon}}},
+			{Name: "c.jpg", Type: "image", frames: frames{icons: []icon{whiteIcon}}},
+		}
+
+		_, stats := GroupMediaWithOptions(media, GroupOptions{Threshold: 0.9})
+		assert.Equal(t, 3, stats.Compared)
+		assert.Equal(t, 0, stats.Pruned)
+	})
+}
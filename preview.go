@@ -0,0 +1,125 @@
+package mediasim
+
+import (
+	"context"
+	"image"
+	"image/draw"
+	"io"
+	"os"
+
+	"github.com/disintegration/imaging"
+	iffmpeg "github.com/vegidio/mediasim/internal/ffmpeg"
+)
+
+// PreviewFrames is the maximum number of frames in the contact strip of a video returned by LoadPreview.
+const PreviewFrames = 4
+
+// LoadPreview loads a small image of the media to be shown to people, like in a report: the image scaled down or, for
+// videos, a contact strip with up to PreviewFrames frames spread over the video, side by side.
+//
+// The media is decoded the same way as by LoadMediaFromFile, so the same formats, limits and FFmpeg fallback apply.
+//
+// # Parameters:
+//   - filePath: The path to the image or video file.
+//   - size: The maximum width and height of the image, and of each frame of the videos, in pixels.
+//   - options: The configuration options for loading the media.
+//
+// # Returns:
+//   - The preview of the media.
+//   - An error if the media can't be loaded; it's a *MediaError, like the errors of LoadMediaFromFile.
+func LoadPreview(filePath string, size int, options LoadOptions) (image.Image, error) {
+	options.SetDefaults()
+
+	file, mediaType, err := openMedia(filePath, options)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	switch mediaType {
+	case "image":
+		img, imgErr := decodeImage(file, filePath, options)
+		if imgErr != nil {
+			return nil, imgErr
+		}
+
+		return imaging.Fit(img, size, size, imaging.Lanczos), nil
+	case "video":
+		frames, vidErr := runFFmpeg(options, func(ctx context.Context, config iffmpeg.Config) ([]image.Image, error) {
+			return iffmpeg.ExtractFrames(ctx, filePath, config)
+		})
+		if vidErr != nil {
+			return nil, ffmpegError(filePath, vidErr)
+		}
+
+		return contactStrip(frames, size), nil
+	}
+
+	return nil, newMediaError(filePath, ErrUnsupportedFormat, nil)
+}
+
+// region - Private functions
+
+// openMedia opens the file of a media and resolves its type, by the extension or, with DetectContent, by the content.
+// The type is empty when the file isn't a supported media.
+func openMedia(filePath string, options LoadOptions) (*os.File, string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, "", newMediaError(filePath, ErrUnreadable, err)
+	}
+
+	var header []byte
+	if options.DetectContent {
+		if header, err = readHeader(file); err == nil {
+			_, err = file.Seek(0, io.SeekStart)
+		}
+
+		if err != nil {
+			file.Close()
+			return nil, "", newMediaError(filePath, ErrUnreadable, err)
+		}
+	}
+
+	mediaType, _ := options.resolveType(filePath, header)
+	return file, mediaType, nil
+}
+
+// decodeImage decodes the image of the file, with the same size limits and FFmpeg fallback of the images loaded by
+// LoadMediaFromFile.
+func decodeImage(file *os.File, filePath string, options LoadOptions) (image.Image, error) {
+	if info, err := file.Stat(); err == nil {
+		if sizeErr := options.checkFileSize(filePath, info.Size()); sizeErr != nil {
+			return nil, sizeErr
+		}
+	}
+
+	return options.decodeImageFile(file, filePath)
+}
+
+// contactStrip places up to PreviewFrames frames, evenly spread over the video, side by side; each frame is scaled to
+// fit the size.
+func contactStrip(frames []image.Image, size int) image.Image {
+	count := min(len(frames), PreviewFrames)
+	scaled := make([]image.Image, 0, count)
+	width, height := 0, 0
+
+	for i := range count {
+		// The frames are taken from the middle of each part of the video, so the first and last black frames are skipped
+		frame := imaging.Fit(frames[(2*i+1)*len(frames)/(2*count)], size, size, imaging.Lanczos)
+		scaled = append(scaled, frame)
+		width += frame.Bounds().Dx()
+		height = max(height, frame.Bounds().Dy())
+	}
+
+	strip := image.NewRGBA(image.Rect(0, 0, width, height))
+	x := 0
+	for _, frame := range scaled {
+		draw.Draw(strip, image.Rect(x, 0, x+frame.Bounds().Dx(), frame.Bounds().Dy()), frame, image.Point{}, draw.Src)
+		x += frame.Bounds().Dx()
+	}
+
+	return strip
+}
+
+// endregion
@@ -0,0 +1,64 @@
+package mediasim
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadPreview(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("images are scaled to fit the size", func(t *testing.T) {
+		filePath := filepath.Join(dir, "photo.png")
+		require.NoError(t, os.WriteFile(filePath, encodePng(t, createSolidImage(color.White, 400, 200)), 0o644))
+
+		preview, err := LoadPreview(filePath, 100, LoadOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, image.Pt(100, 50), preview.Bounds().Size())
+	})
+
+	t.Run("videos have a contact strip of frames", func(t *testing.T) {
+		fake := &fakeFFmpeg{frame: encodeJpeg(t, createSolidImage(color.White, 64, 48))}
+		filePath := filepath.Join(dir, "clip.mp4")
+		require.NoError(t, os.WriteFile(filePath, []byte("video"), 0o644))
+
+		preview, err := LoadPreview(filePath, 32, LoadOptions{FFmpeg: fake})
+		require.NoError(t, err)
+
+		// The fake FFmpeg writes two frames
+		assert.Equal(t, image.Pt(64, 24), preview.Bounds().Size())
+	})
+
+	t.Run("media that can't be decoded are an error", func(t *testing.T) {
+		filePath := filepath.Join(dir, "broken.png")
+		require.NoError(t, os.WriteFile(filePath, []byte("not a png"), 0o644))
+
+		_, err := LoadPreview(filePath, 100, LoadOptions{})
+		requireMediaError(t, err, ErrUnsupportedFormat, filePath)
+
+		_, err = LoadPreview(filepath.Join(dir, "notes.txt"), 100, LoadOptions{})
+		assert.Error(t, err)
+	})
+}
+
+func TestContactStrip(t *testing.T) {
+	frames := make([]image.Image, 10)
+	for i := range frames {
+		frames[i] = createSolidImage(color.Gray{Y: uint8(i * 20)}, 40, 30)
+	}
+
+	strip := contactStrip(frames, 20)
+	assert.Equal(t, image.Pt(4*20, 15), strip.Bounds().Size())
+
+	// The frames are taken from the middle of each quarter of the video: 1, 3, 6 and 8
+	for i, frame := range []int{1, 3, 6, 8} {
+		r, _, _, _ := strip.At(i*20+10, 7).RGBA()
+		assert.EqualValues(t, frame*20, r>>8, "frame %d", i)
+	}
+}
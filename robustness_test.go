@@ -0,0 +1,103 @@
+package mediasim
+
+import (
+	"context"
+	"image"
+	"math/rand/v2"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vegidio/mediasim/internal/variants"
+)
+
+// robustnessThreshold is the threshold used to decide whether a variant matches its original; it's the default
+// threshold of the CLI.
+const robustnessThreshold = 0.8
+
+func TestRobustness_Images(t *testing.T) {
+	// The fraction of the variants of each transform matched with their originals. The transforms with 0 aren't matched
+	// with the default threshold; raise their rates when the algorithm gets better at them.
+	expected := map[string]float64{
+		"resize-50%":     1,
+		"resize-200%":    1,
+		"jpeg-q90":       1,
+		"jpeg-q60":       1,
+		"jpeg-q30":       1,
+		"jpeg-q10":       1,
+		"crop-5%":        1,
+		"crop-15%":       0,
+		"pad-10%":        0,
+		"flip-h":         1,
+		"flip-v":         1,
+		"rotate-90":      1,
+		"rotate-180":     1,
+		"color-shift-20": 1,
+		"watermark":      1,
+		"grayscale":      0,
+	}
+
+	random := rand.New(rand.NewPCG(5, 5))
+	matched := make(map[string]int)
+	const originals = 8
+
+	for range originals {
+		img := createRandomImage(random, 320, 240)
+		original := LoadMediaFromImages("original", []image.Image{img}, FrameOptions{FrameFlip: true, FrameRotate: true})
+
+		generated, err := variants.Generate(img)
+		require.NoError(t, err)
+
+		for _, variant := range generated {
+			media := LoadMediaFromImages(variant.Label, []image.Image{variant.Image}, FrameOptions{})
+			if CalculateSimilarity(media, original) >= robustnessThreshold {
+				matched[variant.Label]++
+			}
+		}
+	}
+
+	require.Len(t, expected, len(variants.Transforms()))
+	for _, transform := range variants.Transforms() {
+		actual := float64(matched[transform.Label]) / originals
+		t.Logf("%-16s %.2f", transform.Label, actual)
+		assert.GreaterOrEqual(t, actual, expected[transform.Label], "match rate of %s", transform.Label)
+	}
+}
+
+func TestRobustness_Videos(t *testing.T) {
+	path, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		t.Skip("FFmpeg isn't installed")
+	}
+
+	run := ExecFFmpeg{Path: path}.Run
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "original.mp4")
+	require.NoError(t, variants.SyntheticVideo(ctx, run, input, 6*time.Second))
+
+	options := LoadOptions{FFmpegPath: path}
+	original, err := LoadMediaFromFileWithOptions(input, options)
+	require.NoError(t, err)
+
+	generated, err := variants.GenerateVideos(ctx, run, input, dir)
+	require.NoError(t, err)
+
+	for _, variant := range generated {
+		media, loadErr := LoadMediaFromFileWithOptions(variant.Path, options)
+		require.NoError(t, loadErr)
+
+		score := CalculateSimilarity(*media, *original)
+		t.Logf("%-16s %.3f", variant.Label, score)
+
+		// Trimmed videos show other scenes, so only the transforms that keep the whole video must be matched
+		if variant.Label == "reencode-crf23" || variant.Label == "reencode-crf40" || variant.Label == "fps-10" {
+			assert.GreaterOrEqual(t, score, robustnessThreshold, "score of %s", variant.Label)
+		}
+	}
+}
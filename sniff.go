@@ -0,0 +1,153 @@
+package mediasim
+
+import (
+	"bytes"
+	"io"
+	"path"
+	"slices"
+	"strings"
+)
+
+// sniffLen is the number of bytes at the start of a file needed to detect its format.
+const sniffLen = 32
+
+// format describes a file format that can be detected from the first bytes of its content.
+type format struct {
+	// mediaType is either "image" or "video".
+	mediaType string
+	// exts are the extensions used by the format; the first one is the canonical extension.
+	exts []string
+	// match reports whether the header has the magic bytes of the format.
+	match func(header []byte) bool
+}
+
+var formats = []format{
+	{"image", []string{".jpg", ".jpeg", ".jpe", ".jfif"}, prefix("\xff\xd8\xff")},
+	{"image", []string{".png"}, prefix("\x89PNG\r\n\x1a\n")},
+	{"image", []string{".gif"}, func(h []byte) bool { return hasPrefix(h, "GIF87a") || hasPrefix(h, "GIF89a") }},
+	{"image", []string{".bmp", ".dib"}, func(h []byte) bool {
+		// The reserved fields after the file size are always zero, which makes the short "BM" magic more reliable
+		return len(h) >= 10 && hasPrefix(h, "BM") && bytes.Equal(h[6:10], []byte{0, 0, 0, 0})
+	}},
+	{"image", []string{".tiff", ".tif"}, func(h []byte) bool { return hasPrefix(h, "II*\x00") || hasPrefix(h, "MM\x00*") }},
+	{"image", []string{".webp"}, riff("WEBP")},
+	{"image", []string{".avif"}, ftyp("avif", "avis")},
+	{"image", []string{".heic", ".heif", ".hif"}, ftyp("heic", "heix", "heim", "heis", "mif1", "msf1")},
+	{"video", []string{".mov", ".qt"}, ftyp("qt  ")},
+	{"video", []string{".mp4", ".m4v", ".mov", ".3gp"}, ftyp("isom", "iso2", "iso4", "iso5", "iso6", "mp41", "mp42",
+		"M4V ", "M4VH", "M4VP", "avc1", "dash", "3gp4", "3gp5", "3gp6", "MSNV", "XAVC", "f4v ")},
+	{"video", []string{".mkv", ".webm", ".mk3d"}, prefix("\x1a\x45\xdf\xa3")},
+	{"video", []string{".avi"}, riff("AVI ")},
+	{"video", []string{".wmv", ".asf", ".wma"}, prefix("\x30\x26\xb2\x75\x8e\x66\xcf\x11")},
+	{"video", []string{".flv"}, prefix("FLV\x01")},
+	{"video", []string{".mpg", ".mpeg", ".vob"}, func(h []byte) bool {
+		return hasPrefix(h, "\x00\x00\x01\xba") || hasPrefix(h, "\x00\x00\x01\xb3")
+	}},
+}
+
+// DetectMediaType detects the type of media from the first bytes of its content, regardless of the file extension.
+//
+// # Parameters:
+//   - header: The first bytes of the content; 32 bytes are enough to detect all the supported formats.
+//
+// # Returns:
+//   - The type of media, "image" or "video", or an empty string if the format was not recognised.
+//   - The canonical extension of the detected format (e.g., ".jpg"), or an empty string if it was not recognised.
+func DetectMediaType(header []byte) (string, string) {
+	for _, f := range formats {
+		if f.match(header) {
+			return f.mediaType, f.exts[0]
+		}
+	}
+
+	return "", ""
+}
+
+// region - Private functions
+
+// resolveType decides the type of the media from the extension of its name and, when content detection is enabled,
+// from the first bytes of its content. The content wins over the extension when they disagree; in that case, the
+// extension of the detected format is also returned, so the mismatch can be reported.
+func (o LoadOptions) resolveType(name string, header []byte) (string, string) {
+	mediaType := o.Types.TypeOf(name)
+	if mediaType == "" && slices.Contains(o.fallbackTypes(), strings.ToLower(path.Ext(name))) {
+		mediaType = "image"
+	}
+
+	if !o.DetectContent {
+		return mediaType, ""
+	}
+
+	detectedType, detectedExt := DetectMediaType(header)
+	if detectedType == "" {
+		// Unknown formats, like the ones added to the registry, are trusted by their extension
+		return mediaType, ""
+	}
+
+	if sameFormat(strings.ToLower(path.Ext(name)), detectedExt) {
+		return detectedType, ""
+	}
+
+	return detectedType, detectedExt
+}
+
+// sniffType returns the type of media of the content read by open, or an empty string if it was not recognised.
+func sniffType(open func() (io.ReadCloser, error)) string {
+	reader, err := open()
+	if err != nil {
+		return ""
+	}
+
+	defer reader.Close()
+
+	header, _ := readHeader(reader)
+	mediaType, _ := DetectMediaType(header)
+	return mediaType
+}
+
+// readHeader reads the first bytes of the content, needed to detect its format. The header is shorter than sniffLen
+// when the content is shorter.
+func readHeader(r io.Reader) ([]byte, error) {
+	header := make([]byte, sniffLen)
+	n, err := io.ReadFull(r, header)
+	if err == io.ErrUnexpectedEOF || err == io.EOF {
+		err = nil
+	}
+
+	return header[:n], err
+}
+
+// sameFormat reports whether the extension is one of the extensions used by the format of the detected extension.
+func sameFormat(ext string, detectedExt string) bool {
+	for _, f := range formats {
+		if f.exts[0] == detectedExt && slices.Contains(f.exts, ext) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasPrefix(header []byte, magic string) bool {
+	return bytes.HasPrefix(header, []byte(magic))
+}
+
+func prefix(magic string) func([]byte) bool {
+	return func(header []byte) bool { return hasPrefix(header, magic) }
+}
+
+// riff matches RIFF containers of the given form type, like "WEBP" or "AVI ".
+func riff(form string) func([]byte) bool {
+	return func(header []byte) bool {
+		return len(header) >= 12 && hasPrefix(header, "RIFF") && string(header[8:12]) == form
+	}
+}
+
+// ftyp matches ISO base media files (MP4, MOV, HEIF, ...) whose major brand is one of the given brands.
+func ftyp(brands ...string) func([]byte) bool {
+	return func(header []byte) bool {
+		return len(header) >= 12 && string(header[4:8]) == "ftyp" && slices.Contains(brands, string(header[8:12]))
+	}
+}
+
+// endregion
@@ -0,0 +1,165 @@
+package mediasim
+
+import (
+	"bytes"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectMediaType(t *testing.T) {
+	img := createSolidImage(color.White, 10, 10)
+
+	tests := []struct {
+		name      string
+		header    []byte
+		mediaType string
+		ext       string
+	}{
+		{"jpeg", encodeJpeg(t, img), "image", ".jpg"},
+		{"png", encodePng(t, img), "image", ".png"},
+		{"gif", []byte("GIF89a\x0a\x00\x0a\x00"), "image", ".gif"},
+		{"bmp", []byte("BM\x36\x00\x00\x00\x00\x00\x00\x00\x36\x00"), "image", ".bmp"},
+		{"tiff", []byte("II*\x00\x08\x00\x00\x00"), "image", ".tiff"},
+		{"webp", []byte("RIFF\x24\x00\x00\x00WEBPVP8 "), "image", ".webp"},
+		{"heic", []byte("\x00\x00\x00\x18ftypheic\x00\x00\x00\x00"), "image", ".heic"},
+		{"avif", []byte("\x00\x00\x00\x1cftypavif\x00\x00\x00\x00"), "image", ".avif"},
+		{"mp4", []byte("\x00\x00\x00\x20ftypisom\x00\x00\x02\x00"), "video", ".mp4"},
+		{"mov", []byte("\x00\x00\x00\x14ftypqt  \x00\x00\x00\x00"), "video", ".mov"},
+		{"mkv", []byte("\x1a\x45\xdf\xa3\x9f\x42\x86\x81"), "video", ".mkv"},
+		{"avi", []byte("RIFF\x24\x00\x00\x00AVI LIST"), "video", ".avi"},
+		{"wmv", []byte("\x30\x26\xb2\x75\x8e\x66\xcf\x11\xa6\xd9"), "video", ".wmv"},
+		{"text", []byte("hello world"), "", ""},
+		{"short", []byte("BM"), "", ""},
+		{"empty", nil, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := tt.header
+			if len(header) > sniffLen {
+				header = header[:sniffLen]
+			}
+
+			mediaType, ext := DetectMediaType(header)
+			assert.Equal(t, tt.mediaType, mediaType)
+			assert.Equal(t, tt.ext, ext)
+		})
+	}
+}
+
+func TestLoadOptions_ResolveType(t *testing.T) {
+	jpegHeader := encodeJpeg(t, createSolidImage(color.White, 10, 10))[:sniffLen]
+	mp4Header := []byte("\x00\x00\x00\x20ftypmp42\x00\x00\x00\x00")
+
+	t.Run("uses only the extension by default", func(t *testing.T) {
+		options := LoadOptions{Types: NewTypeRegistry()}
+
+		mediaType, contentExt := options.resolveType("photo.png", jpegHeader)
+		assert.Equal(t, "image", mediaType)
+		assert.Empty(t, contentExt)
+
+		mediaType, _ = options.resolveType("photo", jpegHeader)
+		assert.Empty(t, mediaType)
+	})
+
+	t.Run("content wins over the extension", func(t *testing.T) {
+		options := LoadOptions{Types: NewTypeRegistry(), DetectContent: true}
+
+		mediaType, contentExt := options.resolveType("photo", jpegHeader)
+		assert.Equal(t, "image", mediaType)
+		assert.Equal(t, ".jpg", contentExt)
+
+		mediaType, contentExt = options.resolveType("clip.jpg", mp4Header)
+		assert.Equal(t, "video", mediaType)
+		assert.Equal(t, ".mp4", contentExt)
+	})
+
+	t.Run("aliases of the same format are not mismatches", func(t *testing.T) {
+		options := LoadOptions{Types: NewTypeRegistry(), DetectContent: true}
+
+		_, contentExt := options.resolveType("photo.JPEG", jpegHeader)
+		assert.Empty(t, contentExt)
+
+		_, contentExt = options.resolveType("clip.mov", mp4Header)
+		assert.Empty(t, contentExt)
+	})
+
+	t.Run("unknown content is trusted by its extension", func(t *testing.T) {
+		types := NewTypeRegistry()
+		types.AddImageType(".custom")
+		options := LoadOptions{Types: types, DetectContent: true}
+
+		mediaType, contentExt := options.resolveType("photo.custom", []byte("custom format"))
+		assert.Equal(t, "image", mediaType)
+		assert.Empty(t, contentExt)
+	})
+}
+
+func TestLoadMediaFromFile_DetectContent(t *testing.T) {
+	dir := t.TempDir()
+	content := encodeJpeg(t, createSolidImage(color.White, 40, 30))
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "photo.png"), content, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "no-extension"), content, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a media file"), 0o644))
+
+	t.Run("reports extension mismatches", func(t *testing.T) {
+		media, err := LoadMediaFromFileWithOptions(filepath.Join(dir, "photo.png"), LoadOptions{DetectContent: true})
+		require.NoError(t, err)
+		assert.True(t, media.HasExtMismatch())
+		assert.Equal(t, ".jpg", media.ContentExt)
+		assert.Equal(t, 40, media.Width)
+		assert.Equal(t, int64(len(content)), media.Size)
+	})
+
+	t.Run("loads files without extension", func(t *testing.T) {
+		_, err := LoadMediaFromFileWithOptions(filepath.Join(dir, "no-extension"), LoadOptions{})
+		assert.Error(t, err)
+
+		media, err := LoadMediaFromFileWithOptions(filepath.Join(dir, "no-extension"), LoadOptions{DetectContent: true})
+		require.NoError(t, err)
+		assert.Equal(t, "image", media.Type)
+	})
+
+	t.Run("reader detects the content too", func(t *testing.T) {
+		media, err := LoadMediaFromReaderWithOptions("upload.bin", bytes.NewReader(content), LoadOptions{DetectContent: true})
+		require.NoError(t, err)
+		assert.Equal(t, ".jpg", media.ContentExt)
+		assert.Equal(t, int64(len(content)), media.Size)
+	})
+
+	t.Run("directories list files by content", func(t *testing.T) {
+		ch, total := LoadMediaFromDirectory(dir, DirectoryOptions{})
+		names, _ := collectNames(ch)
+		assert.Equal(t, 1, total)
+		assert.Equal(t, []string{filepath.Join(dir, "photo.png")}, names)
+
+		ch, total = LoadMediaFromDirectory(dir, DirectoryOptions{LoadOptions: LoadOptions{DetectContent: true}})
+		names, errs := collectNames(ch)
+		assert.Equal(t, 2, total)
+		assert.Equal(t, 0, errs)
+		assert.ElementsMatch(t, []string{filepath.Join(dir, "photo.png"), filepath.Join(dir, "no-extension")}, names)
+	})
+
+	t.Run("directories use the registry in the options", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "scan.img"), content, 0o644))
+		defer os.Remove(filepath.Join(dir, "scan.img"))
+
+		types := NewTypeRegistry()
+		types.AddImageType(".img")
+
+		ch, total := LoadMediaFromDirectory(dir, DirectoryOptions{LoadOptions: LoadOptions{Types: types}})
+		names, _ := collectNames(ch)
+		assert.Equal(t, 2, total)
+		assert.Contains(t, names, filepath.Join(dir, "scan.img"))
+
+		ch, total = LoadMediaFromDirectory(dir, DirectoryOptions{})
+		_, _ = collectNames(ch)
+		assert.Equal(t, 1, total)
+	})
+}
@@ -0,0 +1,194 @@
+package mediasim
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"math"
+	"slices"
+)
+
+// region - Private functions
+
+const (
+	// minThumbnailSide is the minimum width and height of an embedded thumbnail to be used instead of the full image;
+	// smaller thumbnails lose too much detail for the icon, which samples 276×276 pixels.
+	minThumbnailSide = 100
+	// maxThumbnailAspectDistance is the maximum distance between the aspect ratios of the thumbnail and the full
+	// image, as the difference of their logarithms; thumbnails with black bars or cropped differently are not used.
+	maxThumbnailAspectDistance = 0.02
+	// minThumbnailChroma is the minimum range of the chroma values of a thumbnail to be used. The icon normalizes the
+	// contrast of each channel, so in grayscale images the tiny color differences of the thumbnail would be amplified.
+	minThumbnailChroma = 16
+)
+
+// reducedImage is an image decoded at a lower resolution than the original, like the thumbnail embedded in a JPEG. It
+// keeps the size of the original image, so the Media has the real dimensions.
+type reducedImage struct {
+	image.Image
+	size image.Point
+}
+
+// imageSize returns the width and height of the original image, even if it was decoded at a lower resolution.
+func imageSize(img image.Image) (int, int) {
+	if reduced, ok := img.(reducedImage); ok {
+		return reduced.size.X, reduced.size.Y
+	}
+
+	return img.Bounds().Dx(), img.Bounds().Dy()
+}
+
+// decodeThumbnail decodes the EXIF thumbnail embedded in the beginning of a JPEG, when it's safe to use it instead of
+// the full image: it must be large enough, have the same aspect ratio of the full image, and have colors.
+//
+// # Parameters:
+//   - header: The beginning of the JPEG, up to the start of the image data; the EXIF segment comes before it.
+//   - config: The dimensions of the full image.
+//
+// # Returns:
+//   - The thumbnail, with the dimensions of the full image, or nil if there's no thumbnail or it can't be used.
+func decodeThumbnail(header []byte, config image.Config) image.Image {
+	content := exifThumbnail(header)
+	if content == nil {
+		return nil
+	}
+
+	thumbnail, err := jpeg.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil
+	}
+
+	width, height := thumbnail.Bounds().Dx(), thumbnail.Bounds().Dy()
+	if width < minThumbnailSide || height < minThumbnailSide || width >= config.Width || config.Height == 0 {
+		return nil
+	}
+
+	if !hasColor(thumbnail) {
+		return nil
+	}
+
+	distance := math.Abs(math.Log(float64(width)/float64(height)) -
+		math.Log(float64(config.Width)/float64(config.Height)))
+	if distance > maxThumbnailAspectDistance {
+		return nil
+	}
+
+	return reducedImage{Image: thumbnail, size: image.Pt(config.Width, config.Height)}
+}
+
+// hasColor reports whether the range of the chroma values of the JPEG image is wide enough to have colors.
+func hasColor(img image.Image) bool {
+	ycbcr, ok := img.(*image.YCbCr)
+	if !ok {
+		// Images with a single component are grayscale
+		return false
+	}
+
+	chromaRange := func(values []uint8) int {
+		if len(values) == 0 {
+			return 0
+		}
+
+		return int(slices.Max(values)) - int(slices.Min(values))
+	}
+
+	return max(chromaRange(ycbcr.Cb), chromaRange(ycbcr.Cr)) >= minThumbnailChroma
+}
+
+// exifThumbnail returns the JPEG thumbnail in the EXIF segment of a JPEG, or nil when there isn't one.
+func exifThumbnail(content []byte) []byte {
+	if len(content) < 4 || content[0] != 0xFF || content[1] != 0xD8 {
+		return nil
+	}
+
+	// The segments before the image data have a marker and a length, which includes itself but not the marker
+	for pos := 2; pos+4 <= len(content) && content[pos] == 0xFF; {
+		marker := content[pos+1]
+		if marker == 0xDA {
+			// Start of scan: no more metadata
+			return nil
+		}
+
+		length := int(binary.BigEndian.Uint16(content[pos+2:]))
+		end := pos + 2 + length
+		if length < 2 || end > len(content) {
+			return nil
+		}
+
+		segment := content[pos+4 : end]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return tiffThumbnail(segment[6:])
+		}
+
+		pos = end
+	}
+
+	return nil
+}
+
+// tiffThumbnail returns the JPEG thumbnail referenced by the second IFD of the TIFF structure of the EXIF segment.
+func tiffThumbnail(tiff []byte) []byte {
+	if len(tiff) < 8 {
+		return nil
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil
+	}
+
+	if order.Uint16(tiff[2:]) != 42 {
+		return nil
+	}
+
+	// The first IFD describes the image; the second one, the thumbnail
+	ifd0 := int(order.Uint32(tiff[4:]))
+	ifd1, ok := nextIFD(tiff, order, ifd0)
+	if !ok || ifd1 < 8 || ifd1+2 > len(tiff) {
+		return nil
+	}
+
+	var offset, length int
+	count := int(order.Uint16(tiff[ifd1:]))
+	for i := range count {
+		entry := ifd1 + 2 + i*12
+		if entry+12 > len(tiff) {
+			return nil
+		}
+
+		switch order.Uint16(tiff[entry:]) {
+		case 0x0201: // JPEGInterchangeFormat
+			offset = int(order.Uint32(tiff[entry+8:]))
+		case 0x0202: // JPEGInterchangeFormatLength
+			length = int(order.Uint32(tiff[entry+8:]))
+		}
+	}
+
+	if offset <= 0 || length <= 0 || offset+length > len(tiff) {
+		return nil
+	}
+
+	return tiff[offset : offset+length]
+}
+
+// nextIFD returns the offset of the IFD after the one at the offset.
+func nextIFD(tiff []byte, order binary.ByteOrder, offset int) (int, bool) {
+	if offset < 8 || offset+2 > len(tiff) {
+		return 0, false
+	}
+
+	next := offset + 2 + int(order.Uint16(tiff[offset:]))*12
+	if next+4 > len(tiff) {
+		return 0, false
+	}
+
+	return int(order.Uint32(tiff[next:])), true
+}
+
+// endregion
@@ -0,0 +1,263 @@
+package mediasim
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/disintegration/imaging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withExifThumbnail inserts an EXIF segment with the JPEG thumbnail right after the start of the JPEG content.
+func withExifThumbnail(content, thumbnail []byte, order binary.ByteOrder) []byte {
+	// The TIFF header is followed by an empty IFD0, and by an IFD1 pointing to the thumbnail
+	var tiff bytes.Buffer
+	if order == binary.LittleEndian {
+		tiff.WriteString("II")
+	} else {
+		tiff.WriteString("MM")
+	}
+
+	const ifd1, offset = 14, 44
+	_ = binary.Write(&tiff, order, uint16(42))
+	_ = binary.Write(&tiff, order, uint32(8))
+	_ = binary.Write(&tiff, order, uint16(0))
+	_ = binary.Write(&tiff, order, uint32(ifd1))
+	_ = binary.Write(&tiff, order, uint16(2))
+	for _, entry := range [][2]uint32{{0x0201, offset}, {0x0202, uint32(len(thumbnail))}} {
+		_ = binary.Write(&tiff, order, uint16(entry[0]))
+		_ = binary.Write(&tiff, order, uint16(4)) // LONG
+		_ = binary.Write(&tiff, order, uint32(1))
+		_ = binary.Write(&tiff, order, entry[1])
+	}
+	_ = binary.Write(&tiff, order, uint32(0))
+	tiff.Write(thumbnail)
+
+	var result bytes.Buffer
+	result.Write(content[:2])
+	result.Write([]byte{0xFF, 0xE1})
+	_ = binary.Write(&result, binary.BigEndian, uint16(2+6+tiff.Len()))
+	result.WriteString("Exif\x00\x00")
+	result.Write(tiff.Bytes())
+	result.Write(content[2:])
+
+	return result.Bytes()
+}
+
+// createPatternImages creates images with different content, used as the corpus to compare the thumbnails with the
+// full images.
+func createPatternImages(w, h int) map[string]image.Image {
+	circles := image.NewRGBA(image.Rect(0, 0, w, h))
+	checker := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			dx, dy := x-w/3, y-h/2
+			if dx*dx+dy*dy < (h/3)*(h/3) {
+				circles.Set(x, y, color.RGBA{R: 200, G: 40, B: 40, A: 255})
+			} else {
+				circles.Set(x, y, color.RGBA{R: 20, G: 60, B: uint8(y * 255 / h), A: 255})
+			}
+
+			if (x/(w/8)+y/(h/6))%2 == 0 {
+				checker.Set(x, y, color.White)
+			} else {
+				checker.Set(x, y, color.RGBA{R: 30, G: 120, B: 30, A: 255})
+			}
+		}
+	}
+
+	return map[string]image.Image{
+		"gradient": createGradientImage(w, h),
+		"two-tone": createTwoToneImage(color.RGBA{R: 220, G: 180, A: 255}, color.RGBA{B: 160, A: 255}, w, h),
+		"circles":  circles,
+		"checker":  checker,
+	}
+}
+
+// createTwoToneImage creates an image whose left half has the first color and the right half, the second one.
+func createTwoToneImage(c1, c2 color.Color, w, h int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if x < w/2 {
+				img.Set(x, y, c1)
+			} else {
+				img.Set(x, y, c2)
+			}
+		}
+	}
+	return img
+}
+
+func TestLoadMediaFromFile_Thumbnail(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, content []byte) string {
+		filePath := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(filePath, content, 0o644))
+		return filePath
+	}
+
+	t.Run("thumbnails give the same results as the full images", func(t *testing.T) {
+		corpus := createPatternImages(1200, 800)
+		loaded := make(map[string]*Media)
+
+		for name, img := range corpus {
+			thumbnail := encodeJpeg(t, imaging.Resize(img, 240, 160, imaging.Lanczos))
+			filePath := write(name+".jpg", withExifThumbnail(encodeJpeg(t, img), thumbnail, binary.LittleEndian))
+
+			fast, err := LoadMediaFromFileWithOptions(filePath, LoadOptions{FastDecode: true})
+			require.NoError(t, err)
+			full, err := LoadMediaFromFileWithOptions(filePath, LoadOptions{})
+			require.NoError(t, err)
+
+			assert.Equal(t, 1200, fast.Width, name)
+			assert.Equal(t, 800, fast.Height, name)
+			assert.GreaterOrEqual(t, CalculateSimilarity(*fast, *full), 0.97, name)
+			loaded[name] = fast
+		}
+
+		// The thumbnails must still tell different images apart
+		for name1, media1 := range loaded {
+			for name2, media2 := range loaded {
+				if name1 != name2 {
+					assert.Less(t, CalculateSimilarity(*media1, *media2), 0.8, "%s vs %s", name1, name2)
+				}
+			}
+		}
+	})
+
+	// The thumbnails have a different content, so it's easy to tell whether they were used
+	img := createGradientImage(600, 400)
+	marker := func(w, h int) image.Image {
+		return createTwoToneImage(color.RGBA{R: 255, A: 255}, color.RGBA{B: 255, A: 255}, w, h)
+	}
+	markerMedia := LoadMediaFromImages("marker.png", []image.Image{marker(600, 400)}, FrameOptions{})
+	usesThumbnail := func(t *testing.T, content []byte, options LoadOptions) bool {
+		t.Helper()
+		media, err := LoadMediaFromReaderWithOptions("photo.jpg", bytes.NewReader(content), options)
+		require.NoError(t, err)
+		assert.Equal(t, 600, media.Width)
+		assert.Equal(t, 400, media.Height)
+		return CalculateSimilarity(*media, markerMedia) > 0.9
+	}
+
+	t.Run("thumbnails in big-endian EXIF are used", func(t *testing.T) {
+		thumbnail := encodeJpeg(t, marker(150, 100))
+		content := withExifThumbnail(encodeJpeg(t, img), thumbnail, binary.BigEndian)
+
+		assert.True(t, usesThumbnail(t, content, LoadOptions{FastDecode: true}))
+	})
+
+	t.Run("thumbnails aren't used by default", func(t *testing.T) {
+		thumbnail := encodeJpeg(t, marker(150, 100))
+		content := withExifThumbnail(encodeJpeg(t, img), thumbnail, binary.LittleEndian)
+
+		assert.False(t, usesThumbnail(t, content, LoadOptions{}))
+	})
+
+	t.Run("edited copies with a stale thumbnail keep the scores of a full decode by default", func(t *testing.T) {
+		original := encodeJpeg(t, img)
+		thumbnail := encodeJpeg(t, imaging.Resize(img, 150, 100, imaging.Lanczos))
+		edited := marker(600, 400)
+
+		fromOriginal, err := LoadMediaFromReaderWithOptions("original.jpg", bytes.NewReader(original), LoadOptions{})
+		require.NoError(t, err)
+		plain, err := LoadMediaFromReaderWithOptions("edited.jpg", bytes.NewReader(encodeJpeg(t, edited)), LoadOptions{})
+		require.NoError(t, err)
+
+		stale := withExifThumbnail(encodeJpeg(t, edited), thumbnail, binary.LittleEndian)
+		fromStale, err := LoadMediaFromReaderWithOptions("edited.jpg", bytes.NewReader(stale), LoadOptions{})
+		require.NoError(t, err)
+
+		assert.Equal(t, CalculateSimilarity(*fromOriginal, *plain), CalculateSimilarity(*fromOriginal, *fromStale))
+		assert.Less(t, CalculateSimilarity(*fromOriginal, *fromStale), 0.9)
+	})
+
+	t.Run("thumbnails with a different aspect ratio aren't used", func(t *testing.T) {
+		// Like the 4:3 thumbnails with black bars that some cameras embed in 3:2 photos
+		thumbnail := encodeJpeg(t, marker(160, 120))
+		content := withExifThumbnail(encodeJpeg(t, img), thumbnail, binary.LittleEndian)
+
+		assert.False(t, usesThumbnail(t, content, LoadOptions{FastDecode: true}))
+	})
+
+	t.Run("small thumbnails aren't used", func(t *testing.T) {
+		thumbnail := encodeJpeg(t, marker(96, 64))
+		content := withExifThumbnail(encodeJpeg(t, img), thumbnail, binary.LittleEndian)
+
+		assert.False(t, usesThumbnail(t, content, LoadOptions{FastDecode: true}))
+	})
+
+	t.Run("thumbnails of grayscale images aren't used", func(t *testing.T) {
+		gray := imaging.Grayscale(img)
+		thumbnail := encodeJpeg(t, imaging.Resize(gray, 150, 100, imaging.Lanczos))
+		content := withExifThumbnail(encodeJpeg(t, gray), thumbnail, binary.LittleEndian)
+
+		media, err := LoadMediaFromReaderWithOptions("gray.jpg", bytes.NewReader(content), LoadOptions{FastDecode: true})
+		require.NoError(t, err)
+		full, err := LoadMediaFromReaderWithOptions("gray.jpg", bytes.NewReader(content), LoadOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, 1.0, CalculateSimilarity(*media, *full))
+	})
+
+	t.Run("broken thumbnails aren't used", func(t *testing.T) {
+		content := withExifThumbnail(encodeJpeg(t, img), []byte{0xFF, 0xD8, 0xFF}, binary.LittleEndian)
+
+		assert.False(t, usesThumbnail(t, content, LoadOptions{FastDecode: true}))
+	})
+
+	t.Run("truncated EXIF segments are ignored", func(t *testing.T) {
+		thumbnail := encodeJpeg(t, marker(150, 100))
+		content := withExifThumbnail(encodeJpeg(t, img), thumbnail, binary.LittleEndian)
+
+		for i := range len(thumbnail) + 60 {
+			assert.NotPanics(t, func() { exifThumbnail(content[:i]) })
+		}
+	})
+}
+
+// BenchmarkLoadMediaFromFiles_LargeJpeg measures the throughput of loading 24-megapixel JPEGs with a 240×160 EXIF
+// thumbnail, decoded in full and from the thumbnail.
+func BenchmarkLoadMediaFromFiles_LargeJpeg(b *testing.B) {
+	dir := b.TempDir()
+	paths := make([]string, 8)
+
+	img := createGradientImage(6000, 4000)
+	thumbnail := encodeJpeg(b, imaging.Resize(img, 240, 160, imaging.Box))
+	content := withExifThumbnail(encodeJpeg(b, img), thumbnail, binary.LittleEndian)
+
+	for i := range paths {
+		paths[i] = filepath.Join(dir, fmt.Sprintf("photo%d.jpg", i))
+		if err := os.WriteFile(paths[i], content, 0o644); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	for _, fullDecode := range []bool{true, false} {
+		name := "thumbnail"
+		if fullDecode {
+			name = "full"
+		}
+
+		b.Run(name, func(b *testing.B) {
+			loaded := 0
+			for b.Loop() {
+				for result := range LoadMediaFromFiles(paths, FilesOptions{LoadOptions: LoadOptions{FastDecode: !fullDecode}}) {
+					if result.Err != nil {
+						b.Fatal(result.Err)
+					}
+					loaded++
+				}
+			}
+
+			b.ReportMetric(float64(loaded)/b.Elapsed().Seconds(), "files/s")
+		})
+	}
+}
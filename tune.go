@@ -0,0 +1,325 @@
+package mediasim
+
+import (
+	"cmp"
+	"math"
+	"math/rand/v2"
+	"slices"
+)
+
+// LabeledPair is a pair of media known to be, or not to be, duplicates of each other.
+//
+// # Fields:
+//   - Name1: The name of the first media, as in Media.Name.
+//   - Name2: The name of the second media.
+//   - Duplicate: Whether the media are duplicates and should be grouped.
+type LabeledPair struct {
+	Name1     string `json:"name1"`
+	Name2     string `json:"name2"`
+	Duplicate bool   `json:"duplicate"`
+}
+
+// HistogramBin is a range of similarity scores and the number of pairs whose score is in it.
+type HistogramBin struct {
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Count int     `json:"count"`
+}
+
+// ThresholdMetrics are the results of classifying the labelled pairs with a threshold: the pairs with a score equal to
+// or above it are predicted to be duplicates.
+type ThresholdMetrics struct {
+	Threshold      float64 `json:"threshold"`
+	Precision      float64 `json:"precision"`
+	Recall         float64 `json:"recall"`
+	F1             float64 `json:"f1"`
+	TruePositives  int     `json:"truePositives"`
+	FalsePositives int     `json:"falsePositives"`
+	FalseNegatives int     `json:"falseNegatives"`
+}
+
+// ThresholdAdvice is the similarity threshold suggested for a collection, with the data it's based on.
+type ThresholdAdvice struct {
+	// Threshold is the suggested threshold, rounded to two decimals.
+	Threshold float64 `json:"threshold"`
+	// Pairs is the number of pairs whose similarity was calculated.
+	Pairs int `json:"pairs"`
+	// Histogram is the distribution of the scores of the pairs.
+	Histogram []HistogramBin `json:"histogram"`
+	// Separated is true when the scores of the near-duplicates are clearly apart from the others; with labelled pairs,
+	// when a threshold classifies every pair correctly.
+	Separated bool `json:"separated"`
+	// BackgroundMode is the most common score of the pairs of unrelated media.
+	BackgroundMode float64 `json:"backgroundMode"`
+	// NearDuplicateMode is the most common score of the pairs of near-duplicates; 0 when they weren't found.
+	NearDuplicateMode float64 `json:"nearDuplicateMode"`
+	// GapLow and GapHigh are the range of the least common scores between the two modes; the suggested threshold is in
+	// the middle of it. They are 0 when the modes weren't found.
+	GapLow  float64 `json:"gapLow"`
+	GapHigh float64 `json:"gapHigh"`
+	// Tradeoff has the precision and recall of each threshold, from 0 to 1 in steps of 0.01; only with labelled pairs.
+	Tradeoff []ThresholdMetrics `json:"tradeoff,omitempty"`
+	// Missing is the number of labelled pairs ignored because any of their media wasn't found.
+	Missing int `json:"missing,omitempty"`
+}
+
+// AdviseThreshold suggests a similarity threshold for the media, based on the distribution of the scores of their pairs.
+//
+// In a typical collection, most pairs are unrelated and their scores form a large mode (the background), while the
+// near-duplicates form a small mode close to 1. The suggested threshold is in the middle of the gap between them. When
+// no gap is found, the threshold only keeps the top 0.1% of the pairs, and Separated is false.
+//
+// When labelled pairs are given in the options, only these pairs are scored and the suggested threshold is the one with
+// the best F1 score; the precision and recall of every threshold are in ThresholdAdvice.Tradeoff.
+//
+// # Parameters:
+//   - media: The media of the collection.
+//   - options: The configuration options for sampling the pairs.
+//
+// # Returns:
+//   - The suggested threshold and the data it's based on; the threshold is 0 when there are no pairs to score.
+func AdviseThreshold(media []Media, options TuneOptions) ThresholdAdvice {
+	options.SetDefaults()
+
+	if len(options.Pairs) > 0 {
+		return adviseFromLabels(media, options)
+	}
+
+	scores := sampleScores(media, options)
+	advice := ThresholdAdvice{Pairs: len(scores), Histogram: newHistogram(scores, options.Bins)}
+	if len(scores) == 0 {
+		return advice
+	}
+
+	counts := make([]int, len(advice.Histogram))
+	for i, bin := range advice.Histogram {
+		counts[i] = bin.Count
+	}
+
+	background, nearDuplicate, ok := findModes(counts)
+	if !ok {
+		// Without a gap, only the most similar pairs are kept
+		slices.Sort(scores)
+		quantile := scores[int(math.Ceil(float64(len(scores))*0.999))-1]
+		advice.Threshold = min(1, math.Ceil(quantile*100)/100)
+		advice.BackgroundMode = advice.Histogram[slices.Index(counts, slices.Max(counts))].center()
+		return advice
+	}
+
+	start, end := findGap(counts, background, nearDuplicate)
+	advice.Separated = true
+	advice.BackgroundMode = advice.Histogram[background].center()
+	advice.NearDuplicateMode = advice.Histogram[nearDuplicate].center()
+	advice.GapLow = advice.Histogram[start].Min
+	advice.GapHigh = advice.Histogram[end].Max
+	advice.Threshold = roundScore((advice.GapLow + advice.GapHigh) / 2)
+
+	return advice
+}
+
+// region - Private functions
+
+// center returns the score in the middle of the bin.
+func (b HistogramBin) center() float64 {
+	return roundScore((b.Min + b.Max) / 2)
+}
+
+// roundScore rounds the score to two decimals.
+func roundScore(score float64) float64 {
+	return math.Round(score*100) / 100
+}
+
+// sampleScores calculates the similarity of the pairs of media of the same type. When there are more pairs than
+// MaxComparisons, a random sample of the media is compared with all the others.
+func sampleScores(media []Media, options TuneOptions) []float64 {
+	n := len(media)
+	scores := make([]float64, 0, min(n*(n-1)/2, options.MaxComparisons))
+	stats := GroupStats{}
+
+	add := func(i, j int) {
+		if media[i].Type != media[j].Type {
+			return
+		}
+
+		if score, ok := stats.score(media[i], media[j], options.Prefilter); ok {
+			scores = append(scores, score)
+		}
+	}
+
+	if n*(n-1)/2 <= options.MaxComparisons {
+		for i := range n {
+			for j := i + 1; j < n; j++ {
+				add(i, j)
+			}
+		}
+
+		return scores
+	}
+
+	random := rand.New(rand.NewPCG(options.Seed, options.Seed))
+	sampled := random.Perm(n)[:max(1, options.MaxComparisons/(n-1))]
+	rank := make(map[int]int, len(sampled))
+	for k, i := range sampled {
+		rank[i] = k
+	}
+
+	for k, i := range sampled {
+		for j := range n {
+			// Pairs of two sampled media are compared only once
+			if r, ok := rank[j]; j == i || (ok && r < k) {
+				continue
+			}
+
+			add(i, j)
+		}
+	}
+
+	return scores
+}
+
+// newHistogram counts the scores in bins of the same width between 0 and 1.
+func newHistogram(scores []float64, bins int) []HistogramBin {
+	histogram := make([]HistogramBin, bins)
+	for i := range histogram {
+		histogram[i] = HistogramBin{Min: float64(i) / float64(bins), Max: float64(i+1) / float64(bins)}
+	}
+
+	for _, score := range scores {
+		i := min(bins-1, max(0, int(score*float64(bins))))
+		histogram[i].Count++
+	}
+
+	return histogram
+}
+
+// findModes returns the bins of the two most prominent peaks of the histogram: the lower one is the background and the
+// higher one, the near-duplicates. A peak is only considered when the valley between it and the highest peak has less
+// than half of its count, so the noise in the tail of the background isn't mistaken for a mode.
+func findModes(counts []int) (int, int, bool) {
+	highest := slices.Index(counts, slices.Max(counts))
+	second, prominence := -1, 0
+
+	for j, count := range counts {
+		if j >= highest-1 && j <= highest+1 {
+			continue
+		}
+
+		valley := slices.Min(counts[min(j, highest)+1 : max(j, highest)])
+		if count-valley > prominence && valley*2 < count {
+			second, prominence = j, count-valley
+		}
+	}
+
+	if second < 0 {
+		return 0, 0, false
+	}
+
+	return min(highest, second), max(highest, second), true
+}
+
+// findGap returns the first and last bins of the longest run of the least common scores between the two modes. When
+// there are several, the one closest to the near-duplicates is chosen.
+func findGap(counts []int, background, nearDuplicate int) (int, int) {
+	valley := slices.Min(counts[background+1 : nearDuplicate])
+	bestStart, bestLength := 0, 0
+
+	for i := background + 1; i < nearDuplicate; {
+		if counts[i] != valley {
+			i++
+			continue
+		}
+
+		start := i
+		for i < nearDuplicate && counts[i] == valley {
+			i++
+		}
+
+		if i-start >= bestLength {
+			bestStart, bestLength = start, i-start
+		}
+	}
+
+	return bestStart, bestStart + bestLength - 1
+}
+
+// adviseFromLabels scores the labelled pairs and suggests the threshold with the best F1 score. When several thresholds
+// have the best score, the one in the middle of them is chosen.
+func adviseFromLabels(media []Media, options TuneOptions) ThresholdAdvice {
+	byName := make(map[string]*Media, len(media))
+	for i := range media {
+		byName[media[i].Name] = &media[i]
+	}
+
+	advice := ThresholdAdvice{}
+	scores := make([]float64, 0, len(options.Pairs))
+	labels := make([]bool, 0, len(options.Pairs))
+	stats := GroupStats{}
+
+	for _, pair := range options.Pairs {
+		media1, media2 := byName[pair.Name1], byName[pair.Name2]
+		if media1 == nil || media2 == nil {
+			advice.Missing++
+			continue
+		}
+
+		// Pairs pruned by the prefilters are never grouped
+		score, _ := stats.score(*media1, *media2, options.Prefilter)
+		scores = append(scores, score)
+		labels = append(labels, pair.Duplicate)
+	}
+
+	advice.Pairs = len(scores)
+	advice.Histogram = newHistogram(scores, options.Bins)
+	if len(scores) == 0 {
+		return advice
+	}
+
+	advice.Tradeoff = make([]ThresholdMetrics, 0, 101)
+	for step := range 101 {
+		metrics := ThresholdMetrics{Threshold: float64(step) / 100}
+
+		for i, score := range scores {
+			predicted := score >= metrics.Threshold
+			switch {
+			case predicted && labels[i]:
+				metrics.TruePositives++
+			case predicted && !labels[i]:
+				metrics.FalsePositives++
+			case !predicted && labels[i]:
+				metrics.FalseNegatives++
+			}
+		}
+
+		if positives := metrics.TruePositives + metrics.FalsePositives; positives > 0 {
+			metrics.Precision = float64(metrics.TruePositives) / float64(positives)
+		}
+		if duplicates := metrics.TruePositives + metrics.FalseNegatives; duplicates > 0 {
+			metrics.Recall = float64(metrics.TruePositives) / float64(duplicates)
+		}
+		if metrics.Precision+metrics.Recall > 0 {
+			metrics.F1 = 2 * metrics.Precision * metrics.Recall / (metrics.Precision + metrics.Recall)
+		}
+
+		advice.Tradeoff = append(advice.Tradeoff, metrics)
+	}
+
+	best := slices.MaxFunc(advice.Tradeoff, func(a, b ThresholdMetrics) int {
+		return cmp.Compare(a.F1, b.F1)
+	})
+
+	// The thresholds with the best F1 are consecutive when the scores are separated, so the middle one has the largest
+	// margin on both sides
+	tied := make([]float64, 0)
+	for _, metrics := range advice.Tradeoff {
+		if metrics.F1 == best.F1 {
+			tied = append(tied, metrics.Threshold)
+		}
+	}
+
+	advice.Threshold = roundScore(tied[len(tied)/2])
+	advice.Separated = best.F1 == 1
+
+	return advice
+}
+
+// endregion
@@ -0,0 +1,154 @@
+package mediasim
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createRandomImage creates an image with random colored rectangles.
+func createRandomImage(random *rand.Rand, w, h int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	randomColor := func() color.Color {
+		return color.RGBA{R: uint8(random.IntN(256)), G: uint8(random.IntN(256)), B: uint8(random.IntN(256)), A: 255}
+	}
+
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: randomColor()}, image.Point{}, draw.Src)
+	for range 6 {
+		x, y := random.IntN(w), random.IntN(h)
+		rect := image.Rect(x, y, x+random.IntN(w/2)+w/8, y+random.IntN(h/2)+h/8)
+		draw.Draw(img, rect, &image.Uniform{C: randomColor()}, image.Point{}, draw.Src)
+	}
+
+	return img
+}
+
+// createCollection creates media with random content, where the first duplicates have a recompressed copy.
+func createCollection(t *testing.T, size, duplicates int) ([]Media, []LabeledPair) {
+	random := rand.New(rand.NewPCG(7, 7))
+	media := make([]Media, 0, size+duplicates)
+	pairs := make([]LabeledPair, 0)
+
+	for i := range size {
+		img := createRandomImage(random, 120, 90)
+		name := fmt.Sprintf("%02d.jpg", i)
+		media = append(media, LoadMediaFromImages(name, []image.Image{img}, FrameOptions{}))
+
+		if i < duplicates {
+			var buf bytes.Buffer
+			require.NoError(t, jpeg.Encode(&buf, img, &jpeg.Options{Quality: 40}))
+			copied, err := jpeg.Decode(&buf)
+			require.NoError(t, err)
+
+			media = append(media, LoadMediaFromImages(name+".copy.jpg", []image.Image{copied}, FrameOptions{}))
+			pairs = append(pairs, LabeledPair{Name1: name, Name2: name + ".copy.jpg", Duplicate: true})
+		}
+
+		if i > 0 {
+			pairs = append(pairs, LabeledPair{Name1: name, Name2: fmt.Sprintf("%02d.jpg", i-1)})
+		}
+	}
+
+	return media, pairs
+}
+
+func TestAdviseThreshold(t *testing.T) {
+	media, pairs := createCollection(t, 40, 8)
+
+	// The score of the most similar unrelated pair, and of the least similar duplicate
+	maxUnrelated, minDuplicate := 0.0, 1.0
+	for i := range media {
+		for j := i + 1; j < len(media); j++ {
+			score := CalculateSimilarity(media[i], media[j])
+			if media[j].Name == media[i].Name+".copy.jpg" {
+				minDuplicate = min(minDuplicate, score)
+			} else {
+				maxUnrelated = max(maxUnrelated, score)
+			}
+		}
+	}
+
+	require.Less(t, maxUnrelated, minDuplicate, "the collection must be separable")
+
+	t.Run("the threshold is in the gap between the modes", func(t *testing.T) {
+		advice := AdviseThreshold(media, TuneOptions{})
+
+		assert.True(t, advice.Separated)
+		assert.Equal(t, len(media)*(len(media)-1)/2, advice.Pairs)
+		assert.Len(t, advice.Histogram, 50)
+		assert.Greater(t, advice.Threshold, maxUnrelated)
+		assert.LessOrEqual(t, advice.Threshold, minDuplicate)
+		assert.Less(t, advice.BackgroundMode, advice.GapLow)
+		assert.Greater(t, advice.NearDuplicateMode, advice.GapHigh)
+	})
+
+	t.Run("large collections are sampled", func(t *testing.T) {
+		advice := AdviseThreshold(media, TuneOptions{MaxComparisons: 200})
+
+		assert.Less(t, advice.Pairs, len(media)*(len(media)-1)/2)
+		assert.GreaterOrEqual(t, advice.Pairs, 150)
+		assert.Equal(t, advice, AdviseThreshold(media, TuneOptions{MaxComparisons: 200}), "the sample is reproducible")
+	})
+
+	t.Run("labelled pairs report the precision and recall", func(t *testing.T) {
+		labelled := append(pairs, LabeledPair{Name1: "missing.jpg", Name2: "00.jpg"})
+		advice := AdviseThreshold(media, TuneOptions{Pairs: labelled})
+
+		assert.Equal(t, len(pairs), advice.Pairs)
+		assert.Equal(t, 1, advice.Missing)
+		assert.True(t, advice.Separated)
+		require.Len(t, advice.Tradeoff, 101)
+
+		best := advice.Tradeoff[int(advice.Threshold*100+0.5)]
+		assert.Equal(t, 1.0, best.F1)
+		assert.Equal(t, 8, best.TruePositives)
+
+		// Every pair is predicted to be a duplicate with a threshold of 0
+		assert.Equal(t, 1.0, advice.Tradeoff[0].Recall)
+		assert.Equal(t, len(pairs)-8, advice.Tradeoff[0].FalsePositives)
+	})
+
+	t.Run("no pairs to score", func(t *testing.T) {
+		advice := AdviseThreshold(media[:1], TuneOptions{})
+
+		assert.Zero(t, advice.Threshold)
+		assert.Zero(t, advice.Pairs)
+	})
+}
+
+func TestFindModes(t *testing.T) {
+	t.Run("the near-duplicates are a separate mode", func(t *testing.T) {
+		counts := []int{0, 10, 200, 900, 400, 90, 95, 30, 2, 0, 0, 0, 3, 20, 12}
+
+		background, nearDuplicate, ok := findModes(counts)
+		require.True(t, ok)
+		assert.Equal(t, 3, background)
+		assert.Equal(t, 13, nearDuplicate)
+
+		start, end := findGap(counts, background, nearDuplicate)
+		assert.Equal(t, 9, start)
+		assert.Equal(t, 11, end)
+	})
+
+	t.Run("collections with mostly duplicates", func(t *testing.T) {
+		counts := []int{0, 5, 40, 8, 0, 0, 60, 300}
+
+		background, nearDuplicate, ok := findModes(counts)
+		require.True(t, ok)
+		assert.Equal(t, 2, background)
+		assert.Equal(t, 7, nearDuplicate)
+	})
+
+	t.Run("bumps in the tail of the background aren't modes", func(t *testing.T) {
+		_, _, ok := findModes([]int{10, 200, 900, 400, 90, 95, 30, 2})
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,134 @@
+package mediasim
+
+import (
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"shared"
+)
+
+// TypeRegistry holds the file extensions that are recognised as images and videos.
+//
+// A registry is safe for concurrent use, and each caller can have its own, passing it in LoadOptions. When no registry
+// is given, the default registry, which can be changed with AddImageType and AddVideoType, is used.
+type TypeRegistry struct {
+	mu         sync.RWMutex
+	imageTypes []string
+	videoTypes []string
+}
+
+// defaultTypes is the registry used when the options don't have one.
+var defaultTypes = NewTypeRegistry()
+
+// NewTypeRegistry creates a registry with the default image and video extensions.
+func NewTypeRegistry() *TypeRegistry {
+	return &TypeRegistry{
+		imageTypes: slices.Clone(shared.ValidImageTypes),
+		videoTypes: slices.Clone(shared.ValidVideoTypes),
+	}
+}
+
+// DefaultTypeRegistry returns the registry used when the options don't have one.
+func DefaultTypeRegistry() *TypeRegistry {
+	return defaultTypes
+}
+
+// AddImageType adds one or more image type extensions (e.g., ".avif") to the registry.
+func (r *TypeRegistry) AddImageType(types ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.imageTypes = appendTypes(r.imageTypes, types)
+}
+
+// AddVideoType adds one or more video type extensions (e.g., ".flv") to the registry.
+func (r *TypeRegistry) AddVideoType(types ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.videoTypes = appendTypes(r.videoTypes, types)
+}
+
+// ImageTypes returns a copy of the image extensions in the registry.
+func (r *TypeRegistry) ImageTypes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return slices.Clone(r.imageTypes)
+}
+
+// VideoTypes returns a copy of the video extensions in the registry.
+func (r *TypeRegistry) VideoTypes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return slices.Clone(r.videoTypes)
+}
+
+// TypeOf returns the type of media ("image" or "video") of the file name, based on its extension, or an empty string
+// if the extension is not in the registry.
+func (r *TypeRegistry) TypeOf(name string) string {
+	ext := strings.ToLower(path.Ext(name))
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	switch {
+	case slices.Contains(r.imageTypes, ext):
+		return "image"
+	case slices.Contains(r.videoTypes, ext):
+		return "video"
+	}
+
+	return ""
+}
+
+// AddImageType adds one or more image type extensions to the default registry and, like before the registries, to
+// shared.ValidImageTypes, so the code that still reads the global lists classifies the files the same way. Changing the
+// global lists isn't safe for concurrent use.
+//
+// Deprecated: create a registry with NewTypeRegistry and pass it in LoadOptions, so the types are scoped to the caller.
+func AddImageType(types ...string) {
+	defaultTypes.AddImageType(types...)
+	shared.ValidImageTypes = appendTypes(shared.ValidImageTypes, types)
+}
+
+// AddVideoType adds one or more video type extensions to the default registry and, like before the registries, to
+// shared.ValidVideoTypes, so the code that still reads the global lists classifies the files the same way. Changing the
+// global lists isn't safe for concurrent use.
+//
+// Deprecated: create a registry with NewTypeRegistry and pass it in LoadOptions, so the types are scoped to the caller.
+func AddVideoType(types ...string) {
+	defaultTypes.AddVideoType(types...)
+	shared.ValidVideoTypes = appendTypes(shared.ValidVideoTypes, types)
+}
+
+// region - Private functions
+
+// mediaTypes returns the extensions of the types of media that are included.
+func (r *TypeRegistry) mediaTypes(includeImages bool, includeVideos bool) []string {
+	mediaTypes := make([]string, 0)
+	if includeImages {
+		mediaTypes = append(mediaTypes, r.ImageTypes()...)
+	}
+	if includeVideos {
+		mediaTypes = append(mediaTypes, r.VideoTypes()...)
+	}
+
+	return mediaTypes
+}
+
+func appendTypes(list []string, types []string) []string {
+	for _, t := range types {
+		t = strings.ToLower(t)
+		if !slices.Contains(list, t) {
+			list = append(list, t)
+		}
+	}
+
+	return list
+}
+
+// endregion
@@ -0,0 +1,136 @@
+package mediasim
+
+import (
+	"shared"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypeRegistry(t *testing.T) {
+	t.Run("starts with the default types", func(t *testing.T) {
+		r := NewTypeRegistry()
+		assert.Equal(t, shared.ValidImageTypes, r.ImageTypes())
+		assert.Equal(t, shared.ValidVideoTypes, r.VideoTypes())
+	})
+
+	t.Run("adds types in lowercase without duplicates", func(t *testing.T) {
+		r := NewTypeRegistry()
+		r.AddImageType(".AVIF", ".heic", ".avif")
+		r.AddVideoType(".flv", ".wmv", ".ts")
+
+		assert.Contains(t, r.ImageTypes(), ".avif")
+		assert.Contains(t, r.ImageTypes(), ".heic")
+		assert.Len(t, r.ImageTypes(), len(shared.ValidImageTypes)+2)
+		assert.Contains(t, r.VideoTypes(), ".flv")
+		assert.Contains(t, r.VideoTypes(), ".ts")
+	})
+
+	t.Run("registries are independent", func(t *testing.T) {
+		r1 := NewTypeRegistry()
+		r2 := NewTypeRegistry()
+		r1.AddImageType(".jxl")
+
+		assert.Contains(t, r1.ImageTypes(), ".jxl")
+		assert.NotContains(t, r2.ImageTypes(), ".jxl")
+		assert.NotContains(t, shared.ValidImageTypes, ".jxl")
+	})
+
+	t.Run("classifies names by extension", func(t *testing.T) {
+		r := NewTypeRegistry()
+		assert.Equal(t, "image", r.TypeOf("dir/photo.JPG"))
+		assert.Equal(t, "video", r.TypeOf("movie.mp4"))
+		assert.Equal(t, "", r.TypeOf("notes.txt"))
+		assert.Equal(t, "", r.TypeOf("no-extension"))
+	})
+
+	t.Run("is safe for concurrent use", func(t *testing.T) {
+		r := NewTypeRegistry()
+		var wg sync.WaitGroup
+
+		for range 10 {
+			wg.Go(func() {
+				r.AddImageType(".avif")
+				_ = r.TypeOf("a.avif")
+			})
+		}
+
+		wg.Wait()
+		assert.Equal(t, "image", r.TypeOf("a.avif"))
+	})
+}
+
+// restoreTypes restores the default registry and the global lists changed by the deprecated functions.
+func restoreTypes(t *testing.T) {
+	images := slices.Clone(shared.ValidImageTypes)
+	videos := slices.Clone(shared.ValidVideoTypes)
+	registry := defaultTypes
+
+	t.Cleanup(func() {
+		shared.ValidImageTypes = images
+		shared.ValidVideoTypes = videos
+		defaultTypes = registry
+	})
+
+	defaultTypes = NewTypeRegistry()
+}
+
+func TestAddImageType(t *testing.T) {
+	t.Run("adds new image type", func(t *testing.T) {
+		restoreTypes(t)
+
+		AddImageType(".avif")
+		assert.Contains(t, shared.ValidImageTypes, ".avif")
+		assert.Contains(t, DefaultTypeRegistry().ImageTypes(), ".avif")
+		assert.Equal(t, "image", DefaultTypeRegistry().TypeOf("photo.avif"))
+	})
+
+	t.Run("converts to lowercase", func(t *testing.T) {
+		restoreTypes(t)
+
+		AddImageType(".HEIC")
+		assert.Contains(t, shared.ValidImageTypes, ".heic")
+		assert.Contains(t, DefaultTypeRegistry().ImageTypes(), ".heic")
+	})
+
+	t.Run("adds multiple types at once", func(t *testing.T) {
+		restoreTypes(t)
+
+		AddImageType(".avif", ".heic", ".jxl")
+		for _, ext := range []string{".avif", ".heic", ".jxl"} {
+			assert.Contains(t, shared.ValidImageTypes, ext)
+			assert.Contains(t, DefaultTypeRegistry().ImageTypes(), ext)
+		}
+	})
+}
+
+func TestAddVideoType(t *testing.T) {
+	t.Run("adds new video type", func(t *testing.T) {
+		restoreTypes(t)
+
+		AddVideoType(".flv")
+		assert.Contains(t, shared.ValidVideoTypes, ".flv")
+		assert.Contains(t, DefaultTypeRegistry().VideoTypes(), ".flv")
+		assert.True(t, shared.IsVideoFile("clip.flv"))
+	})
+
+	t.Run("converts to lowercase", func(t *testing.T) {
+		restoreTypes(t)
+
+		AddVideoType(".FLV")
+		assert.Contains(t, shared.ValidVideoTypes, ".flv")
+		assert.Contains(t, DefaultTypeRegistry().VideoTypes(), ".flv")
+	})
+
+	t.Run("adds multiple types at once", func(t *testing.T) {
+		restoreTypes(t)
+
+		AddVideoType(".flv", ".wmv", ".ts")
+		for _, ext := range []string{".flv", ".wmv", ".ts"} {
+			assert.Contains(t, shared.ValidVideoTypes, ext)
+			assert.Contains(t, DefaultTypeRegistry().VideoTypes(), ext)
+		}
+	})
+}
@@ -0,0 +1,155 @@
+package watch
+
+import (
+	"cmp"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/vegidio/mediasim"
+)
+
+// region - Private functions
+
+// indexVersion is the version of the format of the index file.
+const indexVersion = 1
+
+// entry is a media of the index, with the modification time of its file when it was loaded.
+type entry struct {
+	Media   mediasim.Media
+	ModTime time.Time
+}
+
+// index holds the media of the watched directory, by path.
+type index struct {
+	entries map[string]entry
+	frames  mediasim.FrameOptions
+	dirty   bool
+}
+
+// indexFile is how the index is stored in a file.
+type indexFile struct {
+	Version int
+	Frames  mediasim.FrameOptions
+	Entries []entry
+}
+
+// newIndex creates an empty index of media loaded with the frame options.
+func newIndex(frames mediasim.FrameOptions) *index {
+	return &index{entries: make(map[string]entry), frames: frames}
+}
+
+// readIndex reads the index file. A missing file, or one of another version or frame options, is an empty index, since
+// its media have to be loaded again anyway.
+func readIndex(path string, frames mediasim.FrameOptions) (*index, error) {
+	idx := newIndex(frames)
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return idx, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read the index: %w", err)
+	}
+
+	defer file.Close()
+
+	var content indexFile
+	if err = gob.NewDecoder(file).Decode(&content); err != nil {
+		return nil, fmt.Errorf("invalid index %s: %w", path, err)
+	}
+
+	if content.Version != indexVersion || content.Frames != frames {
+		return idx, nil
+	}
+
+	for _, e := range content.Entries {
+		idx.entries[e.Media.Name] = e
+	}
+
+	return idx, nil
+}
+
+// save writes the index to the file, replacing it only after it's completely written.
+func (idx *index) save(path string) error {
+	content := indexFile{Version: indexVersion, Frames: idx.frames, Entries: make([]entry, 0, len(idx.entries))}
+	for _, e := range idx.entries {
+		content.Entries = append(content.Entries, e)
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to save the index: %w", err)
+	}
+
+	err = gob.NewEncoder(temp).Encode(content)
+	err = errors.Join(err, temp.Close())
+	if err == nil {
+		err = os.Rename(temp.Name(), path)
+	}
+
+	if err != nil {
+		_ = os.Remove(temp.Name())
+		return fmt.Errorf("failed to save the index: %w", err)
+	}
+
+	idx.dirty = false
+	return nil
+}
+
+// isCurrent reports whether the media of the file is in the index, loaded from the same version of the file.
+func (idx *index) isCurrent(path string, info os.FileInfo) bool {
+	e, ok := idx.entries[path]
+	return ok && e.Media.Size == info.Size() && e.ModTime.Equal(info.ModTime())
+}
+
+func (idx *index) set(media mediasim.Media, modTime time.Time) {
+	idx.entries[media.Name] = entry{Media: media, ModTime: modTime}
+	idx.dirty = true
+}
+
+// remove removes the file, or all the files in the directory, from the index and returns their entries.
+func (idx *index) remove(path string) []entry {
+	removed := make([]entry, 0)
+	prefix := path + string(filepath.Separator)
+
+	for name, e := range idx.entries {
+		if name == path || strings.HasPrefix(name, prefix) {
+			removed = append(removed, e)
+			delete(idx.entries, name)
+		}
+	}
+
+	if len(removed) > 0 {
+		idx.dirty = true
+	}
+
+	return removed
+}
+
+// matches returns the media of the index that are similar to the media, from the most to the least similar.
+func (idx *index) matches(media mediasim.Media, threshold float64, prefilter mediasim.PrefilterOptions) []Match {
+	matches := make([]Match, 0)
+
+	for name, e := range idx.entries {
+		if name == media.Name || !prefilter.Accept(media, e.Media) {
+			continue
+		}
+
+		if score := mediasim.CalculateSimilarity(media, e.Media); score >= threshold {
+			matches = append(matches, Match{Media: e.Media, Score: score})
+		}
+	}
+
+	slices.SortFunc(matches, func(a, b Match) int {
+		return cmp.Or(cmp.Compare(b.Score, a.Score), cmp.Compare(a.Media.Name, b.Media.Name))
+	})
+
+	return matches
+}
+
+// endregion
@@ -0,0 +1,507 @@
+// Package watch watches a directory and reports, as soon as media are created or modified, the media of the directory
+// that are similar to them. The fingerprints of the media are kept in memory and, optionally, in an index file, so only
+// the new and modified media are loaded when the watch restarts.
+package watch
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/vegidio/mediasim"
+)
+
+// Kind is the kind of event.
+type Kind string
+
+const (
+	// KindReady reports that the media already in the directory were indexed, and new media are being watched.
+	KindReady Kind = "ready"
+	// KindAdded reports a new media, with the media similar to it.
+	KindAdded Kind = "added"
+	// KindModified reports a media whose file was changed, with the media similar to it.
+	KindModified Kind = "modified"
+	// KindRenamed reports a media that was renamed or moved inside the directory; it's not compared again.
+	KindRenamed Kind = "renamed"
+	// KindRemoved reports a media that was removed, or moved out of the directory.
+	KindRemoved Kind = "removed"
+	// KindError reports a media that failed to load, or a failure of the watch itself.
+	KindError Kind = "error"
+)
+
+// Match is a media similar to the media of an event.
+//
+// # Fields:
+//   - Media: The similar media, already in the directory.
+//   - Score: The similarity between the two media.
+type Match struct {
+	Media mediasim.Media `json:"media"`
+	Score float64        `json:"score"`
+}
+
+// Event is something that happened to the media of the watched directory.
+//
+// # Fields:
+//   - Kind: What happened.
+//   - Time: When it was reported.
+//   - Path: The path of the file; empty in ready events and in errors of the watch itself.
+//   - OldPath: The previous path of a renamed file.
+//   - Media: The media of the file; nil in ready and error events.
+//   - Matches: The media similar to the media, from the most to the least similar, in added and modified events.
+//   - Indexed: The number of media in the index, in ready events.
+//   - Err: The error of error events.
+type Event struct {
+	Kind    Kind            `json:"kind"`
+	Time    time.Time       `json:"time"`
+	Path    string          `json:"path,omitempty"`
+	OldPath string          `json:"oldPath,omitempty"`
+	Media   *mediasim.Media `json:"media,omitempty"`
+	Matches []Match         `json:"matches,omitempty"`
+	Indexed int             `json:"indexed,omitempty"`
+	Err     error           `json:"-"`
+}
+
+// Options represents the configuration options for watching a directory.
+//
+// # Fields:
+//   - Threshold: The minimum similarity (0.0–1.0) of the matches. 0 means DefaultThreshold.
+//   - Prefilter: Metadata checks used to skip pairs before they are scored, as in the grouping.
+//   - Index: The path of the file where the fingerprints are kept between runs. Empty means they are kept only in
+//     memory, and all the media of the directory are loaded when the watch starts.
+//   - Settle: How long a file must go without changes before it's loaded, so files still being written, like
+//     uploads, are loaded once and complete. 0 means DefaultSettle.
+//   - DirectoryOptions: The media watched, and how they are loaded, as in mediasim.LoadMediaFromDirectory. Archives
+//     aren't watched.
+type Options struct {
+	Threshold float64
+	Prefilter mediasim.PrefilterOptions
+	Index     string
+	Settle    time.Duration
+	mediasim.DirectoryOptions
+}
+
+// DefaultThreshold is the threshold of the matches when Options.Threshold is 0.
+const DefaultThreshold = 0.8
+
+// DefaultSettle is how long a file must go without changes before it's loaded, when Options.Settle is 0.
+const DefaultSettle = time.Second
+
+// SetDefaults sets the default values of the options that weren't set.
+func (o *Options) SetDefaults() {
+	if o.Threshold == 0 {
+		o.Threshold = DefaultThreshold
+	}
+
+	if o.Settle == 0 {
+		o.Settle = DefaultSettle
+	}
+
+	o.IncludeArchives = false
+	o.DirectoryOptions.SetDefaults()
+}
+
+// Watch indexes the media of the directory and watches it for changes until the context is cancelled.
+//
+// The media already in the directory, or in the index, are not compared with each other; a KindReady event is sent
+// when they are indexed. After that, every media created or modified is compared with all the others, and sent in a
+// KindAdded or KindModified event with its matches. Renamed files are recognised by their size and modification time,
+// so they aren't loaded again.
+//
+// # Parameters:
+//   - ctx: The context that stops the watch; the index is saved, and the channel closed, when it's cancelled.
+//   - directory: The directory to watch; its subdirectories are also watched when IsRecursive is set.
+//   - options: The media watched, the threshold of the matches and the index file.
+//
+// # Returns:
+//   - A channel that receives the events, in order.
+//   - An error if the directory or the index can't be read, or the directory can't be watched.
+func Watch(ctx context.Context, directory string, options Options) (<-chan Event, error) {
+	options.SetDefaults()
+
+	directory, err := filepath.Abs(directory)
+	if err != nil {
+		return nil, err
+	}
+
+	if info, statErr := os.Stat(directory); statErr != nil {
+		return nil, statErr
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("'%s' is not a directory", directory)
+	}
+
+	idx := newIndex(options.FrameOptions)
+	if options.Index != "" {
+		if idx, err = readIndex(options.Index, options.FrameOptions); err != nil {
+			return nil, err
+		}
+	}
+
+	notifier, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch the directory: %w", err)
+	}
+
+	w := &watcher{
+		directory: directory,
+		options:   options,
+		index:     idx,
+		notifier:  notifier,
+		events:    make(chan Event, 64),
+		loaded:    make(chan loadResult),
+		slots:     make(chan struct{}, options.Parallel),
+		pending:   make(map[string]pendingFile),
+		removed:   make(map[string]removal),
+		scanning:  true,
+		saved:     time.Now(),
+	}
+
+	// The directories are watched before they are listed, so the files created while the media are indexed aren't lost
+	if err = w.addDirs(directory); err != nil {
+		_ = notifier.Close()
+		return nil, fmt.Errorf("failed to watch the directory: %w", err)
+	}
+
+	toLoad := w.scan()
+	go w.run(ctx, toLoad)
+
+	return w.events, nil
+}
+
+// region - Private functions
+
+// indexSaveInterval is the minimum time between two saves of the index while the directory is watched.
+const indexSaveInterval = 10 * time.Second
+
+// watcher holds the state of a watch; it's only changed by the goroutine of run.
+type watcher struct {
+	directory string
+	options   Options
+	index     *index
+	notifier  *fsnotify.Watcher
+	events    chan Event
+	loaded    chan loadResult
+	slots     chan struct{}
+	pending   map[string]pendingFile
+	removed   map[string]removal
+	scanning  bool
+	saved     time.Time
+}
+
+// pendingFile is a file that was created or modified, and is loaded when it settles.
+type pendingFile struct {
+	changed time.Time
+	created bool
+}
+
+// removal is a media removed from the index, which is reported as removed when it settles, or as renamed when a file
+// with the same size and modification time is created.
+type removal struct {
+	entry   entry
+	removed time.Time
+}
+
+// loadResult is the result of loading a file.
+type loadResult struct {
+	path    string
+	modTime time.Time
+	media   *mediasim.Media
+	err     error
+	created bool
+	initial bool
+}
+
+// scan lists the media of the directory, removes from the index the media that no longer exist, and returns the
+// media that must be loaded, with their modification times.
+func (w *watcher) scan() map[string]time.Time {
+	toLoad := make(map[string]time.Time)
+	found := make(map[string]bool)
+
+	_ = filepath.WalkDir(w.directory, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+
+		if d.IsDir() {
+			if path != w.directory && !w.options.IsRecursive {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		info, infoErr := os.Stat(path)
+		if infoErr != nil || !w.options.AllowsFile(w.directory, path) {
+			return nil
+		}
+
+		found[path] = true
+		if !w.index.isCurrent(path, info) {
+			toLoad[path] = info.ModTime()
+		}
+
+		return nil
+	})
+
+	for name := range w.index.entries {
+		if !found[name] {
+			w.index.remove(name)
+		}
+	}
+
+	return toLoad
+}
+
+// run loads the media listed by scan, and then handles the changes of the files until the context is cancelled.
+func (w *watcher) run(ctx context.Context, toLoad map[string]time.Time) {
+	defer close(w.events)
+	defer w.notifier.Close()
+
+	scanned := make(chan struct{})
+	go func() {
+		paths := make(chan string)
+		var wg sync.WaitGroup
+
+		for range w.options.Parallel {
+			wg.Go(func() {
+				for path := range paths {
+					w.load(ctx, path, toLoad[path], false, true)
+				}
+			})
+		}
+
+		for path := range toLoad {
+			paths <- path
+		}
+
+		close(paths)
+		wg.Wait()
+		close(scanned)
+	}()
+
+	ticker := time.NewTicker(max(w.options.Settle/4, 10*time.Millisecond))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			w.saveIndex(true)
+			return
+		case <-scanned:
+			scanned = nil
+			w.scanning = false
+			w.emit(ctx, Event{Kind: KindReady, Indexed: len(w.index.entries)})
+		case event, ok := <-w.notifier.Events:
+			if ok {
+				w.handle(event)
+			}
+		case err, ok := <-w.notifier.Errors:
+			if ok {
+				w.emit(ctx, Event{Kind: KindError, Err: fmt.Errorf("failed to watch the directory: %w", err)})
+			}
+		case result := <-w.loaded:
+			w.finish(ctx, result)
+		case now := <-ticker.C:
+			w.settle(ctx, now)
+			w.saveIndex(false)
+		}
+	}
+}
+
+// handle records the change of a file, which is processed when the file settles.
+func (w *watcher) handle(event fsnotify.Event) {
+	path := event.Name
+
+	switch {
+	case event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename):
+		delete(w.pending, path)
+		_ = w.notifier.Remove(path)
+
+		for _, e := range w.index.remove(path) {
+			w.removed[e.Media.Name] = removal{entry: e, removed: time.Now()}
+		}
+	case event.Has(fsnotify.Create):
+		info, err := os.Stat(path)
+		if err == nil && info.IsDir() {
+			if w.options.IsRecursive {
+				_ = w.addDirs(path)
+				w.addFiles(path)
+			}
+
+			return
+		}
+
+		w.pending[path] = pendingFile{changed: time.Now(), created: true}
+	case event.Has(fsnotify.Write):
+		p := w.pending[path]
+		p.changed = time.Now()
+		w.pending[path] = p
+	}
+}
+
+// settle loads the files that haven't changed for the settle time, and reports the removals that weren't renames.
+// Nothing is done while the media already in the directory are indexed, so new media are compared with all of them.
+// Removals wait twice as long as files, since the file created by a rename may be reported after the removal.
+func (w *watcher) settle(ctx context.Context, now time.Time) {
+	if w.scanning {
+		return
+	}
+
+	for path, p := range w.pending {
+		if now.Sub(p.changed) < w.options.Settle {
+			continue
+		}
+
+		delete(w.pending, path)
+		w.process(ctx, path, p)
+	}
+
+	for path, r := range w.removed {
+		if now.Sub(r.removed) >= 2*w.options.Settle {
+			delete(w.removed, path)
+			w.emit(ctx, Event{Kind: KindRemoved, Path: path, Media: &r.entry.Media})
+		}
+	}
+}
+
+// process loads a settled file, unless it's a media that was renamed.
+func (w *watcher) process(ctx context.Context, path string, p pendingFile) {
+	info, err := os.Stat(path)
+	if err != nil || !w.options.AllowsFile(w.directory, path) {
+		return
+	}
+
+	for oldPath, r := range w.removed {
+		if r.entry.Media.Size != info.Size() || !r.entry.ModTime.Equal(info.ModTime()) {
+			continue
+		}
+
+		delete(w.removed, oldPath)
+
+		media := r.entry.Media
+		media.Name = path
+		w.index.set(media, info.ModTime())
+		w.emit(ctx, Event{Kind: KindRenamed, Path: path, OldPath: oldPath, Media: &media})
+
+		return
+	}
+
+	go w.load(ctx, path, info.ModTime(), p.created, false)
+}
+
+// load loads the media of the file and sends the result to run.
+func (w *watcher) load(ctx context.Context, path string, modTime time.Time, created bool, initial bool) {
+	select {
+	case w.slots <- struct{}{}:
+		defer func() { <-w.slots }()
+	case <-ctx.Done():
+		return
+	}
+
+	// The frame options of the directory are the ones of the files
+	options := w.options.LoadOptions
+	options.FrameOptions = w.options.FrameOptions
+
+	media, err := mediasim.LoadMediaFromFileWithOptions(path, options)
+	result := loadResult{path: path, modTime: modTime, media: media, err: err, created: created, initial: initial}
+
+	select {
+	case w.loaded <- result:
+	case <-ctx.Done():
+	}
+}
+
+// finish adds the loaded media to the index and reports it with its matches. Media whose files changed, or were
+// removed, while they were loaded are ignored, since they are loaded again.
+func (w *watcher) finish(ctx context.Context, result loadResult) {
+	if _, changed := w.pending[result.path]; changed {
+		return
+	}
+
+	if info, err := os.Stat(result.path); err != nil || !info.ModTime().Equal(result.modTime) {
+		return
+	}
+
+	if result.err != nil {
+		w.emit(ctx, Event{Kind: KindError, Path: result.path, Err: result.err})
+		return
+	}
+
+	media := *result.media
+	if result.initial {
+		w.index.set(media, result.modTime)
+		return
+	}
+
+	kind := KindAdded
+	if _, exists := w.index.entries[media.Name]; exists && !result.created {
+		kind = KindModified
+	}
+
+	matches := w.index.matches(media, w.options.Threshold, w.options.Prefilter)
+	w.index.set(media, result.modTime)
+	w.emit(ctx, Event{Kind: kind, Path: media.Name, Media: &media, Matches: matches})
+}
+
+// addDirs watches the directory and, when IsRecursive is set, its subdirectories.
+func (w *watcher) addDirs(directory string) error {
+	if !w.options.IsRecursive {
+		return w.notifier.Add(directory)
+	}
+
+	return filepath.WalkDir(directory, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+
+		if d.IsDir() {
+			return w.notifier.Add(path)
+		}
+
+		return nil
+	})
+}
+
+// addFiles marks the files of a new directory as created; they may have been created before the directory was
+// watched.
+func (w *watcher) addFiles(directory string) {
+	_ = filepath.WalkDir(directory, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			w.pending[path] = pendingFile{changed: time.Now(), created: true}
+		}
+
+		return nil
+	})
+}
+
+// saveIndex saves the index when it has changed, at most once every indexSaveInterval unless it's forced.
+func (w *watcher) saveIndex(force bool) {
+	if w.options.Index == "" || !w.index.dirty || (!force && time.Since(w.saved) < indexSaveInterval) {
+		return
+	}
+
+	w.saved = time.Now()
+	if err := w.index.save(w.options.Index); err != nil {
+		// The context may be cancelled already, so the error is only sent if it can be received
+		select {
+		case w.events <- Event{Kind: KindError, Time: time.Now(), Err: err}:
+		default:
+		}
+	}
+}
+
+// emit sends the event, unless the context is cancelled.
+func (w *watcher) emit(ctx context.Context, event Event) {
+	event.Time = time.Now()
+
+	select {
+	case w.events <- event:
+	case <-ctx.Done():
+	}
+}
+
+// endregion
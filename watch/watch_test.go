@@ -0,0 +1,195 @@
+package watch
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vegidio/mediasim"
+)
+
+// writeImage writes a PNG with a grid of random colors, chosen by the seed, so images with the same seed are similar at
+// any size.
+func writeImage(t *testing.T, path string, seed uint64, width, height int) {
+	t.Helper()
+
+	random := rand.New(rand.NewPCG(seed, seed))
+	colors := make([]color.Color, 16)
+	for i := range colors {
+		colors[i] = color.RGBA{R: uint8(random.IntN(256)), G: uint8(random.IntN(256)), B: uint8(random.IntN(256)), A: 255}
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			img.Set(x, y, colors[(y*4/height)*4+x*4/width])
+		}
+	}
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+
+	// The image is written to a temporary file and renamed, so it's created complete
+	temp := path + ".tmp"
+	file, err := os.Create(temp)
+	require.NoError(t, err)
+	require.NoError(t, png.Encode(file, img))
+	require.NoError(t, file.Close())
+	require.NoError(t, os.Rename(temp, path))
+}
+
+// startWatch watches the directory until the end of the test, and waits for it to be ready.
+func startWatch(t *testing.T, dir string, options Options) (<-chan Event, context.CancelFunc) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	options.Settle = 50 * time.Millisecond
+	events, err := Watch(ctx, dir, options)
+	require.NoError(t, err)
+
+	ready := nextEvent(t, events)
+	require.Equal(t, KindReady, ready.Kind)
+
+	return events, cancel
+}
+
+// nextEvent returns the next event, failing the test when there's none in a few seconds.
+func nextEvent(t *testing.T, events <-chan Event) Event {
+	t.Helper()
+
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "no event was received")
+		return Event{}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	original := filepath.Join(dir, "original.png")
+	writeImage(t, original, 1, 160, 120)
+	writeImage(t, filepath.Join(dir, "other.png"), 2, 160, 120)
+
+	events, _ := startWatch(t, dir, Options{DirectoryOptions: mediasim.DirectoryOptions{IsRecursive: true}})
+
+	t.Run("new media are reported with their matches", func(t *testing.T) {
+		copied := filepath.Join(dir, "uploads", "copy.png")
+		writeImage(t, copied, 1, 80, 60)
+
+		event := nextEvent(t, events)
+		assert.Equal(t, KindAdded, event.Kind)
+		assert.Equal(t, copied, event.Path)
+		require.Len(t, event.Matches, 1)
+		assert.Equal(t, original, event.Matches[0].Media.Name)
+		assert.Greater(t, event.Matches[0].Score, 0.9)
+
+		writeImage(t, filepath.Join(dir, "new.png"), 3, 160, 120)
+
+		event = nextEvent(t, events)
+		assert.Equal(t, KindAdded, event.Kind)
+		assert.Empty(t, event.Matches)
+	})
+
+	t.Run("files that aren't media are ignored", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("notes"), 0o644))
+		writeImage(t, filepath.Join(dir, "third.png"), 4, 160, 120)
+
+		assert.Equal(t, filepath.Join(dir, "third.png"), nextEvent(t, events).Path)
+	})
+
+	t.Run("renamed media aren't loaded again", func(t *testing.T) {
+		renamed := filepath.Join(dir, "renamed.png")
+		require.NoError(t, os.Rename(filepath.Join(dir, "other.png"), renamed))
+
+		event := nextEvent(t, events)
+		assert.Equal(t, KindRenamed, event.Kind)
+		assert.Equal(t, renamed, event.Path)
+		assert.Equal(t, filepath.Join(dir, "other.png"), event.OldPath)
+		assert.Equal(t, renamed, event.Media.Name)
+	})
+
+	t.Run("removed media are reported", func(t *testing.T) {
+		require.NoError(t, os.Remove(original))
+
+		event := nextEvent(t, events)
+		assert.Equal(t, KindRemoved, event.Kind)
+		assert.Equal(t, original, event.Path)
+
+		// The removed media isn't matched anymore
+		writeImage(t, filepath.Join(dir, "copy2.png"), 1, 120, 90)
+
+		event = nextEvent(t, events)
+		assert.Equal(t, KindAdded, event.Kind)
+		require.Len(t, event.Matches, 1)
+		assert.Equal(t, filepath.Join(dir, "uploads", "copy.png"), event.Matches[0].Media.Name)
+	})
+
+	t.Run("media that fail to load are reported as errors", func(t *testing.T) {
+		broken := filepath.Join(dir, "broken.png")
+		require.NoError(t, os.WriteFile(broken, []byte("not a png"), 0o644))
+
+		event := nextEvent(t, events)
+		assert.Equal(t, KindError, event.Kind)
+		assert.Equal(t, broken, event.Path)
+		assert.Error(t, event.Err)
+	})
+}
+
+func TestWatch_Index(t *testing.T) {
+	dir := t.TempDir()
+	indexPath := filepath.Join(t.TempDir(), "index")
+	writeImage(t, filepath.Join(dir, "a.png"), 1, 160, 120)
+	writeImage(t, filepath.Join(dir, "b.png"), 2, 160, 120)
+
+	var loads atomic.Int32
+	options := Options{Index: indexPath}
+	options.Observer = mediasim.ObserverFunc(func(event mediasim.Event) {
+		if _, ok := event.(mediasim.FileStartedEvent); ok {
+			loads.Add(1)
+		}
+	})
+
+	events, cancel := startWatch(t, dir, options)
+	cancel()
+	for range events {
+	}
+
+	require.FileExists(t, indexPath)
+	assert.EqualValues(t, 2, loads.Load())
+
+	t.Run("only the changed media are loaded again", func(t *testing.T) {
+		require.NoError(t, os.Remove(filepath.Join(dir, "b.png")))
+		writeImage(t, filepath.Join(dir, "c.png"), 3, 160, 120)
+		loads.Store(0)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		events, err := Watch(ctx, dir, options)
+		require.NoError(t, err)
+
+		ready := nextEvent(t, events)
+		assert.Equal(t, KindReady, ready.Kind)
+		assert.Equal(t, 2, ready.Indexed)
+		assert.EqualValues(t, 1, loads.Load())
+	})
+
+	t.Run("invalid indexes are an error", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(indexPath, []byte("invalid"), 0o644))
+
+		_, err := Watch(context.Background(), dir, options)
+		assert.ErrorContains(t, err, "invalid index")
+	})
+}